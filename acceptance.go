@@ -0,0 +1,55 @@
+package pricey
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// signedContentHash hashes the content of a quote snapshot that a customer
+// agrees to when signing: the quote's details, line items, adjustments and
+// contacts. Timestamps and the quote's status, lock and acceptance are left out
+// so that accepting the quote does not change its hash. Line item costs are
+// left out too: the customer never sees them and they can still be corrected
+// once the quote is locked (see SetUnitCost).
+func signedContentHash(r *QuoteRevision) string {
+	quote := r.Quote
+	quote.Updated = time.Time{}
+	quote.Status = ""
+	quote.StatusHistory = nil
+	quote.UnlockHistory = nil
+	quote.SoldOn = nil
+	quote.Locked = false
+	quote.Acceptance = nil
+
+	lineItems := []LineItem{}
+	for _, li := range r.LineItems {
+		copied := *li
+		copied.Created = time.Time{}
+		copied.Updated = time.Time{}
+		copied.UnitCost = 0
+		lineItems = append(lineItems, copied)
+	}
+	adjustments := []Adjustment{}
+	for _, a := range r.Adjustments {
+		copied := *a
+		copied.Created = time.Time{}
+		copied.Updated = time.Time{}
+		adjustments = append(adjustments, copied)
+	}
+	contacts := []Contact{}
+	for _, c := range r.Contacts {
+		copied := *c
+		copied.Created = time.Time{}
+		copied.Updated = time.Time{}
+		contacts = append(contacts, copied)
+	}
+
+	sum := sha256.Sum256(mustMarshal(struct {
+		Quote       Quote        `json:"quote"`
+		LineItems   []LineItem   `json:"lineItems"`
+		Adjustments []Adjustment `json:"adjustments"`
+		Contacts    []Contact    `json:"contacts"`
+	}{quote, lineItems, adjustments, contacts}))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,195 @@
+package pricey
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedContentHash(t *testing.T) {
+	now := time.Now()
+	revision := func() *QuoteRevision {
+		return &QuoteRevision{
+			Quote: Quote{Id: "1", Code: "Q-1", LineItemIds: []ID{"1"}, Total: 10000, Status: QuoteStatusSent, Updated: now},
+			LineItems: []*LineItem{
+				{Id: "1", Description: "Water heater", Quantity: 100, UnitPrice: 10000, Updated: now},
+			},
+			Adjustments: []*Adjustment{},
+			Contacts:    []*Contact{{Id: "1", Name: "Jane Doe", Updated: now}},
+		}
+	}
+	signed := signedContentHash(revision())
+	assert.Len(t, signed, 64)
+
+	testCases := []struct {
+		name     string
+		change   func(r *QuoteRevision)
+		expected bool
+	}{
+		{name: "unchanged", change: func(r *QuoteRevision) {}, expected: true},
+		{name: "accepted and locked", change: func(r *QuoteRevision) {
+			r.Quote.Status = QuoteStatusAccepted
+			r.Quote.StatusHistory = []QuoteStatusChange{{From: QuoteStatusSent, To: QuoteStatusAccepted}}
+			r.Quote.SoldOn = &now
+			r.Quote.Locked = true
+			r.Quote.Acceptance = &QuoteAcceptance{ContentHash: signed}
+			r.Quote.Updated = now.Add(time.Minute)
+			r.LineItems[0].Updated = now.Add(time.Minute)
+		}, expected: true},
+		{name: "cost changed", change: func(r *QuoteRevision) { r.LineItems[0].UnitCost = 7000 }, expected: true},
+		{name: "price changed", change: func(r *QuoteRevision) { r.LineItems[0].UnitPrice = 9000 }, expected: false},
+		{name: "line item removed", change: func(r *QuoteRevision) { r.LineItems = nil }, expected: false},
+		{name: "bill to changed", change: func(r *QuoteRevision) { r.Contacts[0].Name = "John Doe" }, expected: false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := revision()
+			tc.change(r)
+			assert.Equal(t, tc.expected, signedContentHash(r) == signed)
+		})
+	}
+}
+
+func TestAccept(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	signature := Signature{Name: "Jane Doe"}
+
+	testCases := []struct {
+		name      string
+		change    func(m *memStore)
+		signature Signature
+		err       error
+	}{
+		{name: "accepted", change: func(m *memStore) {}, signature: signature},
+		{name: "unsigned", change: func(m *memStore) {}, signature: Signature{Name: " "}, err: SignatureRequiredError},
+		{name: "expired", change: func(m *memStore) { m.quotes["q"].ExpirationDate = &past }, signature: signature, err: QuoteExpiredError},
+		{name: "draft", change: func(m *memStore) { m.quotes["q"].Status = QuoteStatusDraft }, signature: signature, err: InvalidQuoteStatusError},
+		{name: "option not chosen", change: func(m *memStore) {
+			m.quotes["q"].Options = []QuoteOption{{Id: "good"}, {Id: "best"}}
+		}, signature: signature, err: QuoteOptionRequiredError},
+		{name: "payment schedule does not add up", change: func(m *memStore) {
+			m.quotes["q"].PaymentSchedule = []PaymentMilestone{{Id: "deposit", Type: AdjustmentTypeFlat, Amount: 5000}}
+		}, signature: signature, err: PaymentScheduleTotalError},
+		{name: "breaks policy", change: func(m *memStore) { m.policy = &QuotePolicy{MinMargin: 50} }, signature: signature, err: &ApprovalRequiredError{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := newMemStore()
+			m.quotes["q"] = &Quote{Id: "q", Status: QuoteStatusSent, LineItemIds: []ID{"1"}, SubTotal: 10000, Total: 10000, AdjustmentIds: []ID{}}
+			m.lineItems["1"] = &LineItem{Id: "1", QuoteId: "q", Description: "Water heater", Quantity: 100, UnitPrice: 10000, UnitCost: 6000}
+			tc.change(m)
+			v := &priceyQuote{store: m}
+
+			q, err := v.Accept(context.Background(), "q", tc.signature)
+			if tc.err != nil {
+				var approvalErr *ApprovalRequiredError
+				if errors.As(tc.err, &approvalErr) {
+					assert.ErrorAs(t, err, &approvalErr)
+				} else {
+					assert.ErrorIs(t, err, tc.err)
+				}
+				assert.Empty(t, m.revisions, "nothing is written when the acceptance is refused")
+				assert.Nil(t, m.quotes["q"].Acceptance)
+				assert.False(t, m.quotes["q"].Locked)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, QuoteStatusAccepted, q.Status)
+			assert.True(t, q.Locked)
+			require.Len(t, m.revisions, 1)
+			require.NotNil(t, q.Acceptance)
+			assert.Equal(t, "Jane Doe", q.Acceptance.Signature.Name)
+			assert.Equal(t, 1, q.Acceptance.RevisionNumber)
+			require.NotEmpty(t, q.StatusHistory)
+			assert.Equal(t, ID("Jane Doe"), q.StatusHistory[len(q.StatusHistory)-1].Actor)
+
+			verified, err := v.VerifyAcceptance(context.Background(), "q")
+			require.NoError(t, err)
+			assert.True(t, verified)
+
+			_, err = (&priceyLineItem{store: m}).SetUnitCost(context.Background(), "1", 7000)
+			require.NoError(t, err)
+			verified, err = v.VerifyAcceptance(context.Background(), "q")
+			require.NoError(t, err)
+			assert.True(t, verified, "correcting a cost after signing does not break the signature")
+		})
+	}
+}
+
+func TestTransitionRefusesAcceptance(t *testing.T) {
+	m := newMemStore()
+	m.quotes["q"] = &Quote{Id: "q", Status: QuoteStatusSent, LineItemIds: []ID{}, AdjustmentIds: []ID{}}
+	v := &priceyQuote{store: m}
+
+	_, err := v.Transition(context.Background(), "q", QuoteStatusAccepted, "u1")
+	assert.ErrorIs(t, err, SignatureRequiredError)
+	assert.Equal(t, QuoteStatusSent, m.quotes["q"].Status)
+	assert.False(t, m.quotes["q"].Locked)
+
+	q, err := v.Accept(WithActor(context.Background(), Actor{Id: "u1"}), "q", Signature{Name: "Jane Doe"})
+	require.NoError(t, err)
+	assert.Equal(t, ID("u1"), q.StatusHistory[len(q.StatusHistory)-1].Actor)
+}
+
+func TestAcceptOption(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	signature := Signature{Name: "Jane Doe"}
+
+	testCases := []struct {
+		name      string
+		change    func(m *memStore)
+		signature Signature
+		optionId  ID
+		err       error
+	}{
+		{name: "accepted", change: func(m *memStore) {}, signature: signature, optionId: "best"},
+		{name: "unsigned", change: func(m *memStore) {}, signature: Signature{}, optionId: "best", err: SignatureRequiredError},
+		{name: "unknown option", change: func(m *memStore) {}, signature: signature, optionId: "other", err: QuoteOptionNotFoundError},
+		{name: "expired", change: func(m *memStore) { m.quotes["q"].ExpirationDate = &past }, signature: signature, optionId: "best", err: QuoteExpiredError},
+		{name: "breaks policy", change: func(m *memStore) { m.policy = &QuotePolicy{MinMargin: 50} }, signature: signature, optionId: "best", err: &ApprovalRequiredError{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := newMemStore()
+			m.quotes["q"] = &Quote{
+				Id: "q", Status: QuoteStatusSent, LineItemIds: []ID{"1"}, SubTotal: 10000, Total: 10000, AdjustmentIds: []ID{},
+				Options: []QuoteOption{{Id: "good"}, {Id: "best"}}, ChosenOptionId: "good",
+			}
+			m.lineItems["1"] = &LineItem{Id: "1", QuoteId: "q", Description: "Water heater", Quantity: 100, UnitPrice: 10000, UnitCost: 6000}
+			tc.change(m)
+			v := &priceyQuote{store: m}
+
+			q, err := v.AcceptOption(context.Background(), "q", tc.optionId, tc.signature)
+			if tc.err != nil {
+				var approvalErr *ApprovalRequiredError
+				if errors.As(tc.err, &approvalErr) {
+					assert.ErrorAs(t, err, &approvalErr)
+				} else {
+					assert.ErrorIs(t, err, tc.err)
+				}
+				assert.Equal(t, ID("good"), m.quotes["q"].ChosenOptionId, "a refused acceptance keeps the option chosen before")
+				assert.Nil(t, m.quotes["q"].Acceptance)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, QuoteStatusAccepted, q.Status)
+			assert.Equal(t, ID("best"), q.ChosenOptionId)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package pricey
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// ContractTotal is what the customer has agreed to pay for a quote once the
+// change orders made to it after it was sold are taken into account.
+type ContractTotal struct {
+	QuoteId ID `json:"quoteId" firestore:"quoteId"`
+	// Original total of the quote as it was accepted
+	Original int `json:"original" firestore:"original"`
+	// ChangeOrders sum of the totals of the accepted change orders
+	ChangeOrders int `json:"changeOrders" firestore:"changeOrders"`
+	// AcceptedChangeOrderIds the change orders counted, in change order number order
+	AcceptedChangeOrderIds []ID `json:"acceptedChangeOrderIds" firestore:"acceptedChangeOrderIds"`
+	// Total original plus the accepted change orders
+	Total int `json:"total" firestore:"total"`
+}
+
+// contractTotal adds the accepted and converted change orders to the original
+// quote's total. Change orders still being drafted or sent, and those that
+// were declined, expired or voided, are left out.
+func contractTotal(original *Quote, changeOrders []*Quote) *ContractTotal {
+	total := &ContractTotal{
+		QuoteId:                original.Id,
+		Original:               original.Total,
+		AcceptedChangeOrderIds: []ID{},
+	}
+	for _, co := range sortedChangeOrders(changeOrders) {
+		if !changeOrderAccepted(co) {
+			continue
+		}
+		total.ChangeOrders += co.Total
+		total.AcceptedChangeOrderIds = append(total.AcceptedChangeOrderIds, co.Id)
+	}
+	total.Total = total.Original + total.ChangeOrders
+	return total
+}
+
+// acceptedChangeOrdersTotal is what the quote's accepted change orders add to
+// it. Payments for that work are taken against the original quote, so its
+// balance is measured against the contract total rather than its own total.
+// A change order adds nothing to itself.
+func acceptedChangeOrdersTotal(ctx context.Context, store Store, q *Quote) (int, error) {
+	if q.ChangeOrderOf != "" || !changeOrderAccepted(q) {
+		return 0, nil
+	}
+	changeOrders, err := listChangeOrders(ctx, store, q.Id, false)
+	if err != nil {
+		return 0, err
+	}
+	return contractTotal(q, changeOrders).ChangeOrders, nil
+}
+
+func changeOrderAccepted(co *Quote) bool {
+	if co.Hidden {
+		return false
+	}
+	switch quoteStatus(co) {
+	case QuoteStatusAccepted, QuoteStatusConverted:
+		return true
+	}
+	return false
+}
+
+// changeOrderOpen is true for change orders that are accepted or could still
+// be, so the line items they remove can not be removed again.
+func changeOrderOpen(co *Quote) bool {
+	if co.Hidden {
+		return false
+	}
+	switch quoteStatus(co) {
+	case QuoteStatusDeclined, QuoteStatusExpired, QuoteStatusVoid:
+		return false
+	}
+	return true
+}
+
+func sortedChangeOrders(changeOrders []*Quote) []*Quote {
+	sorted := slices.Clone(changeOrders)
+	slices.SortStableFunc(sorted, func(a, b *Quote) int {
+		return a.ChangeOrderNumber - b.ChangeOrderNumber
+	})
+	return sorted
+}
+
+// changeOrderCode numbers a change order after the quote it changes, e.g.
+// "EST-0042-CO2".
+func changeOrderCode(originalCode string, number int) string {
+	if originalCode == "" {
+		return fmt.Sprintf("CO%d", number)
+	}
+	return fmt.Sprintf("%s-CO%d", originalCode, number)
+}
+
+// changeOrderRemoval builds the credit line item that removes one of the
+// original quote's line items on a change order. Only top level line items
+// counted in the original's total can be removed, and each only once across
+// the open change orders (removed).
+func changeOrderRemoval(original *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment, removed map[ID]bool, lineItemId ID) (*LineItem, error) {
+	l := lineItems[lineItemId]
+	if l == nil || !slices.Contains(original.LineItemIds, lineItemId) {
+		return nil, LineItemNotOnQuoteError
+	}
+	totals := calculateQuoteTotals(original, lineItems, adjustments)
+	if l.ParentId != nil || totals.Excluded[lineItemId] || !inQuoteOption(original, l.OptionId, activeQuoteOptionId(original)) {
+		return nil, ChangeOrderLineItemError
+	}
+	if removed[lineItemId] {
+		return nil, AlreadyRemovedError
+	}
+	amount := -totals.LineItemAmounts[lineItemId]
+	return &LineItem{
+		Name:              l.Name,
+		Description:       l.Description,
+		Amount:            &amount,
+		RemovesLineItemId: lineItemId,
+	}, nil
+}
+
+// listChangeOrders loads every change order made to the original quote in
+// change order number order.
+func listChangeOrders(ctx context.Context, store Store, originalId ID, includeDeleted bool) ([]*Quote, error) {
+	changeOrders := []*Quote{}
+	for {
+		page, err := store.ListQuotes(ctx, QuoteFilter{
+			ChangeOrderOf:  &originalId,
+			IncludeDeleted: includeDeleted,
+			Sort:           QuoteSortCreated,
+			Limit:          MaxQuoteLimit,
+			Offset:         len(changeOrders),
+		})
+		if err != nil {
+			return nil, err
+		}
+		changeOrders = append(changeOrders, page.Quotes...)
+		if len(page.Quotes) == 0 || len(changeOrders) >= page.Total {
+			break
+		}
+	}
+	return sortedChangeOrders(changeOrders), nil
+}
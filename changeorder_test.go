@@ -0,0 +1,127 @@
+package pricey
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractTotal(t *testing.T) {
+	original := &Quote{Id: "q", Total: 100000, Status: QuoteStatusConverted}
+	tests := []struct {
+		name         string
+		changeOrders []*Quote
+		expected     int
+		expectedIds  []ID
+	}{
+		{"no change orders", nil, 100000, []ID{}},
+		{"accepted", []*Quote{
+			{Id: "co2", ChangeOrderNumber: 2, Total: -5000, Status: QuoteStatusAccepted},
+			{Id: "co1", ChangeOrderNumber: 1, Total: 20000, Status: QuoteStatusConverted},
+		}, 115000, []ID{"co1", "co2"}},
+		{"pending and declined are left out", []*Quote{
+			{Id: "co1", ChangeOrderNumber: 1, Total: 20000, Status: QuoteStatusSent},
+			{Id: "co2", ChangeOrderNumber: 2, Total: 30000, Status: QuoteStatusDeclined},
+			{Id: "co3", ChangeOrderNumber: 3, Total: 40000, Status: QuoteStatusVoid},
+			{Id: "co4", ChangeOrderNumber: 4, Total: 1000, Status: QuoteStatusAccepted},
+		}, 101000, []ID{"co4"}},
+		{"deleted", []*Quote{
+			{Id: "co1", ChangeOrderNumber: 1, Total: 20000, Status: QuoteStatusAccepted, Hidden: true},
+		}, 100000, []ID{}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			total := contractTotal(original, tc.changeOrders)
+			assert.Equal(t, 100000, total.Original)
+			assert.Equal(t, tc.expected, total.Total)
+			assert.Equal(t, tc.expected-100000, total.ChangeOrders)
+			assert.Equal(t, tc.expectedIds, total.AcceptedChangeOrderIds)
+		})
+	}
+}
+
+func TestChangeOrderCode(t *testing.T) {
+	assert.Equal(t, "EST-0042-CO2", changeOrderCode("EST-0042", 2))
+	assert.Equal(t, "CO1", changeOrderCode("", 1))
+}
+
+func TestChangeOrderRemoval(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	original := &Quote{
+		Id:            "q",
+		Status:        QuoteStatusAccepted,
+		LineItemIds:   []ID{"1", "2", "3", "4"},
+		AdjustmentIds: []ID{"tax"},
+	}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Name: "Labor", Description: "Install", Quantity: 200, UnitPrice: 15000},
+		"2": {Id: "2", Description: "Materials", SubItemIds: []ID{"3"}},
+		"3": {Id: "3", ParentId: s("2"), Quantity: 100, UnitPrice: 5000},
+		"4": {Id: "4", Optional: true, Quantity: 100, UnitPrice: 9900},
+		"5": {Id: "5", Description: "Not on the quote"},
+	}
+	adjustments := map[ID]*Adjustment{
+		"tax": {Id: "tax", Description: "Tax", Type: AdjustmentTypePercent, Amount: 10},
+	}
+
+	tests := []struct {
+		name       string
+		lineItemId ID
+		removed    map[ID]bool
+		expected   int
+		err        error
+	}{
+		{"top level", "1", nil, -30000, nil},
+		{"with sub items", "2", nil, -5000, nil},
+		{"sub item", "3", nil, 0, ChangeOrderLineItemError},
+		{"excluded", "4", nil, 0, ChangeOrderLineItemError},
+		{"not on quote", "5", nil, 0, LineItemNotOnQuoteError},
+		{"already removed", "1", map[ID]bool{"1": true}, 0, AlreadyRemovedError},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			credit, err := changeOrderRemoval(original, lineItems, adjustments, tc.removed, tc.lineItemId)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, credit.Amount)
+			assert.Equal(t, tc.expected, *credit.Amount)
+			assert.Equal(t, tc.lineItemId, credit.RemovesLineItemId)
+			assert.Equal(t, lineItems[tc.lineItemId].Description, credit.Description)
+		})
+	}
+}
+
+func TestBalanceWithChangeOrders(t *testing.T) {
+	m := newMemStore()
+	m.quotes["q"] = &Quote{Id: "q", Total: 100000, Status: QuoteStatusAccepted}
+	m.quotes["co1"] = &Quote{Id: "co1", Total: 20000, Status: QuoteStatusAccepted, ChangeOrderOf: "q", ChangeOrderNumber: 1}
+	m.quotes["co2"] = &Quote{Id: "co2", Total: 5000, Status: QuoteStatusSent, ChangeOrderOf: "q", ChangeOrderNumber: 2}
+	m.quotes["co3"] = &Quote{Id: "co3", Total: 7000, Status: QuoteStatusAccepted, ChangeOrderOf: "q", ChangeOrderNumber: 3, Hidden: true}
+	m.payments = []*Payment{{QuoteId: "q", Amount: 110000}}
+	v := &priceyQuote{store: m}
+
+	b, err := v.Balance(context.Background(), "q")
+	require.NoError(t, err)
+	assert.Equal(t, 120000, b.Total, "accepted change orders are paid for on the original")
+	assert.Equal(t, 10000, b.Outstanding)
+	assert.Zero(t, b.Overpaid)
+
+	m.payments = append(m.payments, &Payment{QuoteId: "co1", Amount: 20000})
+	b, err = v.Balance(context.Background(), "co1")
+	require.NoError(t, err)
+	assert.Equal(t, 20000, b.Total, "a change order is measured against its own total")
+	assert.Zero(t, b.Outstanding)
+}
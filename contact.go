@@ -0,0 +1,79 @@
+package pricey
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// phoneDigits strips everything but the digits from a phone number so numbers
+// written differently can be compared.
+func phoneDigits(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+// normalizeContactName lowercases the name and collapses its whitespace.
+func normalizeContactName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// containsFold reports whether search appears in any of the values, ignoring
+// case.
+func containsFold(search string, values ...string) bool {
+	search = strings.ToLower(search)
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.Contains(strings.ToLower(v), search)
+	})
+}
+
+// searchContact reports whether search appears in the contact's name,
+// company, emails or phones, ignoring case. Phones are also compared on their
+// digits alone, so "555-0100" finds "(555) 0100".
+func searchContact(c *Contact, search string) bool {
+	if containsFold(search, c.Name, c.CompanyName) || containsFold(search, c.Emails...) || containsFold(search, c.Phones...) {
+		return true
+	}
+	digits := phoneDigits(search)
+	return digits != "" && slices.ContainsFunc(c.Phones, func(p string) bool {
+		return strings.Contains(phoneDigits(p), digits)
+	})
+}
+
+// contactMatches finds the existing contacts that look like the same person
+// or company as contact: a shared email (ignoring case), a shared phone number
+// (ignoring formatting), or the same name and company name. contact itself is
+// never reported.
+func contactMatches(contact Contact, existing []*Contact) []*ContactMatch {
+	matches := []*ContactMatch{}
+	for _, c := range existing {
+		if c == nil || (contact.Id != "" && c.Id == contact.Id) {
+			continue
+		}
+		var reasons []string
+		if slices.ContainsFunc(contact.Emails, func(email string) bool {
+			return email != "" && slices.ContainsFunc(c.Emails, func(e string) bool { return strings.EqualFold(strings.TrimSpace(e), strings.TrimSpace(email)) })
+		}) {
+			reasons = append(reasons, "email")
+		}
+		if slices.ContainsFunc(contact.Phones, func(phone string) bool {
+			digits := phoneDigits(phone)
+			return digits != "" && slices.ContainsFunc(c.Phones, func(p string) bool { return phoneDigits(p) == digits })
+		}) {
+			reasons = append(reasons, "phone")
+		}
+		name := normalizeContactName(contact.Name)
+		company := normalizeContactName(contact.CompanyName)
+		if (name != "" || company != "") && normalizeContactName(c.Name) == name && normalizeContactName(c.CompanyName) == company {
+			reasons = append(reasons, "name")
+		}
+		if len(reasons) > 0 {
+			matches = append(matches, &ContactMatch{Contact: c, Reasons: reasons})
+		}
+	}
+	return matches
+}
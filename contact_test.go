@@ -0,0 +1,93 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContactMatches(t *testing.T) {
+	existing := []*Contact{
+		{Id: "1", Name: "Dana Smith", Emails: []string{"Dana@Example.com"}},
+		{Id: "2", Name: "Pat Jones", Phones: []string{"(555) 010-0100"}},
+		{Id: "3", Name: "dana  smith", CompanyName: "Acme"},
+		{Id: "4", Name: "Someone Else", Emails: []string{"other@example.com"}, Phones: []string{"555 999 0000"}},
+	}
+	testCases := []struct {
+		name     string
+		contact  Contact
+		expected map[ID][]string
+	}{
+		{
+			name:     "email ignores case",
+			contact:  Contact{Emails: []string{"dana@example.com"}},
+			expected: map[ID][]string{"1": {"email"}},
+		},
+		{
+			name:     "phone ignores formatting",
+			contact:  Contact{Phones: []string{"555-010-0100"}},
+			expected: map[ID][]string{"2": {"phone"}},
+		},
+		{
+			name:     "name and company",
+			contact:  Contact{Name: "Dana Smith", CompanyName: "ACME"},
+			expected: map[ID][]string{"3": {"name"}},
+		},
+		{
+			name:     "several reasons",
+			contact:  Contact{Name: "Dana Smith", Emails: []string{"dana@example.com"}},
+			expected: map[ID][]string{"1": {"email", "name"}},
+		},
+		{
+			name:     "itself is skipped",
+			contact:  Contact{Id: "1", Emails: []string{"dana@example.com"}},
+			expected: map[ID][]string{},
+		},
+		{
+			name:     "blank contact matches nothing",
+			contact:  Contact{Emails: []string{""}, Phones: []string{"n/a"}},
+			expected: map[ID][]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := map[ID][]string{}
+			for _, m := range contactMatches(tc.contact, existing) {
+				actual[m.Contact.Id] = m.Reasons
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestSearchContact(t *testing.T) {
+	contact := &Contact{Name: "Dana Smith", CompanyName: "Acme Heating", Emails: []string{"Dana@Example.com"}, Phones: []string{"(555) 010-0100"}}
+
+	testCases := []struct {
+		search   string
+		expected bool
+	}{
+		{search: "", expected: true},
+		{search: "smith", expected: true},
+		{search: "ACME", expected: true},
+		{search: "dana@example", expected: true},
+		{search: "(555)", expected: true},
+		{search: "555-010", expected: true},
+		{search: "0100", expected: true},
+		{search: "jones", expected: false},
+		{search: "555-999", expected: false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.search, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, searchContact(contact, tc.search))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package pricey
+
+import (
+	"slices"
+	"time"
+)
+
+// Customer is someone the group quotes for. ContactIds are the people at the
+// customer, the first being the primary contact that quotes are billed to.
+// AddressIds are contacts holding the customer's service addresses, the first
+// being where quotes are shipped to by default.
+type Customer struct {
+	Id         ID        `json:"id" firestore:"id"`
+	OrgId      ID        `json:"orgId" firestore:"orgId"`
+	GroupId    ID        `json:"groupId" firestore:"groupId"`
+	Name       string    `json:"name" firestore:"name"`
+	ContactIds []ID      `json:"contactIds" firestore:"contactIds"`
+	AddressIds []ID      `json:"addressIds" firestore:"addressIds"`
+	Tags       []string  `json:"tags" firestore:"tags"`
+	Notes      string    `json:"notes" firestore:"notes"`
+	Created    time.Time `json:"created" firestore:"created"`
+	Updated    time.Time `json:"updated" firestore:"updated"`
+	Hidden     bool      `json:"hidden" firestore:"hidden"`
+}
+
+// CustomerHistory is everything quoted for a customer, newest first.
+type CustomerHistory struct {
+	CustomerId ID                      `json:"customerId" firestore:"customerId"`
+	Quotes     []*CustomerQuoteSummary `json:"quotes" firestore:"quotes"`
+	// QuotedTotal sum of the totals of every quote that was sent to the customer
+	QuotedTotal int `json:"quotedTotal" firestore:"quotedTotal"`
+	// AcceptedTotal sum of the totals of the quotes the customer accepted
+	AcceptedTotal int `json:"acceptedTotal" firestore:"acceptedTotal"`
+}
+
+// CustomerQuoteSummary is a single quote in a customer's history.
+type CustomerQuoteSummary struct {
+	QuoteId     ID          `json:"quoteId" firestore:"quoteId"`
+	Code        string      `json:"code" firestore:"code"`
+	OrderNumber string      `json:"orderNumber" firestore:"orderNumber"`
+	Status      QuoteStatus `json:"status" firestore:"status"`
+	Total       int         `json:"total" firestore:"total"`
+	BalanceDue  int         `json:"balanceDue" firestore:"balanceDue"`
+	IssueDate   *time.Time  `json:"issueDate" firestore:"issueDate"`
+	SentOn      *time.Time  `json:"sentOn" firestore:"sentOn"`
+	SoldOn      *time.Time  `json:"soldOn" firestore:"soldOn"`
+	Created     time.Time   `json:"created" firestore:"created"`
+}
+
+// customerHistory summarizes the customer's quotes. Drafts and void quotes are
+// listed but never count towards QuotedTotal, and only accepted and converted
+// quotes count towards AcceptedTotal.
+// searchCustomer reports whether search appears in the customer's name, notes
+// or tags, or in the name, company, emails, phones or street of one of its
+// contacts or addresses, ignoring case. contacts holds the group's contacts by
+// id.
+func searchCustomer(c *Customer, contacts map[ID]*Contact, search string) bool {
+	if containsFold(search, c.Name, c.Notes) || containsFold(search, c.Tags...) {
+		return true
+	}
+	return slices.ContainsFunc(slices.Concat(c.ContactIds, c.AddressIds), func(id ID) bool {
+		ct, ok := contacts[id]
+		return ok && (containsFold(search, ct.Name, ct.CompanyName, ct.Street) ||
+			containsFold(search, ct.Emails...) || containsFold(search, ct.Phones...))
+	})
+}
+
+func customerHistory(customerId ID, quotes []*Quote) *CustomerHistory {
+	history := &CustomerHistory{
+		CustomerId: customerId,
+		Quotes:     []*CustomerQuoteSummary{},
+	}
+	for _, q := range quotes {
+		status := quoteStatus(q)
+		history.Quotes = append(history.Quotes, &CustomerQuoteSummary{
+			QuoteId:     q.Id,
+			Code:        q.Code,
+			OrderNumber: q.OrderNumber,
+			Status:      status,
+			Total:       q.Total,
+			BalanceDue:  q.BalanceDue,
+			IssueDate:   q.IssueDate,
+			SentOn:      q.SentOn,
+			SoldOn:      q.SoldOn,
+			Created:     q.Created,
+		})
+		switch status {
+		case QuoteStatusDraft, QuoteStatusVoid:
+			continue
+		case QuoteStatusAccepted, QuoteStatusConverted:
+			history.AcceptedTotal += q.Total
+		}
+		history.QuotedTotal += q.Total
+	}
+	return history
+}
@@ -0,0 +1,57 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerHistory(t *testing.T) {
+	quotes := []*Quote{
+		{Id: "1", Code: "EST-1", Status: QuoteStatusDraft, Total: 1000},
+		{Id: "2", Code: "EST-2", Status: QuoteStatusSent, Total: 2000},
+		{Id: "3", Code: "EST-3", Status: QuoteStatusAccepted, Total: 4000},
+		{Id: "4", Code: "EST-4", Status: QuoteStatusConverted, Total: 8000},
+		{Id: "5", Code: "EST-5", Status: QuoteStatusVoid, Total: 16000},
+		{Id: "6", Code: "EST-6", Status: QuoteStatusAccepted, Total: 32000},
+	}
+
+	history := customerHistory("c", quotes)
+	assert.Equal(t, ID("c"), history.CustomerId)
+	require.Len(t, history.Quotes, 6)
+	assert.Equal(t, "EST-2", history.Quotes[1].Code)
+	assert.Equal(t, 2000+4000+8000+32000, history.QuotedTotal)
+	assert.Equal(t, 4000+8000+32000, history.AcceptedTotal)
+}
+
+func TestSearchCustomer(t *testing.T) {
+	customer := &Customer{Name: "Smith Residence", Tags: []string{"VIP"}, Notes: "gate code 1234", ContactIds: []ID{"dana"}, AddressIds: []ID{"home"}}
+	contacts := map[ID]*Contact{
+		"dana":  {Id: "dana", Name: "Dana Smith", Emails: []string{"dana@example.com"}, Phones: []string{"555-0100"}},
+		"home":  {Id: "home", Street: "12 Elm St"},
+		"other": {Id: "other", Name: "Pat Jones"},
+	}
+
+	testCases := []struct {
+		search   string
+		expected bool
+	}{
+		{search: "residence", expected: true},
+		{search: "vip", expected: true},
+		{search: "gate code", expected: true},
+		{search: "DANA@", expected: true},
+		{search: "555-01", expected: true},
+		{search: "elm st", expected: true},
+		{search: "jones", expected: false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.search, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, searchCustomer(customer, contacts, tc.search))
+		})
+	}
+}
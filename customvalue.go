@@ -0,0 +1,303 @@
+package pricey
+
+import (
+	"context"
+	"fmt"
+)
+
+// resolveCustomValueConfigId walks from the item's category up through its
+// parent categories and finally to the pricebook, returning the first
+// CustomValueConfigId it finds. A category's config overrides its parent's,
+// which in turn overrides the pricebook's.
+func resolveCustomValueConfigId(ctx context.Context, store Store, item *Item) (*ID, error) {
+	return pricebookLookup{store: store}.resolveCustomValueConfigId(ctx, item)
+}
+
+// pricebookLookup reads categories, pricebooks and configs from the store,
+// except for candidates standing in for changes that have not been written
+// yet. Transactions are not atomic on every store, so the formula prices a
+// change leads to are worked out against the candidates first and the change
+// is only written once they all evaluate.
+type pricebookLookup struct {
+	store      Store
+	categories map[ID]*Category
+	pricebooks map[ID]*Pricebook
+	configs    map[ID]*CustomValueConfig
+}
+
+func (l pricebookLookup) category(ctx context.Context, id ID) (*Category, error) {
+	if c, ok := l.categories[id]; ok {
+		return c, nil
+	}
+	return l.store.GetCategory(ctx, id)
+}
+
+func (l pricebookLookup) pricebook(ctx context.Context, id ID) (*Pricebook, error) {
+	if pb, ok := l.pricebooks[id]; ok {
+		return pb, nil
+	}
+	return l.store.GetPricebook(ctx, id)
+}
+
+func (l pricebookLookup) config(ctx context.Context, id ID) (*CustomValueConfig, error) {
+	if c, ok := l.configs[id]; ok {
+		return c, nil
+	}
+	return l.store.GetCustomValueConfig(ctx, id)
+}
+
+func (l pricebookLookup) resolveCustomValueConfigId(ctx context.Context, item *Item) (*ID, error) {
+	visited := map[ID]bool{}
+	var categoryId *ID
+	if item.CategoryId != "" {
+		categoryId = &item.CategoryId
+	}
+	for categoryId != nil && !visited[*categoryId] {
+		visited[*categoryId] = true
+		c, err := l.category(ctx, *categoryId)
+		if err != nil {
+			return nil, err
+		}
+		if c.CustomValueConfigId != nil {
+			return c.CustomValueConfigId, nil
+		}
+		categoryId = c.ParentId
+	}
+
+	pb, err := l.pricebook(ctx, item.PricebookId)
+	if err != nil {
+		return nil, err
+	}
+	return pb.CustomValueConfigId, nil
+}
+
+// itemCustomValueConfig loads the config that applies to the item, or nil when
+// neither its categories nor its pricebook set one.
+func (l pricebookLookup) itemCustomValueConfig(ctx context.Context, item *Item) (*CustomValueConfig, error) {
+	configId, err := l.resolveCustomValueConfigId(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	if configId == nil {
+		return nil, nil
+	}
+	return l.config(ctx, *configId)
+}
+
+// itemPrice is the default price a formula computes for an item.
+type itemPrice struct {
+	item   *Item
+	amount int
+}
+
+// itemPrices evaluates the formulas that apply to each item, failing on the
+// first item they cannot be evaluated for. Only items whose price is driven by
+// a formula are returned. When configId is set, items using another config
+// are skipped.
+func (l pricebookLookup) itemPrices(ctx context.Context, items []*Item, configId *ID) ([]itemPrice, error) {
+	prices := []itemPrice{}
+	for _, item := range items {
+		config, err := l.itemCustomValueConfig(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		if configId != nil && (config == nil || config.Id != *configId) {
+			continue
+		}
+		amount, ok, err := itemFormulaPrice(config, item)
+		if err != nil {
+			return nil, fmt.Errorf("item %s: %w", item.Id, err)
+		}
+		if ok {
+			prices = append(prices, itemPrice{item: item, amount: amount})
+		}
+	}
+	return prices, nil
+}
+
+// itemFormulaPrice evaluates every formula of the config for the item and
+// returns the amount its price formula computes. ok is false when the config
+// does not drive prices.
+func itemFormulaPrice(config *CustomValueConfig, item *Item) (amount int, ok bool, err error) {
+	if config == nil {
+		return 0, false, nil
+	}
+	effective := mergeCustomValues(config, item.CustomValues)
+	err = evaluateFormulas(config, effective, item.Cost, defaultPriceAmount(item))
+	if err != nil {
+		return 0, false, err
+	}
+	return formulaPrice(config, effective)
+}
+
+// pricebookItems lists the items in the pricebook. When categoryId is set only
+// the items in that category and the categories nested beneath it are listed.
+func pricebookItems(ctx context.Context, store Store, pricebookId ID, categoryId *ID) ([]*Item, error) {
+	categories, err := store.GetCategories(ctx, pricebookId)
+	if err != nil {
+		return nil, err
+	}
+	items := []*Item{}
+	for _, c := range categories {
+		if categoryId != nil && !categoryWithin(categories, c.Id, *categoryId) {
+			continue
+		}
+		inCategory, err := store.GetItemsInCategory(ctx, c.Id)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, inCategory...)
+	}
+	return items, nil
+}
+
+// categoryWithin reports whether the category is ancestorId or nested beneath
+// it.
+func categoryWithin(categories []*Category, id ID, ancestorId ID) bool {
+	parents := map[ID]*ID{}
+	for _, c := range categories {
+		parents[c.Id] = c.ParentId
+	}
+	visited := map[ID]bool{}
+	for current := &id; current != nil && !visited[*current]; current = parents[*current] {
+		if *current == ancestorId {
+			return true
+		}
+		visited[*current] = true
+	}
+	return false
+}
+
+// configItemPrices works out the default price of every item in the group
+// that uses the config, as if the config were already changed to candidate.
+func configItemPrices(ctx context.Context, store Store, candidate *CustomValueConfig) ([]itemPrice, error) {
+	pricebooks, err := store.GetPricebooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := []*Item{}
+	for _, pb := range pricebooks {
+		inPricebook, err := pricebookItems(ctx, store, pb.Id, nil)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, inPricebook...)
+	}
+	lookup := pricebookLookup{store: store, configs: map[ID]*CustomValueConfig{candidate.Id: candidate}}
+	return lookup.itemPrices(ctx, items, &candidate.Id)
+}
+
+// applyItemPrices writes each computed amount to its item's default price.
+func applyItemPrices(ctx context.Context, store Store, prices []itemPrice) error {
+	for _, p := range prices {
+		_, err := applyItemPrice(ctx, store, p.item, p.amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeCustomValues pairs every descriptor in the config with the item's value
+// for that key, falling back to the descriptor's DefaultValue. Item values whose
+// keys are not described by the config are reported as orphaned. A nil config
+// orphans every value.
+func mergeCustomValues(config *CustomValueConfig, values []CustomValue) *EffectiveCustomValues {
+	result := &EffectiveCustomValues{
+		Values:   []EffectiveCustomValue{},
+		Orphaned: []CustomValue{},
+	}
+	lookup := map[ID]CustomValue{}
+	for _, cv := range values {
+		lookup[cv.Key] = cv
+	}
+	described := map[ID]bool{}
+	if config != nil {
+		result.ConfigId = &config.Id
+		for _, d := range config.Descriptors {
+			described[d.Key] = true
+			ev := EffectiveCustomValue{Descriptor: d, Value: d.DefaultValue, IsDefault: true}
+			if cv, ok := lookup[d.Key]; ok {
+				ev.Value = cv.Value
+				ev.IsDefault = false
+			}
+			result.Values = append(result.Values, ev)
+		}
+	}
+	for _, cv := range values {
+		if !described[cv.Key] {
+			result.Orphaned = append(result.Orphaned, cv)
+		}
+	}
+	return result
+}
+
+// effectiveCustomValues resolves the config that applies to the item, merges it
+// with the item's values and computes any formula values.
+func effectiveCustomValues(ctx context.Context, store Store, item *Item) (*CustomValueConfig, *EffectiveCustomValues, error) {
+	config, err := itemCustomValueConfig(ctx, store, item)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := mergeCustomValues(config, item.CustomValues)
+	err = evaluateFormulas(config, result, item.Cost, defaultPriceAmount(item))
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, result, nil
+}
+
+// itemCustomValueConfig loads the config that applies to the item, or nil when
+// neither its categories nor its pricebook set one.
+func itemCustomValueConfig(ctx context.Context, store Store, item *Item) (*CustomValueConfig, error) {
+	return pricebookLookup{store: store}.itemCustomValueConfig(ctx, item)
+}
+
+// checkItemPrice evaluates the formulas that would apply to the item, which
+// may be a candidate for a change that has not been written yet, so that a
+// change formulas cannot be evaluated for is refused before it is stored.
+func checkItemPrice(ctx context.Context, store Store, item *Item) error {
+	_, err := pricebookLookup{store: store}.itemPrices(ctx, []*Item{item}, nil)
+	return err
+}
+
+// recalculateItemPrice re-evaluates the price formula of the item's config, if
+// it has one, and writes the result to the item's default price. The item is
+// returned unchanged when no formula drives its price.
+func recalculateItemPrice(ctx context.Context, store Store, item *Item) (*Item, error) {
+	config, err := itemCustomValueConfig(ctx, store, item)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || config.PriceFormulaKey == nil {
+		return item, nil
+	}
+	amount, _, err := itemFormulaPrice(config, item)
+	if err != nil {
+		return nil, err
+	}
+	return applyItemPrice(ctx, store, item, amount)
+}
+
+// applyItemPrice sets the item's default price to amount, adding a price when
+// the item has none.
+func applyItemPrice(ctx context.Context, store Store, item *Item, amount int) (*Item, error) {
+	if len(item.Prices) == 0 {
+		return store.AddItemPrice(ctx, item.Id, amount)
+	}
+	if item.Prices[0].Amount == amount {
+		return item, nil
+	}
+	price := item.Prices[0]
+	price.Amount = amount
+	return store.UpdateItemPrice(ctx, item.Id, price)
+}
+
+// defaultPriceAmount is the amount of the item's first price, which is the
+// default price (see SetDefaultItemPrice), or 0 when it has none.
+func defaultPriceAmount(item *Item) int {
+	if len(item.Prices) == 0 {
+		return 0
+	}
+	return item.Prices[0].Amount
+}
@@ -0,0 +1,133 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeCustomValues(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	config := &CustomValueConfig{
+		Id: "cfg",
+		Descriptors: []CustomValueDescriptor{
+			{Key: "hours", Label: "Labor Hours", DefaultValue: "1", ValueType: CustomValueTypeNumber},
+			{Key: "brand", Label: "Brand", DefaultValue: "", ValueType: CustomValueTypeString},
+		},
+	}
+	testCases := []struct {
+		name     string
+		config   *CustomValueConfig
+		values   []CustomValue
+		expected *EffectiveCustomValues
+	}{
+		{
+			name:   "no config orphans everything",
+			config: nil,
+			values: []CustomValue{{Key: "hours", Value: "2"}},
+			expected: &EffectiveCustomValues{
+				Values:   []EffectiveCustomValue{},
+				Orphaned: []CustomValue{{Key: "hours", Value: "2"}},
+			},
+		},
+		{
+			name:   "defaults fill missing values",
+			config: config,
+			values: nil,
+			expected: &EffectiveCustomValues{
+				ConfigId: s("cfg"),
+				Values: []EffectiveCustomValue{
+					{Descriptor: config.Descriptors[0], Value: "1", IsDefault: true},
+					{Descriptor: config.Descriptors[1], Value: "", IsDefault: true},
+				},
+				Orphaned: []CustomValue{},
+			},
+		},
+		{
+			name:   "item values override defaults and unknown keys are orphaned",
+			config: config,
+			values: []CustomValue{{Key: "old", Value: "x"}, {Key: "hours", Value: "3"}},
+			expected: &EffectiveCustomValues{
+				ConfigId: s("cfg"),
+				Values: []EffectiveCustomValue{
+					{Descriptor: config.Descriptors[0], Value: "3", IsDefault: false},
+					{Descriptor: config.Descriptors[1], Value: "", IsDefault: true},
+				},
+				Orphaned: []CustomValue{{Key: "old", Value: "x"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, mergeCustomValues(tc.config, tc.values))
+		})
+	}
+}
+
+func TestItemFormulaPrice(t *testing.T) {
+	key := ID("suggested")
+	config := &CustomValueConfig{
+		Id: "cfg",
+		Descriptors: []CustomValueDescriptor{
+			{Key: "hours", DefaultValue: "1", ValueType: CustomValueTypeNumber},
+			{Key: "suggested", DefaultValue: "hours * 9000 + cost", ValueType: CustomValueTypeFormula},
+		},
+		PriceFormulaKey: &key,
+	}
+	withoutPriceFormula := &CustomValueConfig{Id: "cfg", Descriptors: config.Descriptors}
+
+	testCases := []struct {
+		name   string
+		config *CustomValueConfig
+		item   *Item
+		amount int
+		ok     bool
+		err    error
+	}{
+		{name: "no config", config: nil, item: &Item{}},
+		{name: "default values", config: config, item: &Item{Cost: 500}, amount: 9500, ok: true},
+		{name: "item values", config: config, item: &Item{CustomValues: []CustomValue{{Key: "hours", Value: "2.5"}}}, amount: 22500, ok: true},
+		{name: "not a number", config: config, item: &Item{CustomValues: []CustomValue{{Key: "hours", Value: "lots"}}}, err: FormulaNotNumberError},
+		{name: "formulas still checked without a price formula", config: withoutPriceFormula, item: &Item{CustomValues: []CustomValue{{Key: "hours", Value: "lots"}}}, err: FormulaNotNumberError},
+		{name: "no price formula", config: withoutPriceFormula, item: &Item{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			amount, ok, err := itemFormulaPrice(tc.config, tc.item)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.amount, amount)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestCategoryWithin(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	categories := []*Category{
+		{Id: "plumbing"},
+		{Id: "heaters", ParentId: s("plumbing")},
+		{Id: "tankless", ParentId: s("heaters")},
+		{Id: "electrical"},
+	}
+
+	assert.True(t, categoryWithin(categories, "plumbing", "plumbing"))
+	assert.True(t, categoryWithin(categories, "tankless", "plumbing"))
+	assert.False(t, categoryWithin(categories, "plumbing", "heaters"))
+	assert.False(t, categoryWithin(categories, "electrical", "plumbing"))
+}
@@ -0,0 +1,45 @@
+package pricey
+
+import (
+	"time"
+)
+
+// ExpirationSweepActor is recorded in the status history of quotes expired by
+// the expiration sweep when no other actor is given.
+const ExpirationSweepActor ID = "system:expiration"
+
+// ExpirationSweep configures a run of the expiration sweep.
+type ExpirationSweep struct {
+	// LockPrices locks expired quotes so their prices cannot change until they are re-issued
+	LockPrices bool `json:"lockPrices" firestore:"lockPrices"`
+	// Actor recorded against each expiry, defaults to ExpirationSweepActor
+	Actor ID `json:"actor" firestore:"actor"`
+	// BatchSize how many overdue quotes are expired at a time, defaults to MaxQuoteLimit
+	BatchSize int `json:"batchSize" firestore:"batchSize"`
+}
+
+// quoteOverdue reports whether the quote is out with the customer past its
+// expiration date and should be moved to QuoteStatusExpired.
+func quoteOverdue(q *Quote, now time.Time) bool {
+	if q.Hidden || q.ExpirationDate == nil || !now.After(*q.ExpirationDate) {
+		return false
+	}
+	switch quoteStatus(q) {
+	case QuoteStatusSent, QuoteStatusViewed:
+		return true
+	}
+	return false
+}
+
+// quoteExpired reports whether the quote can no longer be accepted as it
+// stands, either because it has been expired or because it is overdue and the
+// sweep has not reached it yet.
+func quoteExpired(q *Quote, now time.Time) bool {
+	return quoteStatus(q) == QuoteStatusExpired || quoteOverdue(q, now)
+}
+
+// validReissueDate reports whether a re-issued quote's new expiration date
+// leaves it open for acceptance. A quote re-issued without one never expires.
+func validReissueDate(expirationDate *time.Time, now time.Time) bool {
+	return expirationDate == nil || expirationDate.After(now)
+}
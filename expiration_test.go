@@ -0,0 +1,99 @@
+package pricey
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteOverdue(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	testCases := []struct {
+		name    string
+		quote   *Quote
+		overdue bool
+		expired bool
+	}{
+		{name: "sent past expiration", quote: &Quote{Status: QuoteStatusSent, ExpirationDate: &past}, overdue: true, expired: true},
+		{name: "viewed past expiration", quote: &Quote{Status: QuoteStatusViewed, ExpirationDate: &past}, overdue: true, expired: true},
+		{name: "sent before expiration", quote: &Quote{Status: QuoteStatusSent, ExpirationDate: &future}},
+		{name: "no expiration", quote: &Quote{Status: QuoteStatusSent}},
+		{name: "draft past expiration", quote: &Quote{Status: QuoteStatusDraft, ExpirationDate: &past}},
+		{name: "accepted past expiration", quote: &Quote{Status: QuoteStatusAccepted, ExpirationDate: &past}},
+		{name: "deleted", quote: &Quote{Status: QuoteStatusSent, ExpirationDate: &past, Hidden: true}},
+		{name: "already expired", quote: &Quote{Status: QuoteStatusExpired, ExpirationDate: &past}, expired: true},
+		{name: "expired then extended", quote: &Quote{Status: QuoteStatusExpired, ExpirationDate: &future}, expired: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.overdue, quoteOverdue(tc.quote, now))
+			assert.Equal(t, tc.expired, quoteExpired(tc.quote, now))
+		})
+	}
+}
+
+func TestValidReissueDate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(30 * 24 * time.Hour)
+
+	assert.True(t, validReissueDate(nil, now))
+	assert.True(t, validReissueDate(&future, now))
+	assert.False(t, validReissueDate(&past, now))
+	assert.False(t, validReissueDate(&now, now))
+}
+
+func TestReissue(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(30 * 24 * time.Hour)
+
+	testCases := []struct {
+		name   string
+		change func(q *Quote)
+		err    error
+	}{
+		{name: "expired", change: func(q *Quote) {}},
+		{name: "locked by the sweep", change: func(q *Quote) { q.Locked, q.ExpiryLocked = true, true }},
+		{name: "locked before it expired", change: func(q *Quote) { q.Locked = true }, err: &QuoteLockedError{}},
+		{name: "not expired", change: func(q *Quote) { q.Status, q.ExpirationDate = QuoteStatusSent, &future }, err: QuoteNotExpiredError},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := newMemStore()
+			m.quotes["q"] = &Quote{Id: "q", Code: "Q-1", Status: QuoteStatusExpired, ExpirationDate: &past, LineItemIds: []ID{}, AdjustmentIds: []ID{}}
+			tc.change(m.quotes["q"])
+			v := &priceyQuote{store: m}
+
+			q, err := v.Reissue(context.Background(), "q", &future, "u1")
+			if tc.err != nil {
+				var lockedErr *QuoteLockedError
+				if errors.As(tc.err, &lockedErr) {
+					assert.ErrorAs(t, err, &lockedErr)
+				} else {
+					assert.ErrorIs(t, err, tc.err)
+				}
+				assert.Nil(t, m.quotes["q"].IssueDate, "nothing is written when re-issuing is refused")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, QuoteStatusSent, q.Status)
+			assert.False(t, q.Locked)
+			assert.Equal(t, &future, q.ExpirationDate)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"maps"
 	"reflect"
 	"slices"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
@@ -14,6 +15,8 @@ import (
 	"github.com/google/uuid"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Firebase struct {
@@ -26,11 +29,28 @@ type Firebase struct {
 type Collection string
 
 const (
-	PricebookCollection   Collection = "pricebook"
-	CategoryCollection    Collection = "category"
-	ItemCollection        Collection = "item"
-	TagCollection         Collection = "tag"
-	CustomValueCollection Collection = "customValue"
+	PricebookCollection     Collection = "pricebook"
+	CategoryCollection      Collection = "category"
+	ItemCollection          Collection = "item"
+	TagCollection           Collection = "tag"
+	CustomValueCollection   Collection = "customValue"
+	ContactCollection       Collection = "contact"
+	CustomerCollection      Collection = "customer"
+	PaymentCollection       Collection = "payment"
+	QuoteTemplateCollection Collection = "quoteTemplate"
+	// QuoteNumberSequenceCollection holds one document per group, keyed by
+	// quoteNumberSequenceDocId
+	QuoteNumberSequenceCollection Collection = "quoteNumberSequence"
+	InvoiceCollection             Collection = "invoice"
+	// InvoiceNumberSequenceCollection holds one document per group, keyed like
+	// QuoteNumberSequenceCollection
+	InvoiceNumberSequenceCollection Collection = "invoiceNumberSequence"
+	// ChangeOrderSequenceCollection holds one document per original quote,
+	// keyed by the quote's id
+	ChangeOrderSequenceCollection Collection = "changeOrderSequence"
+	// QuotePolicyCollection holds one document per group, keyed like
+	// QuoteNumberSequenceCollection
+	QuotePolicyCollection Collection = "quotePolicy"
 )
 
 var (
@@ -39,6 +59,9 @@ var (
 	InvalidPriceIdError           = errors.New("price id is invalid because it does not exist on the sub item")
 	CustomValueAlreadyExistsError = errors.New("custom value keys must be unique")
 	CustomValueNotFoundError      = errors.New("no custom value matches the provided key")
+	// QuotesNotImplementedError is returned by quote queries and writes whose
+	// results callers depend on, since quotes are not stored in firestore yet
+	QuotesNotImplementedError = errors.New("quotes are not implemented on firebase")
 )
 
 func NewFirebase(ctx context.Context, ext OrgGroupExtractor, config *firebase.Config, opts ...option.ClientOption) (*Firebase, error) {
@@ -1214,6 +1237,12 @@ func (f *Firebase) DeleteCustomValueConfigDescriptor(ctx context.Context, id ID,
 	)
 }
 
+func (f *Firebase) UpdateCustomValueConfigPriceFormula(ctx context.Context, id ID, key *ID) (*CustomValueConfig, error) {
+	return update[CustomValueConfig](f, ctx, CustomValueCollection, id,
+		field{"PriceFormulaKey", key},
+	)
+}
+
 func (f *Firebase) DeleteCustomValueConfig(ctx context.Context, id ID) error {
 	return f.delete(ctx, CustomValueCollection, id)
 }
@@ -1251,12 +1280,12 @@ func (f *Firebase) DeleteImage(ctx context.Context, id ID) error {
 // QUOTE
 // ////////////
 
-func (f *Firebase) CreateQuote(ctx context.Context) (*Quote, error) {
+func (f *Firebase) CreateQuote(ctx context.Context, createdBy ID) (*Quote, error) {
 	// TODO: implement me
 	return nil, nil
 }
 
-func (f *Firebase) CreateDuplicateQuote(ctx context.Context, quoteId ID) (*Quote, error) {
+func (f *Firebase) CreateDuplicateQuote(ctx context.Context, quoteId ID, createdBy ID) (*Quote, error) {
 	// TODO: implement me
 	return nil, nil
 }
@@ -1266,6 +1295,11 @@ func (f *Firebase) GetQuote(ctx context.Context, id ID) (*Quote, error) {
 	return nil, nil
 }
 
+func (f *Firebase) ListQuotes(ctx context.Context, filter QuoteFilter) (*QuotePage, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
 func (f *Firebase) UpdateQuoteCode(ctx context.Context, id ID, code string) (*Quote, error) {
 	// TODO: implement me
 	return nil, nil
@@ -1291,6 +1325,11 @@ func (f *Firebase) UpdateQuoteExpirationDate(ctx context.Context, id ID, expirat
 	return nil, nil
 }
 
+func (f *Firebase) UpdateQuoteColors(ctx context.Context, id ID, backgroundColor, textColor string) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
 func (f *Firebase) UpdateQuotePaymentTerms(ctx context.Context, id ID, paymentTerms string) (*Quote, error) {
 	// TODO: implement me
 	return nil, nil
@@ -1316,6 +1355,92 @@ func (f *Firebase) UpdateQuoteShipToId(ctx context.Context, id ID, contactId ID)
 	return nil, nil
 }
 
+func (f *Firebase) UpdateQuoteCustomerId(ctx context.Context, id ID, customerId ID) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateQuoteOptions(ctx context.Context, id ID, options []QuoteOption) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateQuoteChosenOptionId(ctx context.Context, id ID, optionId ID) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateQuoteAcceptance(ctx context.Context, id ID, acceptance *QuoteAcceptance) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateQuotePaymentSchedule(ctx context.Context, id ID, schedule []PaymentMilestone) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateQuoteChangeOrder(ctx context.Context, id ID, originalId ID, number int) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+// changeOrderSequence counts up the change orders of one original quote.
+type changeOrderSequence struct {
+	OrgId   ID        `json:"orgId" firestore:"orgId"`
+	GroupId ID        `json:"groupId" firestore:"groupId"`
+	Counter int       `json:"counter" firestore:"counter"`
+	Updated time.Time `json:"updated" firestore:"updated"`
+}
+
+// AllocateChangeOrderNumber hands out the original quote's next change order
+// number inside a Firestore transaction, which is retried when another
+// allocation changes the counter first.
+func (f *Firebase) AllocateChangeOrderNumber(ctx context.Context, originalId ID) (int, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	doc := f.fire.Collection(string(ChangeOrderSequenceCollection)).Doc(originalId)
+	var data changeOrderSequence
+	err = f.fire.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		data = changeOrderSequence{OrgId: orgId, GroupId: groupId}
+		snap, err := tx.Get(doc)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			err = snap.DataTo(&data)
+			if err != nil {
+				return err
+			}
+			if data.OrgId != orgId {
+				return UnauthorizedOrgError
+			}
+			if data.GroupId != groupId {
+				return UnauthorizedGroupError
+			}
+		}
+		data.Counter++
+		data.Updated = time.Now()
+		return tx.Set(doc, data)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return data.Counter, nil
+}
+
+func (f *Firebase) UpdateQuoteApprovals(ctx context.Context, id ID, approvals []QuoteApproval) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateQuoteDiscountRole(ctx context.Context, id ID, role Role) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
 func (f *Firebase) UpdateQuoteSubTotal(ctx context.Context, id ID, subTotal int) (*Quote, error) {
 	// TODO: implement me
 	return nil, nil
@@ -1346,24 +1471,29 @@ func (f *Firebase) UpdateQuotePayUrl(ctx context.Context, id ID, payUrl string)
 	return nil, nil
 }
 
-func (f *Firebase) UpdateQuoteSent(ctx context.Context, id ID, sent bool) (*Quote, error) {
+func (f *Firebase) UpdateQuoteSentOn(ctx context.Context, id ID, sentOn *time.Time) (*Quote, error) {
 	// TODO: implement me
 	return nil, nil
 }
 
-func (f *Firebase) UpdateQuoteSentOn(ctx context.Context, id ID, sentOn *time.Time) (*Quote, error) {
+func (f *Firebase) UpdateQuoteSoldOn(ctx context.Context, id ID, soldOn *time.Time) (*Quote, error) {
 	// TODO: implement me
 	return nil, nil
 }
 
-func (f *Firebase) UpdateQuoteSold(ctx context.Context, id ID, sold bool) (*Quote, error) {
-	// TODO: implement me
-	return nil, nil
+func (f *Firebase) ListOverdueQuotes(ctx context.Context, now time.Time, limit int) ([]*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
 }
 
-func (f *Firebase) UpdateQuoteSoldOn(ctx context.Context, id ID, soldOn *time.Time) (*Quote, error) {
-	// TODO: implement me
-	return nil, nil
+func (f *Firebase) ExpireQuote(ctx context.Context, id ID, change QuoteStatusChange, lock bool) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateQuoteStatus(ctx context.Context, id ID, status QuoteStatus, change QuoteStatusChange) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
 }
 
 func (f *Firebase) LockQuote(ctx context.Context, id ID) (*Quote, error) {
@@ -1371,11 +1501,234 @@ func (f *Firebase) LockQuote(ctx context.Context, id ID) (*Quote, error) {
 	return nil, nil
 }
 
+func (f *Firebase) UnlockQuote(ctx context.Context, id ID, unlock QuoteUnlock) (*Quote, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
 func (f *Firebase) DeleteQuote(ctx context.Context, id ID) (*Quote, error) {
 	// TODO: implement me
 	return nil, nil
 }
 
+func (f *Firebase) CreateQuoteRevision(ctx context.Context, revision QuoteRevision) (*QuoteRevision, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) GetQuoteRevision(ctx context.Context, id ID) (*QuoteRevision, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) GetQuoteRevisions(ctx context.Context, quoteId ID) ([]*QuoteRevision, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) CreateQuoteTemplate(ctx context.Context, template QuoteTemplate) (*QuoteTemplate, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	doc := f.fire.Collection(string(QuoteTemplateCollection)).NewDoc()
+
+	template.Id = doc.ID
+	template.OrgId = orgId
+	template.GroupId = groupId
+	template.Created = now
+	template.Updated = now
+	template.Hidden = false
+
+	_, err = doc.Create(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+func (f *Firebase) GetQuoteTemplate(ctx context.Context, id ID) (*QuoteTemplate, error) {
+	data := &QuoteTemplate{}
+	err := f.get(ctx, QuoteTemplateCollection, id, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (f *Firebase) GetQuoteTemplates(ctx context.Context) ([]*QuoteTemplate, error) {
+	docs, err := f.query(ctx, QuoteTemplateCollection,
+		firestore.PropertyFilter{Path: "hidden", Operator: "==", Value: false},
+	)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := docsToType[QuoteTemplate](docs)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(templates, func(a, b *QuoteTemplate) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return templates, nil
+}
+
+// SearchQuoteTemplates matches the search against the name and description of
+// each of the group's templates. Firestore has no substring queries, so the
+// matching is done here; a group's templates are few.
+func (f *Firebase) SearchQuoteTemplates(ctx context.Context, search string) ([]*QuoteTemplate, error) {
+	templates, err := f.GetQuoteTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	search = strings.ToLower(search)
+	return slices.DeleteFunc(templates, func(t *QuoteTemplate) bool {
+		return !strings.Contains(strings.ToLower(t.Name), search) && !strings.Contains(strings.ToLower(t.Description), search)
+	}), nil
+}
+
+func (f *Firebase) UpdateQuoteTemplateInfo(ctx context.Context, id ID, name, description string) (*QuoteTemplate, error) {
+	return update[QuoteTemplate](f, ctx, QuoteTemplateCollection, id,
+		field{"Name", name},
+		field{"Description", description},
+	)
+}
+
+func (f *Firebase) DeleteQuoteTemplate(ctx context.Context, id ID) error {
+	_, err := update[QuoteTemplate](f, ctx, QuoteTemplateCollection, id,
+		field{Name: "Hidden", Value: true},
+	)
+	return err
+}
+
+// quoteNumberSequenceDocId is the id of the group's sequence document.
+func quoteNumberSequenceDocId(orgId, groupId ID) string {
+	return orgId + "_" + groupId
+}
+
+func (f *Firebase) SetQuoteNumberSequence(ctx context.Context, seq QuoteNumberSequence) (*QuoteNumberSequence, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc := f.fire.Collection(string(QuoteNumberSequenceCollection)).Doc(quoteNumberSequenceDocId(orgId, groupId))
+	var data QuoteNumberSequence
+	err = f.fire.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		data = QuoteNumberSequence{}
+		snap, err := tx.Get(doc)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			err = snap.DataTo(&data)
+			if err != nil {
+				return err
+			}
+		}
+		// the counter carries on from where it was
+		data.OrgId = orgId
+		data.GroupId = groupId
+		data.Pattern = seq.Pattern
+		data.AssignOn = seq.AssignOn
+		data.ResetYearly = seq.ResetYearly
+		data.Updated = time.Now()
+		return tx.Set(doc, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (f *Firebase) GetQuoteNumberSequence(ctx context.Context) (*QuoteNumberSequence, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := &QuoteNumberSequence{}
+	err = f.get(ctx, QuoteNumberSequenceCollection, quoteNumberSequenceDocId(orgId, groupId), data)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (f *Firebase) SetQuotePolicy(ctx context.Context, policy QuotePolicy) (*QuotePolicy, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policy.OrgId = orgId
+	policy.GroupId = groupId
+	if policy.MaxDiscount == nil {
+		policy.MaxDiscount = map[Role]int{}
+	}
+	if policy.ApproverRoles == nil {
+		policy.ApproverRoles = []Role{}
+	}
+	policy.Updated = time.Now()
+	_, err = f.fire.Collection(string(QuotePolicyCollection)).Doc(quoteNumberSequenceDocId(orgId, groupId)).Set(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (f *Firebase) GetQuotePolicy(ctx context.Context) (*QuotePolicy, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := &QuotePolicy{}
+	err = f.get(ctx, QuotePolicyCollection, quoteNumberSequenceDocId(orgId, groupId), data)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// AllocateQuoteNumber hands out the group's next number inside a Firestore
+// transaction, which is retried when another allocation changes the counter
+// first.
+func (f *Firebase) AllocateQuoteNumber(ctx context.Context, year int) (*QuoteNumberSequence, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc := f.fire.Collection(string(QuoteNumberSequenceCollection)).Doc(quoteNumberSequenceDocId(orgId, groupId))
+	var data QuoteNumberSequence
+	err = f.fire.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		data = QuoteNumberSequence{}
+		snap, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		err = snap.DataTo(&data)
+		if err != nil {
+			return err
+		}
+		advanceQuoteNumberSequence(&data, year)
+		data.Updated = time.Now()
+		return tx.Set(doc, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
 func (f *Firebase) QuoteAddLineItem(ctx context.Context, id ID, lineItemId ID) (*Quote, error) {
 	// TODO: implement me
 	return nil, nil
@@ -1396,6 +1749,11 @@ func (f *Firebase) QuoteRemoveAdjustment(ctx context.Context, id ID, adjustmentI
 	return nil, nil
 }
 
+func (f *Firebase) QuoteAddOption(ctx context.Context, id ID, name, description string) (*QuoteOption, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
 // ////////////
 // LINE ITEM
 // ////////////
@@ -1410,7 +1768,7 @@ func (f *Firebase) CreateSubLineItem(ctx context.Context, quoteId, parentId ID,
 	return nil, nil
 }
 
-func (f *Firebase) CreateDuplicateLineItem(ctx context.Context, id ID) (*LineItem, error) {
+func (f *Firebase) CreateDuplicateLineItem(ctx context.Context, id ID, quoteId ID, parentId *ID) (*LineItem, error) {
 	// TODO: implement me
 	return nil, nil
 }
@@ -1445,6 +1803,11 @@ func (f *Firebase) UpdateLineItemUnitPrice(ctx context.Context, id ID, unitPrice
 	return nil, nil
 }
 
+func (f *Firebase) UpdateLineItemUnitCost(ctx context.Context, id ID, unitCost int) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
 func (f *Firebase) UpdateLineItemAmount(ctx context.Context, id ID, amount *int, prefix, suffix string) (*LineItem, error) {
 	// TODO: implement me
 	return nil, nil
@@ -1455,6 +1818,56 @@ func (f *Firebase) UpdateLineItemOpen(ctx context.Context, id ID, open bool) (*L
 	return nil, nil
 }
 
+func (f *Firebase) UpdateLineItemName(ctx context.Context, id ID, name string) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateLineItemSource(ctx context.Context, id ID, itemId, priceId *ID) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateLineItemHideFromCustomer(ctx context.Context, id ID, hideFromCustomer bool) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateLineItemOptional(ctx context.Context, id ID, optional bool) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateLineItemAlternateGroup(ctx context.Context, id ID, alternateGroup string) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateLineItemSelected(ctx context.Context, id ID, selected bool) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateLineItemOptionId(ctx context.Context, id ID, optionId ID) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) UpdateLineItemRemovesLineItemId(ctx context.Context, id ID, removesLineItemId ID) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) LineItemAddSubItem(ctx context.Context, id ID, subItemId ID) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
+func (f *Firebase) LineItemRemoveSubItem(ctx context.Context, id ID, subItemId ID) (*LineItem, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
 func (f *Firebase) DeleteLineItem(ctx context.Context, id ID) error {
 	// TODO: implement me
 	return nil
@@ -1479,6 +1892,11 @@ func (f *Firebase) UpdateAdjustment(ctx context.Context, id ID, description stri
 	return nil, nil
 }
 
+func (f *Firebase) UpdateAdjustmentOptionId(ctx context.Context, id ID, optionId ID) (*Adjustment, error) {
+	// TODO: implement me once quotes are stored
+	return nil, QuotesNotImplementedError
+}
+
 func (f *Firebase) RemoveAdjustment(ctx context.Context, id ID) error {
 	// TODO: implement me
 	return nil
@@ -1488,9 +1906,466 @@ func (f *Firebase) RemoveAdjustment(ctx context.Context, id ID) error {
 // CONTACT
 // ////////////
 
+func (f *Firebase) CreateContact(ctx context.Context, contact Contact) (*Contact, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	doc := f.fire.Collection(string(ContactCollection)).NewDoc()
+
+	contact.Id = doc.ID
+	contact.OrgId = orgId
+	contact.GroupId = groupId
+	contact.Created = now
+	contact.Updated = now
+	contact.Hidden = false
+	if contact.Phones == nil {
+		contact.Phones = []string{}
+	}
+	if contact.Emails == nil {
+		contact.Emails = []string{}
+	}
+	if contact.Websites == nil {
+		contact.Websites = []string{}
+	}
+
+	_, err = doc.Create(ctx, contact)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contact, nil
+}
+
 func (f *Firebase) GetContact(ctx context.Context, id ID) (*Contact, error) {
-	// TODO: implement me
-	return nil, nil
+	data := &Contact{}
+	err := f.get(ctx, ContactCollection, id, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (f *Firebase) GetContacts(ctx context.Context) ([]*Contact, error) {
+	docs, err := f.query(ctx, ContactCollection,
+		firestore.PropertyFilter{Path: "hidden", Operator: "==", Value: false},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return docsToType[Contact](docs)
+}
+
+func (f *Firebase) SearchContacts(ctx context.Context, search string) ([]*Contact, error) {
+	contacts, err := f.GetContacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(contacts, func(c *Contact) bool {
+		return !searchContact(c, search)
+	}), nil
+}
+
+func (f *Firebase) UpdateContact(ctx context.Context, contact Contact) (*Contact, error) {
+	return update[Contact](f, ctx, ContactCollection, contact.Id,
+		field{"Name", contact.Name},
+		field{"CompanyName", contact.CompanyName},
+		field{"Phones", contact.Phones},
+		field{"Emails", contact.Emails},
+		field{"Websites", contact.Websites},
+		field{"Street", contact.Street},
+		field{"City", contact.City},
+		field{"State", contact.State},
+		field{"Zip", contact.Zip},
+	)
+}
+
+func (f *Firebase) DeleteContact(ctx context.Context, id ID) error {
+	_, err := update[Contact](f, ctx, ContactCollection, id,
+		field{Name: "Hidden", Value: true},
+	)
+	return err
+}
+
+func (f *Firebase) CreateDuplicateContact(ctx context.Context, id ID) (*Contact, error) {
+	original, err := f.GetContact(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return f.CreateContact(ctx, *original)
+}
+
+// ////////////
+// CUSTOMER
+// ////////////
+
+func (f *Firebase) CreateCustomer(ctx context.Context, customer Customer) (*Customer, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	doc := f.fire.Collection(string(CustomerCollection)).NewDoc()
+
+	customer.Id = doc.ID
+	customer.OrgId = orgId
+	customer.GroupId = groupId
+	customer.Created = now
+	customer.Updated = now
+	customer.Hidden = false
+	if customer.ContactIds == nil {
+		customer.ContactIds = []ID{}
+	}
+	if customer.AddressIds == nil {
+		customer.AddressIds = []ID{}
+	}
+	if customer.Tags == nil {
+		customer.Tags = []string{}
+	}
+
+	_, err = doc.Create(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &customer, nil
+}
+
+func (f *Firebase) GetCustomer(ctx context.Context, id ID) (*Customer, error) {
+	data := &Customer{}
+	err := f.get(ctx, CustomerCollection, id, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (f *Firebase) GetCustomers(ctx context.Context) ([]*Customer, error) {
+	docs, err := f.query(ctx, CustomerCollection,
+		firestore.PropertyFilter{Path: "hidden", Operator: "==", Value: false},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return docsToType[Customer](docs)
+}
+
+func (f *Firebase) SearchCustomers(ctx context.Context, search string) ([]*Customer, error) {
+	customers, err := f.GetCustomers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// deleted contacts still describe the customers they are attached to
+	docs, err := f.query(ctx, ContactCollection)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := docsToType[Contact](docs)
+	if err != nil {
+		return nil, err
+	}
+	byId := map[ID]*Contact{}
+	for _, c := range contacts {
+		byId[c.Id] = c
+	}
+	return slices.DeleteFunc(customers, func(c *Customer) bool {
+		return !searchCustomer(c, byId, search)
+	}), nil
+}
+
+func (f *Firebase) UpdateCustomerInfo(ctx context.Context, id ID, name string, tags []string, notes string) (*Customer, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	return update[Customer](f, ctx, CustomerCollection, id,
+		field{"Name", name},
+		field{"Tags", tags},
+		field{"Notes", notes},
+	)
+}
+
+func (f *Firebase) CustomerAddContact(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	c, err := f.GetCustomer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.GetContact(ctx, contactId); err != nil {
+		return nil, err
+	}
+	if slices.Contains(c.ContactIds, contactId) {
+		return c, nil
+	}
+	return update[Customer](f, ctx, CustomerCollection, id,
+		field{"ContactIds", append(slices.Clone(c.ContactIds), contactId)},
+	)
+}
+
+func (f *Firebase) CustomerRemoveContact(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	c, err := f.GetCustomer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return update[Customer](f, ctx, CustomerCollection, id,
+		field{"ContactIds", slices.DeleteFunc(slices.Clone(c.ContactIds), func(i ID) bool { return i == contactId })},
+	)
+}
+
+func (f *Firebase) CustomerAddAddress(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	c, err := f.GetCustomer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.GetContact(ctx, contactId); err != nil {
+		return nil, err
+	}
+	if slices.Contains(c.AddressIds, contactId) {
+		return c, nil
+	}
+	return update[Customer](f, ctx, CustomerCollection, id,
+		field{"AddressIds", append(slices.Clone(c.AddressIds), contactId)},
+	)
+}
+
+func (f *Firebase) CustomerRemoveAddress(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	c, err := f.GetCustomer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return update[Customer](f, ctx, CustomerCollection, id,
+		field{"AddressIds", slices.DeleteFunc(slices.Clone(c.AddressIds), func(i ID) bool { return i == contactId })},
+	)
+}
+
+func (f *Firebase) DeleteCustomer(ctx context.Context, id ID) error {
+	_, err := update[Customer](f, ctx, CustomerCollection, id,
+		field{Name: "Hidden", Value: true},
+	)
+	return err
+}
+
+// ////////////
+// PAYMENT
+// ////////////
+
+func (f *Firebase) CreatePayment(ctx context.Context, payment Payment) (*Payment, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	doc := f.fire.Collection(string(PaymentCollection)).NewDoc()
+
+	payment.Id = doc.ID
+	payment.OrgId = orgId
+	payment.GroupId = groupId
+	payment.Created = now
+	payment.Updated = now
+	payment.Hidden = false
+
+	_, err = doc.Create(ctx, payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+func (f *Firebase) GetPayment(ctx context.Context, id ID) (*Payment, error) {
+	data := &Payment{}
+	err := f.get(ctx, PaymentCollection, id, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (f *Firebase) GetQuotePayments(ctx context.Context, quoteId ID) ([]*Payment, error) {
+	docs, err := f.query(ctx, PaymentCollection,
+		firestore.PropertyFilter{Path: "quoteId", Operator: "==", Value: quoteId},
+		firestore.PropertyFilter{Path: "hidden", Operator: "==", Value: false},
+	)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := docsToType[Payment](docs)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(payments, func(a, b *Payment) int {
+		return a.ReceivedOn.Compare(b.ReceivedOn)
+	})
+	return payments, nil
+}
+
+func (f *Firebase) DeletePayment(ctx context.Context, id ID) error {
+	_, err := update[Payment](f, ctx, PaymentCollection, id,
+		field{Name: "Hidden", Value: true},
+	)
+	return err
+}
+
+// ////////////
+// INVOICE
+// ////////////
+
+func (f *Firebase) CreateInvoice(ctx context.Context, invoice Invoice) (*Invoice, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	doc := f.fire.Collection(string(InvoiceCollection)).NewDoc()
+
+	invoice.Id = doc.ID
+	invoice.OrgId = orgId
+	invoice.GroupId = groupId
+	invoice.Created = now
+	invoice.Updated = now
+	invoice.Hidden = false
+
+	_, err = doc.Create(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	return &invoice, nil
+}
+
+func (f *Firebase) GetInvoice(ctx context.Context, id ID) (*Invoice, error) {
+	data := &Invoice{}
+	err := f.get(ctx, InvoiceCollection, id, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetQuoteInvoices returns the invoices made from the quote, oldest first.
+// Deleted invoices are left out.
+func (f *Firebase) GetQuoteInvoices(ctx context.Context, quoteId ID) ([]*Invoice, error) {
+	docs, err := f.query(ctx, InvoiceCollection,
+		firestore.PropertyFilter{Path: "quoteId", Operator: "==", Value: quoteId},
+		firestore.PropertyFilter{Path: "hidden", Operator: "==", Value: false},
+	)
+	if err != nil {
+		return nil, err
+	}
+	invoices, err := docsToType[Invoice](docs)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(invoices, func(a, b *Invoice) int {
+		return a.Created.Compare(b.Created)
+	})
+	return invoices, nil
+}
+
+func (f *Firebase) UpdateInvoiceDueDate(ctx context.Context, id ID, dueDate *time.Time) (*Invoice, error) {
+	return update[Invoice](f, ctx, InvoiceCollection, id,
+		field{"DueDate", dueDate},
+	)
+}
+
+func (f *Firebase) UpdateInvoicePaymentTerms(ctx context.Context, id ID, paymentTerms string) (*Invoice, error) {
+	return update[Invoice](f, ctx, InvoiceCollection, id,
+		field{"PaymentTerms", paymentTerms},
+	)
+}
+
+func (f *Firebase) DeleteInvoice(ctx context.Context, id ID) error {
+	_, err := update[Invoice](f, ctx, InvoiceCollection, id,
+		field{Name: "Hidden", Value: true},
+	)
+	return err
+}
+
+func (f *Firebase) SetInvoiceNumberSequence(ctx context.Context, seq InvoiceNumberSequence) (*InvoiceNumberSequence, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc := f.fire.Collection(string(InvoiceNumberSequenceCollection)).Doc(quoteNumberSequenceDocId(orgId, groupId))
+	var data InvoiceNumberSequence
+	err = f.fire.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		data = InvoiceNumberSequence{}
+		snap, err := tx.Get(doc)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			err = snap.DataTo(&data)
+			if err != nil {
+				return err
+			}
+		}
+		// the counter carries on from where it was
+		data.OrgId = orgId
+		data.GroupId = groupId
+		data.Pattern = seq.Pattern
+		data.ResetYearly = seq.ResetYearly
+		data.Updated = time.Now()
+		return tx.Set(doc, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (f *Firebase) GetInvoiceNumberSequence(ctx context.Context) (*InvoiceNumberSequence, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := &InvoiceNumberSequence{}
+	err = f.get(ctx, InvoiceNumberSequenceCollection, quoteNumberSequenceDocId(orgId, groupId), data)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// AllocateInvoiceNumber hands out the group's next invoice number inside a
+// Firestore transaction, which is retried when another allocation changes the
+// counter first. A group without a sequence starts one with
+// DefaultInvoiceNumberPattern.
+func (f *Firebase) AllocateInvoiceNumber(ctx context.Context, year int) (*InvoiceNumberSequence, error) {
+	orgId, groupId, err := f.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc := f.fire.Collection(string(InvoiceNumberSequenceCollection)).Doc(quoteNumberSequenceDocId(orgId, groupId))
+	var data InvoiceNumberSequence
+	err = f.fire.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		data = InvoiceNumberSequence{}
+		snap, err := tx.Get(doc)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			err = snap.DataTo(&data)
+			if err != nil {
+				return err
+			}
+		}
+		data.OrgId = orgId
+		data.GroupId = groupId
+		advanceInvoiceNumberSequence(&data, year)
+		data.Updated = time.Now()
+		return tx.Set(doc, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
 }
 
 // ////////////
@@ -0,0 +1,491 @@
+package pricey
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Formula custom values use a deliberately small expression language:
+//
+//	number      1, 2.5, .75
+//	identifier  another custom value key, or one of the reserved names below
+//	operators   + - * / with the usual precedence, unary minus and parentheses
+//	functions   min(a, b, ...), max(a, b, ...), round(a), ceil(a), floor(a)
+//
+// The reserved names are `cost` (Item.Cost) and `price` (the amount of the
+// item's default price). Both are in pennies, like every other amount in the
+// library, and they take precedence over custom values with the same key.
+// There are no assignments, loops or side effects, so evaluating a formula can
+// never do anything other than return a number or an error.
+
+const (
+	FormulaVarCost  = "cost"
+	FormulaVarPrice = "price"
+
+	maxFormulaLength = 1000
+	maxFormulaDepth  = 50
+)
+
+var (
+	FormulaSyntaxError          = errors.New("formula is not valid")
+	FormulaCycleError           = errors.New("formula depends on itself")
+	FormulaUnknownVariableError = errors.New("formula references an unknown custom value")
+	FormulaNotNumberError       = errors.New("formula references a value that is not a number")
+	FormulaDivideByZeroError    = errors.New("formula divided by zero")
+	FormulaNotFoundError        = errors.New("price formula key does not match a formula descriptor")
+)
+
+type formulaExpr interface {
+	eval(vars map[string]float64) (float64, error)
+}
+
+type formulaNumber float64
+
+type formulaVar string
+
+type formulaUnary struct {
+	op rune
+	x  formulaExpr
+}
+
+type formulaBinary struct {
+	op   rune
+	l, r formulaExpr
+}
+
+type formulaCall struct {
+	name string
+	args []formulaExpr
+}
+
+func (n formulaNumber) eval(map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (n formulaVar) eval(vars map[string]float64) (float64, error) {
+	v, ok := vars[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", FormulaUnknownVariableError, string(n))
+	}
+	return v, nil
+}
+
+func (n formulaUnary) eval(vars map[string]float64) (float64, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	return -x, nil
+}
+
+func (n formulaBinary) eval(vars map[string]float64) (float64, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return 0, FormulaDivideByZeroError
+		}
+		return l / r, nil
+	}
+	return 0, FormulaSyntaxError
+}
+
+func (n formulaCall) eval(vars map[string]float64) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	switch n.name {
+	case "min":
+		return slices.Min(args), nil
+	case "max":
+		return slices.Max(args), nil
+	case "round":
+		return math.Round(args[0]), nil
+	case "ceil":
+		return math.Ceil(args[0]), nil
+	case "floor":
+		return math.Floor(args[0]), nil
+	}
+	return 0, FormulaSyntaxError
+}
+
+// formulaVars returns every identifier referenced by the expression.
+func formulaVars(e formulaExpr) []string {
+	var names []string
+	var walk func(e formulaExpr)
+	walk = func(e formulaExpr) {
+		switch n := e.(type) {
+		case formulaVar:
+			if !slices.Contains(names, string(n)) {
+				names = append(names, string(n))
+			}
+		case formulaUnary:
+			walk(n.x)
+		case formulaBinary:
+			walk(n.l)
+			walk(n.r)
+		case formulaCall:
+			for _, a := range n.args {
+				walk(a)
+			}
+		}
+	}
+	walk(e)
+	return names
+}
+
+// formulaParser is a recursive descent parser over the raw formula string.
+type formulaParser struct {
+	src   string
+	pos   int
+	depth int
+}
+
+func parseFormula(src string) (formulaExpr, error) {
+	if len(src) > maxFormulaLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", FormulaSyntaxError, maxFormulaLength)
+	}
+	p := &formulaParser{src: src}
+	e, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos])
+	}
+	return e, nil
+}
+
+func (p *formulaParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", FormulaSyntaxError, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *formulaParser) skipSpace() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *formulaParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *formulaParser) parseSum() (formulaExpr, error) {
+	l, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for c := p.peek(); c == '+' || c == '-'; c = p.peek() {
+		p.pos++
+		r, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l = formulaBinary{op: rune(c), l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *formulaParser) parseProduct() (formulaExpr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for c := p.peek(); c == '*' || c == '/'; c = p.peek() {
+		p.pos++
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = formulaBinary{op: rune(c), l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *formulaParser) parseUnary() (formulaExpr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxFormulaDepth {
+		return nil, p.errorf("nested deeper than %d", maxFormulaDepth)
+	}
+	switch c := p.peek(); {
+	case c == '-':
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return formulaUnary{op: '-', x: x}, nil
+	case c == '+':
+		p.pos++
+		return p.parseUnary()
+	case c == '(':
+		p.pos++
+		e, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("expected )")
+		}
+		p.pos++
+		return e, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case isFormulaIdentStart(c):
+		return p.parseIdentifier()
+	case c == 0:
+		return nil, p.errorf("unexpected end of formula")
+	default:
+		return nil, p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *formulaParser) parseNumber() (formulaExpr, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] == '.' || (p.src[p.pos] >= '0' && p.src[p.pos] <= '9')) {
+		p.pos++
+	}
+	text := p.src[start:p.pos]
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("invalid number %q", text)
+	}
+	return formulaNumber(v), nil
+}
+
+func (p *formulaParser) parseIdentifier() (formulaExpr, error) {
+	start := p.pos
+	for p.pos < len(p.src) && isFormulaIdentPart(p.src[p.pos]) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if p.peek() != '(' {
+		return formulaVar(name), nil
+	}
+
+	arity, ok := map[string]int{"min": -1, "max": -1, "round": 1, "ceil": 1, "floor": 1}[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function %q", name)
+	}
+	p.pos++
+	var args []formulaExpr
+	for p.peek() != ')' {
+		if len(args) > 0 {
+			if p.peek() != ',' {
+				return nil, p.errorf("expected , or )")
+			}
+			p.pos++
+		}
+		a, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	p.pos++
+	if len(args) == 0 || (arity > 0 && len(args) != arity) {
+		return nil, p.errorf("wrong number of arguments to %s", name)
+	}
+	return formulaCall{name: name, args: args}, nil
+}
+
+func isFormulaIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isFormulaIdentPart(c byte) bool {
+	return isFormulaIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// formulaOrder parses every formula descriptor in the config and returns them
+// in an order where each formula comes after the formulas it depends on. It
+// fails with FormulaCycleError when formulas reference each other in a loop,
+// and with FormulaUnknownVariableError when a formula references a key the
+// config does not describe.
+func formulaOrder(config *CustomValueConfig) ([]ID, map[ID]formulaExpr, error) {
+	exprs := map[ID]formulaExpr{}
+	described := map[string]bool{FormulaVarCost: true, FormulaVarPrice: true}
+	for _, d := range config.Descriptors {
+		described[d.Key] = true
+		if d.ValueType != CustomValueTypeFormula {
+			continue
+		}
+		e, err := parseFormula(d.DefaultValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", d.Key, err)
+		}
+		exprs[d.Key] = e
+	}
+
+	var order []ID
+	state := map[ID]int{} // 0 unvisited, 1 visiting, 2 done
+	var visit func(key ID) error
+	visit = func(key ID) error {
+		switch state[key] {
+		case 1:
+			return fmt.Errorf("%w: %s", FormulaCycleError, key)
+		case 2:
+			return nil
+		}
+		state[key] = 1
+		for _, name := range formulaVars(exprs[key]) {
+			if !described[name] {
+				return fmt.Errorf("%s: %w: %s", key, FormulaUnknownVariableError, name)
+			}
+			if _, ok := exprs[name]; ok {
+				if err := visit(name); err != nil {
+					return err
+				}
+			}
+		}
+		state[key] = 2
+		order = append(order, key)
+		return nil
+	}
+	for _, d := range config.Descriptors {
+		if _, ok := exprs[d.Key]; ok {
+			if err := visit(d.Key); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if config.PriceFormulaKey != nil {
+		if _, ok := exprs[*config.PriceFormulaKey]; !ok {
+			return nil, nil, FormulaNotFoundError
+		}
+		// a formula that drives the price cannot read the price it is writing
+		if formulaDependsOn(*config.PriceFormulaKey, FormulaVarPrice, exprs, map[ID]bool{}) {
+			return nil, nil, fmt.Errorf("%w: %s uses %s", FormulaCycleError, *config.PriceFormulaKey, FormulaVarPrice)
+		}
+	}
+	return order, exprs, nil
+}
+
+func formulaDependsOn(key ID, name string, exprs map[ID]formulaExpr, visited map[ID]bool) bool {
+	if visited[key] {
+		return false
+	}
+	visited[key] = true
+	for _, v := range formulaVars(exprs[key]) {
+		if v == name {
+			return true
+		}
+		if _, ok := exprs[v]; ok && formulaDependsOn(v, name, exprs, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFormulas checks that every formula in the config parses, references
+// only described keys and is free of dependency cycles.
+func validateFormulas(config *CustomValueConfig) error {
+	_, _, err := formulaOrder(config)
+	return err
+}
+
+// evaluateFormulas computes every formula descriptor of the config against the
+// effective values of an item and replaces their Value with the result.
+func evaluateFormulas(config *CustomValueConfig, effective *EffectiveCustomValues, cost, price int) error {
+	if config == nil {
+		return nil
+	}
+	order, exprs, err := formulaOrder(config)
+	if err != nil {
+		return err
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	vars := map[string]float64{
+		FormulaVarCost:  float64(cost),
+		FormulaVarPrice: float64(price),
+	}
+	index := map[ID]int{}
+	for i, ev := range effective.Values {
+		index[ev.Descriptor.Key] = i
+		if ev.Descriptor.ValueType != CustomValueTypeNumber || ev.Descriptor.Key == FormulaVarCost || ev.Descriptor.Key == FormulaVarPrice {
+			continue
+		}
+		if strings.TrimSpace(ev.Value) == "" {
+			vars[ev.Descriptor.Key] = 0
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(ev.Value), 64)
+		if err != nil {
+			// leave unparsable numbers out so only formulas that use them fail
+			continue
+		}
+		vars[ev.Descriptor.Key] = v
+	}
+
+	for _, key := range order {
+		for _, name := range formulaVars(exprs[key]) {
+			if _, ok := vars[name]; !ok {
+				return fmt.Errorf("%s: %w: %s", key, FormulaNotNumberError, name)
+			}
+		}
+		v, err := exprs[key].eval(vars)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		vars[key] = v
+		if i, ok := index[key]; ok {
+			effective.Values[i].Value = strconv.FormatFloat(v, 'f', -1, 64)
+			effective.Values[i].IsDefault = false
+		}
+	}
+	return nil
+}
+
+// formulaPrice returns the amount the config's price formula computes for the
+// item, rounded to the nearest penny. ok is false when the config has no price
+// formula.
+func formulaPrice(config *CustomValueConfig, effective *EffectiveCustomValues) (amount int, ok bool, err error) {
+	if config == nil || config.PriceFormulaKey == nil {
+		return 0, false, nil
+	}
+	for _, ev := range effective.Values {
+		if ev.Descriptor.Key != *config.PriceFormulaKey {
+			continue
+		}
+		v, err := strconv.ParseFloat(ev.Value, 64)
+		if err != nil {
+			return 0, false, err
+		}
+		return int(math.Round(v)), true, nil
+	}
+	return 0, false, FormulaNotFoundError
+}
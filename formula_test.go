@@ -0,0 +1,167 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormulaEval(t *testing.T) {
+	vars := map[string]float64{"laborHours": 2, "groupLaborRate": 9000, "cost": 10000}
+	testCases := []struct {
+		name     string
+		formula  string
+		expected float64
+		err      error
+	}{
+		{name: "number", formula: "42", expected: 42},
+		{name: "decimal", formula: ".5 + 1.25", expected: 1.75},
+		{name: "precedence", formula: "1 + 2 * 3", expected: 7},
+		{name: "parentheses", formula: "(1 + 2) * 3", expected: 9},
+		{name: "left associative", formula: "10 - 4 - 3", expected: 3},
+		{name: "unary minus", formula: "-2 * -3", expected: 6},
+		{name: "variables", formula: "laborHours * groupLaborRate + cost * 1.35", expected: 31500},
+		{name: "functions", formula: "max(1, min(5, 3), 2) + round(2.5) + ceil(0.1) + floor(1.9)", expected: 8},
+		{name: "unknown variable", formula: "missing + 1", err: FormulaUnknownVariableError},
+		{name: "divide by zero", formula: "1 / (2 - 2)", err: FormulaDivideByZeroError},
+		{name: "unknown function", formula: "exec(1)", err: FormulaSyntaxError},
+		{name: "wrong arity", formula: "round(1, 2)", err: FormulaSyntaxError},
+		{name: "dangling operator", formula: "1 +", err: FormulaSyntaxError},
+		{name: "unbalanced", formula: "(1 + 2", err: FormulaSyntaxError},
+		{name: "trailing input", formula: "1 2", err: FormulaSyntaxError},
+		{name: "bad number", formula: "1.2.3", err: FormulaSyntaxError},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e, err := parseFormula(tc.formula)
+			if err == nil {
+				var v float64
+				v, err = e.eval(vars)
+				if tc.err == nil {
+					assert.InDelta(t, tc.expected, v, 0.000001)
+				}
+			}
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFormulaOrder(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	number := func(key ID) CustomValueDescriptor {
+		return CustomValueDescriptor{Key: key, ValueType: CustomValueTypeNumber}
+	}
+	formula := func(key ID, f string) CustomValueDescriptor {
+		return CustomValueDescriptor{Key: key, DefaultValue: f, ValueType: CustomValueTypeFormula}
+	}
+	testCases := []struct {
+		name   string
+		config *CustomValueConfig
+		err    error
+	}{
+		{
+			name: "dependencies",
+			config: &CustomValueConfig{Descriptors: []CustomValueDescriptor{
+				formula("total", "labor + cost"),
+				formula("labor", "hours * 100"),
+				number("hours"),
+			}},
+		},
+		{
+			name: "self reference",
+			config: &CustomValueConfig{Descriptors: []CustomValueDescriptor{
+				formula("a", "a + 1"),
+			}},
+			err: FormulaCycleError,
+		},
+		{
+			name: "indirect cycle",
+			config: &CustomValueConfig{Descriptors: []CustomValueDescriptor{
+				formula("a", "b + 1"),
+				formula("b", "c + 1"),
+				formula("c", "a + 1"),
+			}},
+			err: FormulaCycleError,
+		},
+		{
+			name: "unknown key",
+			config: &CustomValueConfig{Descriptors: []CustomValueDescriptor{
+				formula("a", "hours * 2"),
+			}},
+			err: FormulaUnknownVariableError,
+		},
+		{
+			name: "price formula reads price",
+			config: &CustomValueConfig{
+				PriceFormulaKey: s("b"),
+				Descriptors: []CustomValueDescriptor{
+					formula("a", "price * 2"),
+					formula("b", "a + 1"),
+				},
+			},
+			err: FormulaCycleError,
+		},
+		{
+			name: "price formula key is not a formula",
+			config: &CustomValueConfig{
+				PriceFormulaKey: s("hours"),
+				Descriptors:     []CustomValueDescriptor{number("hours")},
+			},
+			err: FormulaNotFoundError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			order, _, err := formulaOrder(tc.config)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []ID{"labor", "total"}, order)
+		})
+	}
+}
+
+func TestEvaluateFormulas(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	config := &CustomValueConfig{
+		Id:              "cfg",
+		PriceFormulaKey: s("suggested"),
+		Descriptors: []CustomValueDescriptor{
+			{Key: "laborHours", DefaultValue: "1", ValueType: CustomValueTypeNumber},
+			{Key: "groupLaborRate", DefaultValue: "9000", ValueType: CustomValueTypeNumber},
+			{Key: "cost", DefaultValue: "5", ValueType: CustomValueTypeNumber},
+			{Key: "suggested", DefaultValue: "laborHours * groupLaborRate + cost * 1.35", ValueType: CustomValueTypeFormula},
+		},
+	}
+	effective := mergeCustomValues(config, []CustomValue{{Key: "laborHours", Value: "2.5"}})
+	require.NoError(t, evaluateFormulas(config, effective, 10001, 0))
+	assert.Equal(t, "36001.35", effective.Values[3].Value)
+	assert.False(t, effective.Values[3].IsDefault)
+
+	amount, ok, err := formulaPrice(config, effective)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 36001, amount)
+
+	effective = mergeCustomValues(config, []CustomValue{{Key: "laborHours", Value: "lots"}})
+	assert.ErrorIs(t, evaluateFormulas(config, effective, 0, 0), FormulaNotNumberError)
+}
@@ -0,0 +1,322 @@
+package pricey
+
+import (
+	"slices"
+	"time"
+)
+
+// DefaultInvoiceNumberPattern numbers a group's invoices until it configures
+// its own invoice number sequence.
+const DefaultInvoiceNumberPattern = "INV-{N:5}"
+
+// Invoice bills the customer for an accepted quote, or part of it. It holds its
+// own copy of the line items, adjustments and contacts so later changes to the
+// quote do not change what was invoiced. An invoice either covers top level
+// line items of the quote, listed in QuoteLineItemIds, or one milestone of its
+// payment schedule.
+type Invoice struct {
+	Id      ID `json:"id" firestore:"id"`
+	OrgId   ID `json:"orgId" firestore:"orgId"`
+	GroupId ID `json:"groupId" firestore:"groupId"`
+	QuoteId ID `json:"quoteId" firestore:"quoteId"`
+	// QuoteCode the number of the quote when it was invoiced
+	QuoteCode              string     `json:"quoteCode" firestore:"quoteCode"`
+	Number                 string     `json:"number" firestore:"number"`
+	OrderNumber            string     `json:"orderNumber" firestore:"orderNumber"`
+	LogoId                 ID         `json:"logoId" firestore:"logoId"`
+	PrimaryBackgroundColor string     `json:"primaryBackgroundColor" firestore:"primaryBackgroundColor"`
+	PrimaryTextColor       string     `json:"primaryTextColor" firestore:"primaryTextColor"`
+	IssueDate              time.Time  `json:"issueDate" firestore:"issueDate"`
+	DueDate                *time.Time `json:"dueDate" firestore:"dueDate"`
+	PaymentTerms           string     `json:"paymentTerms" firestore:"paymentTerms"`
+	Notes                  string     `json:"notes" firestore:"notes"`
+	SenderId               ID         `json:"senderId" firestore:"senderId"`
+	BillToId               ID         `json:"billToId" firestore:"billToId"`
+	ShipToId               ID         `json:"shipToId" firestore:"shipToId"`
+	PayUrl                 string     `json:"payUrl" firestore:"payUrl"`
+	// QuoteLineItemIds the top level line items of the quote the invoice covers
+	QuoteLineItemIds []ID `json:"quoteLineItemIds" firestore:"quoteLineItemIds"`
+	// MilestoneId the payment schedule milestone the invoice covers
+	MilestoneId ID            `json:"milestoneId" firestore:"milestoneId"`
+	LineItems   []*LineItem   `json:"lineItems" firestore:"lineItems"`
+	Adjustments []*Adjustment `json:"adjustments" firestore:"adjustments"`
+	Contacts    []*Contact    `json:"contacts" firestore:"contacts"`
+	SubTotal    int           `json:"subTotal" firestore:"subTotal"`
+	Total       int           `json:"total" firestore:"total"`
+	Created     time.Time     `json:"created" firestore:"created"`
+	Updated     time.Time     `json:"updated" firestore:"updated"`
+	Hidden      bool          `json:"hidden" firestore:"hidden"`
+}
+
+// InvoiceRequest says what to invoice from a quote. With neither LineItemIds
+// nor MilestoneId set, every line item not yet invoiced is.
+type InvoiceRequest struct {
+	// LineItemIds top level line items of the quote to invoice
+	LineItemIds []ID `json:"lineItemIds" firestore:"lineItemIds"`
+	// MilestoneId payment schedule milestone to invoice
+	MilestoneId ID         `json:"milestoneId" firestore:"milestoneId"`
+	DueDate     *time.Time `json:"dueDate" firestore:"dueDate"`
+	// PaymentTerms defaults to the quote's payment terms
+	PaymentTerms string `json:"paymentTerms" firestore:"paymentTerms"`
+}
+
+// InvoiceNumberSequence numbers the invoices of a group, separately from its
+// quotes. Pattern is expanded by formatQuoteNumber.
+type InvoiceNumberSequence struct {
+	OrgId       ID        `json:"orgId" firestore:"orgId"`
+	GroupId     ID        `json:"groupId" firestore:"groupId"`
+	Pattern     string    `json:"pattern" firestore:"pattern"`
+	ResetYearly bool      `json:"resetYearly" firestore:"resetYearly"`
+	Counter     int       `json:"counter" firestore:"counter"`
+	Year        int       `json:"year" firestore:"year"`
+	Updated     time.Time `json:"updated" firestore:"updated"`
+}
+
+// advanceInvoiceNumberSequence moves the sequence on to its next number for an
+// invoice numbered in year, like advanceQuoteNumberSequence. A new sequence
+// starts with DefaultInvoiceNumberPattern.
+func advanceInvoiceNumberSequence(seq *InvoiceNumberSequence, year int) {
+	if seq.Pattern == "" {
+		seq.Pattern = DefaultInvoiceNumberPattern
+	}
+	if seq.ResetYearly && seq.Year != year {
+		seq.Counter = 0
+	}
+	seq.Counter++
+	seq.Year = year
+}
+
+// PrintableInvoice represents an invoice ready to be printed.
+type PrintableInvoice struct {
+	Id                     ID                   `json:"id" firestore:"id"`
+	Number                 string               `json:"number" firestore:"number"`
+	QuoteCode              string               `json:"quoteCode" firestore:"quoteCode"`
+	OrderNumber            string               `json:"orderNumber" firestore:"orderNumber"`
+	Logo                   *Image               `json:"logo" firestore:"logo"`
+	PrimaryBackgroundColor string               `json:"primaryBackgroundColor" firestore:"primaryBackgroundColor"`
+	PrimaryTextColor       string               `json:"primaryTextColor" firestore:"primaryTextColor"`
+	IssueDate              time.Time            `json:"issueDate" firestore:"issueDate"`
+	DueDate                *time.Time           `json:"dueDate" firestore:"dueDate"`
+	PaymentTerms           string               `json:"paymentTerms" firestore:"paymentTerms"`
+	Notes                  string               `json:"notes" firestore:"notes"`
+	Sender                 *Contact             `json:"sender" firestore:"sender"`
+	BillTo                 *Contact             `json:"billTo" firestore:"billTo"`
+	ShipTo                 *Contact             `json:"shipTo" firestore:"shipTo"`
+	LineItems              []*PrintableLineItem `json:"lineItems" firestore:"lineItems"`
+	SubTotal               int                  `json:"subTotal" firestore:"subTotal"`
+	Adjustments            []*Adjustment        `json:"adjustments" firestore:"adjustments"`
+	Total                  int                  `json:"total" firestore:"total"`
+	PayUrl                 string               `json:"payUrl" firestore:"payUrl"`
+}
+
+// invoiceQuote is the invoice's copy of the quote, priced and printed like any
+// other quote.
+func invoiceQuote(inv *Invoice) (*Quote, map[ID]*LineItem, map[ID]*Adjustment, map[ID]*Contact) {
+	q := &Quote{
+		Id:                     inv.Id,
+		LogoId:                 inv.LogoId,
+		PrimaryBackgroundColor: inv.PrimaryBackgroundColor,
+		PrimaryTextColor:       inv.PrimaryTextColor,
+		SenderId:               inv.SenderId,
+		BillToId:               inv.BillToId,
+		ShipToId:               inv.ShipToId,
+		LineItemIds:            []ID{},
+		AdjustmentIds:          []ID{},
+	}
+	lineItems := map[ID]*LineItem{}
+	for _, li := range inv.LineItems {
+		q.LineItemIds = append(q.LineItemIds, li.Id)
+		lineItems[li.Id] = li
+	}
+	adjustments := map[ID]*Adjustment{}
+	for _, a := range inv.Adjustments {
+		q.AdjustmentIds = append(q.AdjustmentIds, a.Id)
+		adjustments[a.Id] = a
+	}
+	contacts := map[ID]*Contact{}
+	for _, c := range inv.Contacts {
+		contacts[c.Id] = c
+	}
+	return q, lineItems, adjustments, contacts
+}
+
+// buildInvoice copies what req asks for from an accepted quote into a new,
+// unnumbered invoice. Only line items counted in the quote's total can be
+// invoiced, each at most once, with their sub line items coming along. Percent
+// adjustments such as tax apply to the invoiced subtotal, and flat adjustments
+// are shared out in proportion to it, the final invoice taking what rounding
+// left over. A milestone invoice bills the milestone's amount as a single line.
+// A quote is invoiced by line items or by milestones, never both.
+func buildInvoice(quote *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment, contacts map[ID]*Contact, invoiced []*Invoice, req InvoiceRequest) (*Invoice, error) {
+	status := quoteStatus(quote)
+	if status != QuoteStatusAccepted && status != QuoteStatusConverted {
+		return nil, QuoteNotAcceptedError
+	}
+	if len(req.LineItemIds) > 0 && req.MilestoneId != "" {
+		return nil, InvoiceScopeError
+	}
+
+	inv := &Invoice{
+		QuoteId:                quote.Id,
+		QuoteCode:              quote.Code,
+		OrderNumber:            quote.OrderNumber,
+		LogoId:                 quote.LogoId,
+		PrimaryBackgroundColor: quote.PrimaryBackgroundColor,
+		PrimaryTextColor:       quote.PrimaryTextColor,
+		DueDate:                req.DueDate,
+		PaymentTerms:           req.PaymentTerms,
+		Notes:                  quote.Notes,
+		SenderId:               quote.SenderId,
+		BillToId:               quote.BillToId,
+		ShipToId:               quote.ShipToId,
+		PayUrl:                 quote.PayUrl,
+		QuoteLineItemIds:       []ID{},
+		LineItems:              []*LineItem{},
+		Adjustments:            []*Adjustment{},
+		Contacts:               []*Contact{},
+	}
+	if inv.PaymentTerms == "" {
+		inv.PaymentTerms = quote.PaymentTerms
+	}
+	for _, contactId := range []ID{quote.SenderId, quote.BillToId, quote.ShipToId} {
+		if c := contacts[contactId]; c != nil && !slices.Contains(inv.Contacts, c) {
+			inv.Contacts = append(inv.Contacts, c)
+		}
+	}
+
+	byMilestone := false
+	byLineItem := false
+	invoicedIds := map[ID]bool{}
+	for _, other := range invoiced {
+		if other.MilestoneId != "" {
+			byMilestone = true
+			invoicedIds[other.MilestoneId] = true
+		} else {
+			byLineItem = true
+			for _, lineItemId := range other.QuoteLineItemIds {
+				invoicedIds[lineItemId] = true
+			}
+		}
+	}
+
+	totals := calculateQuoteTotals(quote, lineItems, adjustments)
+
+	if req.MilestoneId != "" {
+		if byLineItem {
+			return nil, InvoiceScopeError
+		}
+		i := slices.IndexFunc(quote.PaymentSchedule, func(m PaymentMilestone) bool { return m.Id == req.MilestoneId })
+		if i < 0 {
+			return nil, PaymentMilestoneNotFoundError
+		}
+		if invoicedIds[req.MilestoneId] {
+			return nil, AlreadyInvoicedError
+		}
+		amount := paymentScheduleAmounts(quote.PaymentSchedule, totals.Total)[i]
+		inv.MilestoneId = req.MilestoneId
+		inv.LineItems = append(inv.LineItems, &LineItem{
+			Id:          req.MilestoneId,
+			QuoteId:     quote.Id,
+			Description: quote.PaymentSchedule[i].Description,
+			Amount:      &amount,
+			SubItemIds:  []ID{},
+		})
+		inv.SubTotal = amount
+		inv.Total = amount
+		return inv, nil
+	}
+
+	if byMilestone {
+		return nil, InvoiceScopeError
+	}
+	active := activeQuoteOptionId(quote)
+	invoiceable := func(l *LineItem) bool {
+		return l.ParentId == nil && !totals.Excluded[l.Id] && inQuoteOption(quote, l.OptionId, active)
+	}
+	if len(req.LineItemIds) == 0 {
+		for _, lineItemId := range quote.LineItemIds {
+			l := lineItems[lineItemId]
+			if l != nil && invoiceable(l) && !invoicedIds[lineItemId] {
+				inv.QuoteLineItemIds = append(inv.QuoteLineItemIds, lineItemId)
+			}
+		}
+		if len(inv.QuoteLineItemIds) == 0 {
+			return nil, AlreadyInvoicedError
+		}
+	}
+	for _, lineItemId := range req.LineItemIds {
+		l := lineItems[lineItemId]
+		if l == nil || !slices.Contains(quote.LineItemIds, lineItemId) {
+			return nil, LineItemNotOnQuoteError
+		}
+		if !invoiceable(l) {
+			return nil, InvoiceLineItemError
+		}
+		if invoicedIds[lineItemId] {
+			return nil, AlreadyInvoicedError
+		}
+		if !slices.Contains(inv.QuoteLineItemIds, lineItemId) {
+			inv.QuoteLineItemIds = append(inv.QuoteLineItemIds, lineItemId)
+		}
+	}
+
+	// the invoiced line items and their sub line items, copied in quote order
+	included := map[ID]bool{}
+	var include func(l *LineItem)
+	include = func(l *LineItem) {
+		if included[l.Id] {
+			return
+		}
+		included[l.Id] = true
+		for _, subItemId := range l.SubItemIds {
+			sub := lineItems[subItemId]
+			if sub != nil && !totals.Excluded[subItemId] && sub.ParentId != nil && *sub.ParentId == l.Id {
+				include(sub)
+			}
+		}
+	}
+	for _, lineItemId := range inv.QuoteLineItemIds {
+		include(lineItems[lineItemId])
+	}
+	for _, lineItemId := range quote.LineItemIds {
+		if !included[lineItemId] {
+			continue
+		}
+		c := *lineItems[lineItemId]
+		c.OptionId = ""
+		c.SubItemIds = slices.DeleteFunc(slices.Clone(c.SubItemIds), func(id ID) bool { return !included[id] })
+		inv.LineItems = append(inv.LineItems, &c)
+	}
+
+	subTotal := 0
+	for _, lineItemId := range inv.QuoteLineItemIds {
+		subTotal += totals.LineItemAmounts[lineItemId]
+	}
+	// shares are cut from the subtotal invoiced so far so that together they
+	// add up to the whole adjustment
+	invoicedSubTotal := 0
+	for lineItemId := range invoicedIds {
+		invoicedSubTotal += totals.LineItemAmounts[lineItemId]
+	}
+	flatShare := func(amount int) int {
+		return amount*(invoicedSubTotal+subTotal)/totals.SubTotal - amount*invoicedSubTotal/totals.SubTotal
+	}
+	for _, adjustmentId := range quote.AdjustmentIds {
+		a := adjustments[adjustmentId]
+		if a == nil || !inQuoteOption(quote, a.OptionId, active) {
+			continue
+		}
+		c := *a
+		c.OptionId = ""
+		if c.Type == AdjustmentTypeFlat && totals.SubTotal != 0 {
+			c.Amount = flatShare(a.Amount)
+		}
+		inv.Adjustments = append(inv.Adjustments, &c)
+	}
+
+	q, invLineItems, invAdjustments, _ := invoiceQuote(inv)
+	invTotals := calculateQuoteTotals(q, invLineItems, invAdjustments)
+	inv.SubTotal = invTotals.SubTotal
+	inv.Total = invTotals.Total
+	return inv, nil
+}
@@ -0,0 +1,174 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInvoice(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	quote := &Quote{
+		Id:            "q",
+		Code:          "EST-7",
+		Status:        QuoteStatusAccepted,
+		PaymentTerms:  "Net 30",
+		BillToId:      "c1",
+		LineItemIds:   []ID{"1", "2", "3", "4", "5", "6"},
+		AdjustmentIds: []ID{"tax", "fee"},
+		PaymentSchedule: []PaymentMilestone{
+			{Id: "deposit", Description: "Deposit", Type: AdjustmentTypePercent, Amount: 25},
+			{Id: "final", Description: "Completion", Type: AdjustmentTypePercent, Amount: 75},
+		},
+	}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Description: "Labor", Quantity: 100, UnitPrice: 30000},
+		"2": {Id: "2", Description: "Materials", SubItemIds: []ID{"3", "4"}},
+		"3": {Id: "3", ParentId: s("2"), Quantity: 200, UnitPrice: 5000},
+		"4": {Id: "4", ParentId: s("2"), Optional: true, Quantity: 100, UnitPrice: 9900},
+		"5": {Id: "5", Description: "Cleanup"},
+		"6": {Id: "6", Optional: true, Quantity: 100, UnitPrice: 50000},
+	}
+	adjustments := map[ID]*Adjustment{
+		"tax": {Id: "tax", Description: "Tax", Type: AdjustmentTypePercent, Amount: 10},
+		"fee": {Id: "fee", Description: "Permit", Type: AdjustmentTypeFlat, Amount: 4000},
+	}
+	contacts := map[ID]*Contact{"c1": {Id: "c1", Name: "Dana"}}
+
+	t.Run("everything", func(t *testing.T) {
+		t.Parallel()
+
+		inv, err := buildInvoice(quote, lineItems, adjustments, contacts, nil, InvoiceRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []ID{"1", "2", "5"}, inv.QuoteLineItemIds)
+		ids := []ID{}
+		for _, li := range inv.LineItems {
+			ids = append(ids, li.Id)
+		}
+		assert.Equal(t, []ID{"1", "2", "3", "5"}, ids)
+		assert.Equal(t, []ID{"3"}, inv.LineItems[1].SubItemIds)
+		assert.Equal(t, 40000, inv.SubTotal)
+		assert.Equal(t, 40000+4000+4000, inv.Total)
+		assert.Equal(t, "Net 30", inv.PaymentTerms)
+		assert.Equal(t, "EST-7", inv.QuoteCode)
+		assert.Equal(t, []*Contact{contacts["c1"]}, inv.Contacts)
+	})
+
+	t.Run("selected line items", func(t *testing.T) {
+		t.Parallel()
+
+		inv, err := buildInvoice(quote, lineItems, adjustments, contacts, nil, InvoiceRequest{LineItemIds: []ID{"1"}, PaymentTerms: "Due on receipt"})
+		require.NoError(t, err)
+		assert.Equal(t, []ID{"1"}, inv.QuoteLineItemIds)
+		require.Len(t, inv.LineItems, 1)
+		assert.Equal(t, 30000, inv.SubTotal)
+		assert.Equal(t, 3000, inv.Adjustments[1].Amount, "flat adjustments are shared out by subtotal")
+		assert.Equal(t, 30000+3000+3000, inv.Total)
+		assert.Equal(t, "Due on receipt", inv.PaymentTerms)
+	})
+
+	t.Run("the rest", func(t *testing.T) {
+		t.Parallel()
+
+		invoiced := []*Invoice{{QuoteLineItemIds: []ID{"1"}}}
+		inv, err := buildInvoice(quote, lineItems, adjustments, contacts, invoiced, InvoiceRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []ID{"2", "5"}, inv.QuoteLineItemIds)
+		assert.Equal(t, 10000, inv.SubTotal)
+	})
+
+	t.Run("milestone", func(t *testing.T) {
+		t.Parallel()
+
+		inv, err := buildInvoice(quote, lineItems, adjustments, contacts, nil, InvoiceRequest{MilestoneId: "deposit"})
+		require.NoError(t, err)
+		assert.Equal(t, ID("deposit"), inv.MilestoneId)
+		require.Len(t, inv.LineItems, 1)
+		assert.Equal(t, "Deposit", inv.LineItems[0].Description)
+		assert.Equal(t, 12000, inv.Total)
+		assert.Empty(t, inv.Adjustments)
+	})
+
+	t.Run("flat adjustments add up across invoices", func(t *testing.T) {
+		t.Parallel()
+
+		thirds := &Quote{Id: "thirds", Status: QuoteStatusAccepted, LineItemIds: []ID{"a", "b", "c"}, AdjustmentIds: []ID{"fee"}}
+		thirdsLineItems := map[ID]*LineItem{
+			"a": {Id: "a", Quantity: 100, UnitPrice: 10000},
+			"b": {Id: "b", Quantity: 100, UnitPrice: 10000},
+			"c": {Id: "c", Quantity: 100, UnitPrice: 10000},
+		}
+		fee := map[ID]*Adjustment{"fee": {Id: "fee", Type: AdjustmentTypeFlat, Amount: 1000}}
+
+		invoiced := []*Invoice{}
+		shares := []int{}
+		for _, lineItemId := range thirds.LineItemIds {
+			inv, err := buildInvoice(thirds, thirdsLineItems, fee, nil, invoiced, InvoiceRequest{LineItemIds: []ID{lineItemId}})
+			require.NoError(t, err)
+			invoiced = append(invoiced, inv)
+			shares = append(shares, inv.Adjustments[0].Amount)
+		}
+		assert.Equal(t, []int{333, 333, 334}, shares)
+	})
+
+	testCases := []struct {
+		name     string
+		quote    *Quote
+		invoiced []*Invoice
+		req      InvoiceRequest
+		expected error
+	}{
+		{name: "not accepted", quote: &Quote{Status: QuoteStatusSent}, expected: QuoteNotAcceptedError},
+		{name: "line items and milestone", quote: quote, req: InvoiceRequest{LineItemIds: []ID{"1"}, MilestoneId: "deposit"}, expected: InvoiceScopeError},
+		{name: "not on the quote", quote: quote, req: InvoiceRequest{LineItemIds: []ID{"missing"}}, expected: LineItemNotOnQuoteError},
+		{name: "sub line item", quote: quote, req: InvoiceRequest{LineItemIds: []ID{"3"}}, expected: InvoiceLineItemError},
+		{name: "unselected optional", quote: quote, req: InvoiceRequest{LineItemIds: []ID{"6"}}, expected: InvoiceLineItemError},
+		{name: "line item invoiced twice", quote: quote, invoiced: []*Invoice{{QuoteLineItemIds: []ID{"1"}}}, req: InvoiceRequest{LineItemIds: []ID{"1"}}, expected: AlreadyInvoicedError},
+		{name: "nothing left", quote: quote, invoiced: []*Invoice{{QuoteLineItemIds: []ID{"1", "2", "5"}}}, expected: AlreadyInvoicedError},
+		{name: "unknown milestone", quote: quote, req: InvoiceRequest{MilestoneId: "missing"}, expected: PaymentMilestoneNotFoundError},
+		{name: "milestone invoiced twice", quote: quote, invoiced: []*Invoice{{MilestoneId: "deposit"}}, req: InvoiceRequest{MilestoneId: "deposit"}, expected: AlreadyInvoicedError},
+		{name: "milestone after line items", quote: quote, invoiced: []*Invoice{{QuoteLineItemIds: []ID{"1"}}}, req: InvoiceRequest{MilestoneId: "deposit"}, expected: InvoiceScopeError},
+		{name: "line items after milestone", quote: quote, invoiced: []*Invoice{{MilestoneId: "deposit"}}, req: InvoiceRequest{LineItemIds: []ID{"1"}}, expected: InvoiceScopeError},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := buildInvoice(tc.quote, lineItems, adjustments, contacts, tc.invoiced, tc.req)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+}
+
+func TestAdvanceInvoiceNumberSequence(t *testing.T) {
+	testCases := []struct {
+		name            string
+		seq             InvoiceNumberSequence
+		year            int
+		expectedCounter int
+		expectedPattern string
+	}{
+		{name: "new sequence", seq: InvoiceNumberSequence{}, year: 2026, expectedCounter: 1, expectedPattern: DefaultInvoiceNumberPattern},
+		{name: "same year", seq: InvoiceNumberSequence{Pattern: "I-{N}", Counter: 41, Year: 2026, ResetYearly: true}, year: 2026, expectedCounter: 42, expectedPattern: "I-{N}"},
+		{name: "new year resets", seq: InvoiceNumberSequence{Pattern: "I-{N}", Counter: 41, Year: 2025, ResetYearly: true}, year: 2026, expectedCounter: 1, expectedPattern: "I-{N}"},
+		{name: "new year continues", seq: InvoiceNumberSequence{Pattern: "I-{N}", Counter: 41, Year: 2025}, year: 2026, expectedCounter: 42, expectedPattern: "I-{N}"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			seq := tc.seq
+			advanceInvoiceNumberSequence(&seq, tc.year)
+			assert.Equal(t, tc.expectedCounter, seq.Counter)
+			assert.Equal(t, tc.year, seq.Year)
+			assert.Equal(t, tc.expectedPattern, seq.Pattern)
+		})
+	}
+}
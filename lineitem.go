@@ -0,0 +1,551 @@
+package pricey
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+var ItemDeletedError = errors.New("item has been deleted and cannot be added to a quote")
+
+// addItemLineItem snapshots a pricebook item into a new line item on the quote,
+// nested under parentId when it is set. The item's sub items are expanded into
+// sub line items with their quantities scaled by the parent's quantity. path
+// holds the items already being expanded above this one so that an item which
+// (directly or indirectly) contains itself is only expanded once.
+func addItemLineItem(ctx context.Context, store Store, quoteId, itemId ID, priceId *ID, quantity int, parentId *ID, path map[ID]bool) (*LineItem, error) {
+	item, err := store.GetItem(ctx, itemId)
+	if err != nil {
+		return nil, err
+	}
+	if item.Hidden {
+		return nil, ItemDeletedError
+	}
+
+	var price *Price
+	if priceId != nil {
+		for i := range item.Prices {
+			if item.Prices[i].Id == *priceId {
+				price = &item.Prices[i]
+				break
+			}
+		}
+		if price == nil {
+			return nil, InvalidPriceIdError
+		}
+	} else if len(item.Prices) > 0 {
+		price = &item.Prices[0]
+	}
+
+	unitPrice := 0
+	lineQuantity := quantity
+	if price != nil {
+		unitPrice = price.Amount
+	} else if len(item.SubItems) > 0 {
+		// without a price of its own the line item's amount is the sum of its
+		// sub line items
+		lineQuantity = 0
+	}
+
+	var li *LineItem
+	if parentId == nil {
+		li, err = store.CreateLineItem(ctx, quoteId, item.Description, lineQuantity, unitPrice, nil)
+	} else {
+		li, err = store.CreateSubLineItem(ctx, quoteId, *parentId, item.Description, lineQuantity, unitPrice, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = store.QuoteAddLineItem(ctx, quoteId, li.Id)
+	if err != nil {
+		return nil, err
+	}
+	if parentId != nil {
+		_, err = store.LineItemAddSubItem(ctx, *parentId, li.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	li, err = store.UpdateLineItemName(ctx, li.Id, item.Name)
+	if err != nil {
+		return nil, err
+	}
+	if price != nil && (price.Prefix != "" || price.Suffix != "") {
+		li, err = store.UpdateLineItemUnitPrice(ctx, li.Id, unitPrice, price.Prefix, price.Suffix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if item.Cost != 0 {
+		li, err = store.UpdateLineItemUnitCost(ctx, li.Id, item.Cost)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if item.ImageId != "" {
+		imageId := item.ImageId
+		li, err = store.UpdateLineItemImage(ctx, li.Id, &imageId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var sourcePriceId *ID
+	if price != nil {
+		sourcePriceId = &price.Id
+	}
+	li, err = store.UpdateLineItemSource(ctx, li.Id, &item.Id, sourcePriceId)
+	if err != nil {
+		return nil, err
+	}
+	hide, err := itemHiddenFromCustomer(ctx, store, item)
+	if err != nil {
+		return nil, err
+	}
+	if hide {
+		li, err = store.UpdateLineItemHideFromCustomer(ctx, li.Id, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	path[item.Id] = true
+	defer delete(path, item.Id)
+	for _, sub := range item.SubItems {
+		if path[sub.SubItemID] {
+			continue
+		}
+		subItem, err := store.GetItem(ctx, sub.SubItemID)
+		if err != nil {
+			return nil, err
+		}
+		if subItem.Hidden {
+			continue
+		}
+		_, err = addItemLineItem(ctx, store, quoteId, sub.SubItemID, sub.PriceId, sub.Quantity*quantity/100, &li.Id, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(item.SubItems) > 0 {
+		li, err = store.GetLineItem(ctx, li.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return li, nil
+}
+
+// itemHiddenFromCustomer reports whether the item, its category or any of the
+// category's parents are hidden from customers.
+func itemHiddenFromCustomer(ctx context.Context, store Store, item *Item) (bool, error) {
+	if item.HideFromCustomer {
+		return true, nil
+	}
+	visited := map[ID]bool{}
+	var categoryId *ID
+	if item.CategoryId != "" {
+		categoryId = &item.CategoryId
+	}
+	for categoryId != nil && !visited[*categoryId] {
+		visited[*categoryId] = true
+		c, err := store.GetCategory(ctx, *categoryId)
+		if err != nil {
+			return false, err
+		}
+		if c.HideFromCustomer {
+			return true, nil
+		}
+		categoryId = c.ParentId
+	}
+	return false, nil
+}
+
+// priceDrift compares the line item's snapshot against its source item and
+// price. It returns nil when the line item was not added from the pricebook or
+// still matches it. A line item added without a price is compared against the
+// item's current default price.
+func priceDrift(ctx context.Context, store Store, li *LineItem) (*PriceDrift, *Price, error) {
+	if li.SourceItemId == nil {
+		return nil, nil, nil
+	}
+	item, err := store.GetItem(ctx, *li.SourceItemId)
+	if err != nil {
+		return nil, nil, err
+	}
+	drift := &PriceDrift{
+		LineItemId:         li.Id,
+		SourceItemId:       item.Id,
+		SourcePriceId:      li.SourcePriceId,
+		Description:        li.Description,
+		CurrentDescription: item.Description,
+		UnitPrice:          li.UnitPrice,
+	}
+	var price *Price
+	if li.SourcePriceId != nil {
+		for i := range item.Prices {
+			if item.Prices[i].Id == *li.SourcePriceId {
+				price = &item.Prices[i]
+				break
+			}
+		}
+		if price == nil {
+			drift.SourceMissing = true
+		}
+	} else if len(item.Prices) > 0 {
+		price = &item.Prices[0]
+	}
+	if price != nil {
+		drift.CurrentUnitPrice = price.Amount
+	}
+	if item.Hidden {
+		drift.SourceMissing = true
+	}
+	if drift.SourceMissing {
+		drift.CurrentDescription = li.Description
+		drift.CurrentUnitPrice = li.UnitPrice
+		return drift, nil, nil
+	}
+	if drift.Description == drift.CurrentDescription && drift.UnitPrice == drift.CurrentUnitPrice {
+		return nil, nil, nil
+	}
+	return drift, price, nil
+}
+
+// refreshLineItemPrice copies the current pricebook description and price onto
+// the line item. Line items that have not drifted, or whose source no longer
+// exists, are returned as they are.
+func refreshLineItemPrice(ctx context.Context, store Store, li *LineItem) (*LineItem, error) {
+	drift, price, err := priceDrift(ctx, store, li)
+	if err != nil {
+		return nil, err
+	}
+	if drift == nil || drift.SourceMissing {
+		return li, nil
+	}
+	if drift.Description != drift.CurrentDescription {
+		li, err = store.UpdateLineItemDescription(ctx, li.Id, drift.CurrentDescription)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if price != nil {
+		li, err = store.UpdateLineItemUnitPrice(ctx, li.Id, price.Amount, price.Prefix, price.Suffix)
+		if err != nil {
+			return nil, err
+		}
+		if li.SourcePriceId == nil {
+			li, err = store.UpdateLineItemSource(ctx, li.Id, li.SourceItemId, &price.Id)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return li, nil
+}
+
+// deleteLineItemTree deletes the line item and every sub line item beneath it,
+// removing each from the quote.
+func deleteLineItemTree(ctx context.Context, store Store, li *LineItem, visited map[ID]bool) error {
+	if visited[li.Id] {
+		return nil
+	}
+	visited[li.Id] = true
+	for _, subItemId := range li.SubItemIds {
+		sub, err := store.GetLineItem(ctx, subItemId)
+		if err != nil {
+			return err
+		}
+		if sub.ParentId == nil || *sub.ParentId != li.Id {
+			continue
+		}
+		err = deleteLineItemTree(ctx, store, sub, visited)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := store.QuoteRemoveLineItem(ctx, li.QuoteId, li.Id)
+	if err != nil {
+		return err
+	}
+	return store.DeleteLineItem(ctx, li.Id)
+}
+
+// lineItemChildren returns the sub line items of parentId in order, or the top
+// level line items in quote order when parentId is nil. Ids listed on a parent
+// whose line item points at a different parent are skipped.
+func lineItemChildren(quote *Quote, lineItems map[ID]*LineItem, parentId *ID) []ID {
+	children := []ID{}
+	if parentId == nil {
+		for _, lineItemId := range quote.LineItemIds {
+			if li := lineItems[lineItemId]; li != nil && li.ParentId == nil {
+				children = append(children, lineItemId)
+			}
+		}
+		return children
+	}
+	parent := lineItems[*parentId]
+	if parent == nil {
+		return children
+	}
+	for _, subItemId := range parent.SubItemIds {
+		if li := lineItems[subItemId]; li != nil && li.ParentId != nil && *li.ParentId == *parentId && !slices.Contains(children, subItemId) {
+			children = append(children, subItemId)
+		}
+	}
+	return children
+}
+
+// lineItemMove is the result of planning a line item move: the new order of
+// the quote's line items and the new sub items of every parent that changed.
+type lineItemMove struct {
+	LineItemIds []ID
+	SubItemIds  map[ID][]ID
+}
+
+// planLineItemMove works out how the quote's line item lists change when the
+// line item moves under parentId (or to the top level when nil) at index among
+// its new siblings. A nil index, or one past the end, moves it to the end.
+// LineItemIds is rebuilt depth first, so sub line items follow their parent.
+func planLineItemMove(quote *Quote, lineItems map[ID]*LineItem, id ID, parentId *ID, index *int) (*lineItemMove, error) {
+	li := lineItems[id]
+	if li == nil {
+		return nil, LineItemNotOnQuoteError
+	}
+	if parentId != nil {
+		if lineItems[*parentId] == nil {
+			return nil, LineItemNotOnQuoteError
+		}
+		visited := map[ID]bool{}
+		for ancestor := parentId; ancestor != nil && !visited[*ancestor]; {
+			if *ancestor == id {
+				return nil, LineItemCycleError
+			}
+			visited[*ancestor] = true
+			a := lineItems[*ancestor]
+			if a == nil {
+				break
+			}
+			ancestor = a.ParentId
+		}
+	}
+
+	children := map[ID][]ID{}
+	for lineItemId := range lineItems {
+		lineItemId := lineItemId
+		children[lineItemId] = lineItemChildren(quote, lineItems, &lineItemId)
+	}
+	topLevel := lineItemChildren(quote, lineItems, nil)
+
+	move := &lineItemMove{SubItemIds: map[ID][]ID{}}
+	remove := func(ids []ID) []ID {
+		return slices.DeleteFunc(slices.Clone(ids), func(i ID) bool { return i == id })
+	}
+	if li.ParentId == nil {
+		topLevel = remove(topLevel)
+	} else if _, ok := children[*li.ParentId]; ok {
+		children[*li.ParentId] = remove(children[*li.ParentId])
+		move.SubItemIds[*li.ParentId] = children[*li.ParentId]
+	}
+
+	siblings := topLevel
+	if parentId != nil {
+		siblings = children[*parentId]
+	}
+	at := len(siblings)
+	if index != nil && *index >= 0 && *index < at {
+		at = *index
+	}
+	siblings = slices.Insert(siblings, at, id)
+	if parentId == nil {
+		topLevel = siblings
+	} else {
+		children[*parentId] = siblings
+		move.SubItemIds[*parentId] = siblings
+	}
+
+	visited := map[ID]bool{}
+	var walk func(ids []ID)
+	walk = func(ids []ID) {
+		for _, lineItemId := range ids {
+			if visited[lineItemId] {
+				continue
+			}
+			visited[lineItemId] = true
+			move.LineItemIds = append(move.LineItemIds, lineItemId)
+			walk(children[lineItemId])
+		}
+	}
+	walk(topLevel)
+	// line items whose parent is missing are kept at the end rather than lost
+	for _, lineItemId := range quote.LineItemIds {
+		if !visited[lineItemId] {
+			visited[lineItemId] = true
+			move.LineItemIds = append(move.LineItemIds, lineItemId)
+		}
+	}
+	return move, nil
+}
+
+// duplicateLineItemTree copies the line item and every sub line item beneath
+// it onto quoteId under parentId, giving each copy a new id and rewiring
+// ParentId and SubItemIds to the copies. source and lineItems describe the
+// quote being copied from. Copies are appended to quoteId's line items depth
+// first.
+func duplicateLineItemTree(ctx context.Context, store Store, source *Quote, lineItems map[ID]*LineItem, li *LineItem, quoteId ID, parentId *ID, visited map[ID]bool) (*LineItem, error) {
+	visited[li.Id] = true
+	dup, err := store.CreateDuplicateLineItem(ctx, li.Id, quoteId, parentId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = store.QuoteAddLineItem(ctx, quoteId, dup.Id)
+	if err != nil {
+		return nil, err
+	}
+	if parentId != nil {
+		_, err = store.LineItemAddSubItem(ctx, *parentId, dup.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, subItemId := range lineItemChildren(source, lineItems, &li.Id) {
+		if visited[subItemId] {
+			continue
+		}
+		_, err = duplicateLineItemTree(ctx, store, source, lineItems, lineItems[subItemId], quoteId, &dup.Id, visited)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return store.GetLineItem(ctx, dup.Id)
+}
+
+// restoreLineItemTree creates a line item on quoteId under parentId from a
+// snapshot, such as one held by a template, followed by the snapshot's sub line
+// items. source and lineItems describe the snapshot's quote. When refresh is
+// set, line items with a pricebook source are repriced from the pricebook.
+func restoreLineItemTree(ctx context.Context, store Store, source *Quote, lineItems map[ID]*LineItem, li *LineItem, quoteId ID, parentId *ID, refresh bool, visited map[ID]bool) (*LineItem, error) {
+	visited[li.Id] = true
+	var restored *LineItem
+	var err error
+	if parentId == nil {
+		restored, err = store.CreateLineItem(ctx, quoteId, li.Description, li.Quantity, li.UnitPrice, li.Amount)
+	} else {
+		restored, err = store.CreateSubLineItem(ctx, quoteId, *parentId, li.Description, li.Quantity, li.UnitPrice, li.Amount)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = store.QuoteAddLineItem(ctx, quoteId, restored.Id)
+	if err != nil {
+		return nil, err
+	}
+	if parentId != nil {
+		_, err = store.LineItemAddSubItem(ctx, *parentId, restored.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	id := restored.Id
+	if li.QuantityPrefix != "" || li.QuantitySuffix != "" {
+		_, err = store.UpdateLineItemQuantity(ctx, id, li.Quantity, li.QuantityPrefix, li.QuantitySuffix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.UnitPricePrefix != "" || li.UnitPriceSuffix != "" {
+		_, err = store.UpdateLineItemUnitPrice(ctx, id, li.UnitPrice, li.UnitPricePrefix, li.UnitPriceSuffix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.AmountPrefix != "" || li.AmountSuffix != "" {
+		_, err = store.UpdateLineItemAmount(ctx, id, li.Amount, li.AmountPrefix, li.AmountSuffix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.UnitCost != 0 {
+		_, err = store.UpdateLineItemUnitCost(ctx, id, li.UnitCost)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.Name != "" {
+		_, err = store.UpdateLineItemName(ctx, id, li.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.ImageId != nil {
+		_, err = store.UpdateLineItemImage(ctx, id, li.ImageId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.SourceItemId != nil {
+		_, err = store.UpdateLineItemSource(ctx, id, li.SourceItemId, li.SourcePriceId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.HideFromCustomer {
+		_, err = store.UpdateLineItemHideFromCustomer(ctx, id, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.Open {
+		_, err = store.UpdateLineItemOpen(ctx, id, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.Optional {
+		_, err = store.UpdateLineItemOptional(ctx, id, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.AlternateGroup != "" {
+		_, err = store.UpdateLineItemAlternateGroup(ctx, id, li.AlternateGroup)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.Selected {
+		_, err = store.UpdateLineItemSelected(ctx, id, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if li.OptionId != "" {
+		_, err = store.UpdateLineItemOptionId(ctx, id, li.OptionId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	restored, err = store.GetLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if refresh {
+		restored, err = refreshLineItemPrice(ctx, store, restored)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, subItemId := range lineItemChildren(source, lineItems, &li.Id) {
+		if visited[subItemId] {
+			continue
+		}
+		_, err = restoreLineItemTree(ctx, store, source, lineItems, lineItems[subItemId], quoteId, &id, refresh, visited)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return store.GetLineItem(ctx, id)
+}
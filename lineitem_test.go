@@ -0,0 +1,255 @@
+package pricey
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanLineItemMove(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	i := func(num int) *int {
+		return &num
+	}
+	// 1
+	//   2
+	//     3
+	//   4
+	// 5
+	quote := &Quote{LineItemIds: []ID{"1", "2", "3", "4", "5"}}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", SubItemIds: []ID{"2", "4"}},
+		"2": {Id: "2", ParentId: s("1"), SubItemIds: []ID{"3"}},
+		"3": {Id: "3", ParentId: s("2")},
+		"4": {Id: "4", ParentId: s("1")},
+		"5": {Id: "5"},
+	}
+	testCases := []struct {
+		name        string
+		id          ID
+		parentId    *ID
+		index       *int
+		lineItemIds []ID
+		subItemIds  map[ID][]ID
+		err         error
+	}{
+		{
+			name:        "reorder top level",
+			id:          "5",
+			index:       i(0),
+			lineItemIds: []ID{"5", "1", "2", "3", "4"},
+			subItemIds:  map[ID][]ID{},
+		},
+		{
+			name:        "reorder siblings",
+			id:          "4",
+			parentId:    s("1"),
+			index:       i(0),
+			lineItemIds: []ID{"1", "4", "2", "3", "5"},
+			subItemIds:  map[ID][]ID{"1": {"4", "2"}},
+		},
+		{
+			name:        "reparent with sub items",
+			id:          "2",
+			parentId:    s("5"),
+			lineItemIds: []ID{"1", "4", "5", "2", "3"},
+			subItemIds:  map[ID][]ID{"1": {"4"}, "5": {"2"}},
+		},
+		{
+			name:        "move to top level past the end",
+			id:          "3",
+			index:       i(10),
+			lineItemIds: []ID{"1", "2", "4", "5", "3"},
+			subItemIds:  map[ID][]ID{"2": {}},
+		},
+		{
+			name:     "beneath itself",
+			id:       "1",
+			parentId: s("1"),
+			err:      LineItemCycleError,
+		},
+		{
+			name:     "beneath a descendant",
+			id:       "1",
+			parentId: s("3"),
+			err:      LineItemCycleError,
+		},
+		{
+			name:     "unknown parent",
+			id:       "1",
+			parentId: s("9"),
+			err:      LineItemNotOnQuoteError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			move, err := planLineItemMove(quote, lineItems, tc.id, tc.parentId, tc.index)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.lineItemIds, move.LineItemIds)
+			assert.Equal(t, tc.subItemIds, move.SubItemIds)
+		})
+	}
+}
+
+// driftStore holds a pricebook item with two prices, and a quote with a line
+// item added from each price and one added by hand.
+func driftStore() *memStore {
+	s := func(str string) *string {
+		return &str
+	}
+	m := newMemStore()
+	m.items["heater"] = &Item{
+		Id:          "heater",
+		Description: "50 gal water heater",
+		Prices: []Price{
+			{Id: "retail", Amount: 120000, Suffix: "/ea"},
+			{Id: "member", Amount: 110000},
+		},
+	}
+	m.quotes["q"] = &Quote{Id: "q", LineItemIds: []ID{"retail", "member", "custom"}, AdjustmentIds: []ID{}}
+	m.lineItems["retail"] = &LineItem{Id: "retail", QuoteId: "q", Description: "50 gal water heater", Quantity: 100, UnitPrice: 100000, SourceItemId: s("heater"), SourcePriceId: s("retail")}
+	m.lineItems["member"] = &LineItem{Id: "member", QuoteId: "q", Description: "40 gal water heater", Quantity: 100, UnitPrice: 110000, SourceItemId: s("heater"), SourcePriceId: s("member")}
+	m.lineItems["custom"] = &LineItem{Id: "custom", QuoteId: "q", Description: "Haul away", Quantity: 100, UnitPrice: 5000}
+	return m
+}
+
+func TestPriceDrift(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	testCases := []struct {
+		name     string
+		change   func(m *memStore)
+		lineItem ID
+		drift    *PriceDrift
+	}{
+		{name: "not from the pricebook", lineItem: "custom"},
+		{
+			name:     "price drift",
+			lineItem: "retail",
+			drift:    &PriceDrift{LineItemId: "retail", SourceItemId: "heater", SourcePriceId: s("retail"), Description: "50 gal water heater", CurrentDescription: "50 gal water heater", UnitPrice: 100000, CurrentUnitPrice: 120000},
+		},
+		{
+			name:     "description drift",
+			lineItem: "member",
+			drift:    &PriceDrift{LineItemId: "member", SourceItemId: "heater", SourcePriceId: s("member"), Description: "40 gal water heater", CurrentDescription: "50 gal water heater", UnitPrice: 110000, CurrentUnitPrice: 110000},
+		},
+		{
+			name:     "matches the pricebook",
+			change:   func(m *memStore) { m.lineItems["retail"].UnitPrice = 120000 },
+			lineItem: "retail",
+		},
+		{
+			name:     "price removed",
+			change:   func(m *memStore) { m.items["heater"].Prices = m.items["heater"].Prices[:1] },
+			lineItem: "member",
+			drift:    &PriceDrift{LineItemId: "member", SourceItemId: "heater", SourcePriceId: s("member"), Description: "40 gal water heater", CurrentDescription: "40 gal water heater", UnitPrice: 110000, CurrentUnitPrice: 110000, SourceMissing: true},
+		},
+		{
+			name:     "item deleted",
+			change:   func(m *memStore) { m.items["heater"].Hidden = true },
+			lineItem: "retail",
+			drift:    &PriceDrift{LineItemId: "retail", SourceItemId: "heater", SourcePriceId: s("retail"), Description: "50 gal water heater", CurrentDescription: "50 gal water heater", UnitPrice: 100000, CurrentUnitPrice: 100000, SourceMissing: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := driftStore()
+			if tc.change != nil {
+				tc.change(m)
+			}
+			drift, _, err := priceDrift(context.Background(), m, m.lineItems[tc.lineItem])
+			require.NoError(t, err)
+			assert.Equal(t, tc.drift, drift)
+		})
+	}
+}
+
+func TestRefreshPrices(t *testing.T) {
+	testCases := []struct {
+		name         string
+		locked       bool
+		sourceGone   bool
+		lineItemIds  []ID
+		descriptions map[ID]string
+		unitPrices   map[ID]int
+		total        int
+		err          error
+	}{
+		{
+			name:         "refreshes only the selected line items",
+			lineItemIds:  []ID{"retail"},
+			descriptions: map[ID]string{"retail": "50 gal water heater", "member": "40 gal water heater"},
+			unitPrices:   map[ID]int{"retail": 120000, "member": 110000, "custom": 5000},
+			total:        235000,
+		},
+		{
+			name:         "refreshes descriptions",
+			lineItemIds:  []ID{"member", "custom"},
+			descriptions: map[ID]string{"retail": "50 gal water heater", "member": "50 gal water heater"},
+			unitPrices:   map[ID]int{"retail": 100000, "member": 110000, "custom": 5000},
+			total:        215000,
+		},
+		{
+			name:         "leaves line items whose source is gone",
+			sourceGone:   true,
+			lineItemIds:  []ID{"retail", "member"},
+			descriptions: map[ID]string{"retail": "50 gal water heater", "member": "40 gal water heater"},
+			unitPrices:   map[ID]int{"retail": 100000, "member": 110000, "custom": 5000},
+			total:        215000,
+		},
+		{name: "refuses locked quotes", locked: true, lineItemIds: []ID{"retail"}, err: &QuoteLockedError{QuoteId: "q"}},
+		{name: "refuses line items on other quotes", lineItemIds: []ID{"elsewhere"}, err: LineItemNotOnQuoteError},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := driftStore()
+			m.quotes["q"].Locked = tc.locked
+			m.items["heater"].Hidden = tc.sourceGone
+			v := &priceyQuote{store: m}
+
+			_, err := v.RefreshPrices(context.Background(), "q", tc.lineItemIds)
+			if tc.err != nil {
+				assert.Equal(t, tc.err, err)
+				assert.Equal(t, 100000, m.lineItems["retail"].UnitPrice, "nothing is refreshed")
+				return
+			}
+			require.NoError(t, err)
+			for id, description := range tc.descriptions {
+				assert.Equal(t, description, m.lineItems[id].Description, id)
+			}
+			for id, unitPrice := range tc.unitPrices {
+				assert.Equal(t, unitPrice, m.lineItems[id].UnitPrice, id)
+			}
+			assert.Equal(t, tc.total, m.quotes["q"].Total)
+
+			drifts, err := v.PriceDrift(context.Background(), "q")
+			require.NoError(t, err)
+			for _, drift := range drifts {
+				if !drift.SourceMissing {
+					assert.NotContains(t, tc.lineItemIds, drift.LineItemId, "refreshed line items no longer drift")
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package pricey
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
 	"time"
 
 	gotenberg "github.com/starwalkn/gotenberg-go-client/v8"
@@ -17,10 +20,13 @@ type Pricey struct {
 	CustomValue *priceyCustomValueConfig
 	Image       *priceyImage
 	Quote       *priceyQuote
+	Customer    *priceyCustomer
+	Invoice     *priceyInvoice
 	Auth
 }
 
 func New(store Store, auth Auth, pdfClient *gotenberg.Client) Pricey {
+	printer := newPrinter(store, pdfClient)
 	return Pricey{
 		store:       store,
 		Auth:        auth,
@@ -36,8 +42,12 @@ func New(store Store, auth Auth, pdfClient *gotenberg.Client) Pricey {
 			LineItem:   &priceyLineItem{store},
 			Adjustment: &priceyAdjustment{store},
 			Contact:    &priceyContact{store},
-			Print:      newPrinter(store, pdfClient),
+			Template:   &priceyQuoteTemplate{store},
+			Payment:    &priceyPayment{store},
+			Print:      printer,
 		},
+		Customer: &priceyCustomer{store},
+		Invoice:  &priceyInvoice{store, printer},
 	}
 }
 
@@ -57,8 +67,26 @@ func (v *priceyPricebook) List(ctx context.Context) ([]*Pricebook, error) {
 	return v.store.GetPricebooks(ctx)
 }
 
+// Set updates the pricebook. Items whose price is driven by a formula are
+// repriced when the pricebook's custom value config changes.
 func (v *priceyPricebook) Set(ctx context.Context, pb Pricebook) (*Pricebook, error) {
-	return v.store.UpdatePricebook(ctx, pb)
+	var updated *Pricebook
+	return updated, v.store.Transaction(ctx, func(ctx context.Context) error {
+		items, err := pricebookItems(ctx, v.store, pb.Id, nil)
+		if err != nil {
+			return err
+		}
+		lookup := pricebookLookup{store: v.store, pricebooks: map[ID]*Pricebook{pb.Id: &pb}}
+		prices, err := lookup.itemPrices(ctx, items, nil)
+		if err != nil {
+			return err
+		}
+		updated, err = v.store.UpdatePricebook(ctx, pb)
+		if err != nil {
+			return err
+		}
+		return applyItemPrices(ctx, v.store, prices)
+	})
 }
 
 func (v *priceyPricebook) Delete(ctx context.Context, id ID) error {
@@ -127,16 +155,55 @@ func (v *priceyCategory) SetInfo(ctx context.Context, id ID, name, description s
 	return v.store.UpdateCategoryInfo(ctx, id, name, description)
 }
 
+// SetCustomValues sets the custom value config the category's items use,
+// repricing the items in and beneath the category whose price is driven by a
+// formula.
 func (v *priceyCategory) SetCustomValues(ctx context.Context, id ID, configId *ID) (*Category, error) {
-	return v.store.UpdateCategoryCustomValues(ctx, id, configId)
+	return v.repriced(ctx, id, func(c *Category) { c.CustomValueConfigId = configId }, func(ctx context.Context) (*Category, error) {
+		return v.store.UpdateCategoryCustomValues(ctx, id, configId)
+	})
 }
 
 func (v *priceyCategory) SetImage(ctx context.Context, id, imageId, thumbnailId ID) (*Category, error) {
 	return v.store.UpdateCategoryImage(ctx, id, imageId, thumbnailId)
 }
 
+// Move nests the category under parentId, repricing the items in and beneath
+// it whose price is driven by a formula they now inherit differently.
 func (v *priceyCategory) Move(ctx context.Context, id ID, parentId *ID) (*Category, error) {
-	return v.store.MoveCategory(ctx, id, parentId)
+	return v.repriced(ctx, id, func(c *Category) { c.ParentId = parentId }, func(ctx context.Context) (*Category, error) {
+		return v.store.MoveCategory(ctx, id, parentId)
+	})
+}
+
+// repriced applies change to a copy of the category and works out the formula
+// prices of the items in and beneath it. write only runs, and the prices are
+// only stored, once every formula evaluates.
+func (v *priceyCategory) repriced(ctx context.Context, id ID, change func(c *Category), write func(ctx context.Context) (*Category, error)) (*Category, error) {
+	var c *Category
+	return c, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		c, err = v.store.GetCategory(ctx, id)
+		if err != nil {
+			return err
+		}
+		candidate := *c
+		change(&candidate)
+		items, err := pricebookItems(ctx, v.store, c.PricebookId, &id)
+		if err != nil {
+			return err
+		}
+		lookup := pricebookLookup{store: v.store, categories: map[ID]*Category{id: &candidate}}
+		prices, err := lookup.itemPrices(ctx, items, nil)
+		if err != nil {
+			return err
+		}
+		c, err = write(ctx)
+		if err != nil {
+			return err
+		}
+		return applyItemPrices(ctx, v.store, prices)
+	})
 }
 
 func (v *priceyCategory) Delete(ctx context.Context, id ID) error {
@@ -193,15 +260,8 @@ func (v *priceyItem) Category(ctx context.Context, categoryId ID) ([]*Item, erro
 }
 
 func (v *priceyItem) Move(ctx context.Context, id, categoryId ID) (*Item, error) {
-	var item *Item
-	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
-		var err error
-		item, err = v.store.MoveItem(ctx, id, categoryId)
-		if err != nil {
-			return err
-		}
-
-		return nil
+	return repricedItem(ctx, v.store, id, func(item *Item) { item.CategoryId = categoryId }, func(ctx context.Context) (*Item, error) {
+		return v.store.MoveItem(ctx, id, categoryId)
 	})
 }
 
@@ -210,7 +270,9 @@ func (v *priceyItem) SetInfo(ctx context.Context, id ID, code, sku, name, descri
 }
 
 func (v *priceyItem) SetCost(ctx context.Context, id ID, cost int) (*Item, error) {
-	return v.store.UpdateItemCost(ctx, id, cost)
+	return repricedItem(ctx, v.store, id, func(item *Item) { item.Cost = cost }, func(ctx context.Context) (*Item, error) {
+		return v.store.UpdateItemCost(ctx, id, cost)
+	})
 }
 
 func (v *priceyItem) AddTag(ctx context.Context, id, tagId ID) (*Item, error) {
@@ -233,6 +295,39 @@ func (v *priceyItem) Search(ctx context.Context, pricebookId ID, search string)
 	return v.store.SearchItemsInPricebook(ctx, pricebookId, search)
 }
 
+// EffectiveCustomValues resolves the CustomValueConfig that applies to the item
+// (item → category → parent categories → pricebook) and merges its descriptors
+// with the item's own values and the descriptor defaults. Formula values are
+// computed from the merged values.
+func (v *priceyItem) EffectiveCustomValues(ctx context.Context, id ID) (*EffectiveCustomValues, error) {
+	var result *EffectiveCustomValues
+	return result, v.store.Transaction(ctx, func(ctx context.Context) error {
+		item, err := v.store.GetItem(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, result, err = effectiveCustomValues(ctx, v.store, item)
+		return err
+	})
+}
+
+// Recalculate re-evaluates the price formula that applies to the item and
+// updates its default price. Every change to a formula's inputs, whether on the
+// item, its categories, its pricebook or the config, already does this; call
+// it to restore a formula price that was overwritten by hand.
+func (v *priceyItem) Recalculate(ctx context.Context, id ID) (*Item, error) {
+	var item *Item
+	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		item, err = v.store.GetItem(ctx, id)
+		if err != nil {
+			return err
+		}
+		item, err = recalculateItemPrice(ctx, v.store, item)
+		return err
+	})
+}
+
 func (v *priceyItem) Delete(ctx context.Context, id ID) error {
 	return v.store.Transaction(ctx, func(ctx context.Context) error {
 		err := v.store.DeleteItem(ctx, id)
@@ -257,12 +352,51 @@ type priceyCustomValue struct {
 	store Store
 }
 
+// Set gives the item its own value for key. A value the item's formulas cannot
+// be evaluated with is refused.
 func (v *priceyCustomValue) Set(ctx context.Context, id ID, key ID, value string) (*Item, error) {
-	return v.store.SetItemCustomValue(ctx, id, key, value)
+	return repricedItem(ctx, v.store, id, func(item *Item) {
+		item.CustomValues = slices.DeleteFunc(slices.Clone(item.CustomValues), func(cv CustomValue) bool { return cv.Key == key })
+		item.CustomValues = append(item.CustomValues, CustomValue{Key: key, Value: value})
+	}, func(ctx context.Context) (*Item, error) {
+		return v.store.SetItemCustomValue(ctx, id, key, value)
+	})
 }
 
+// Delete removes the item's own value for key, falling back to the
+// descriptor's default.
 func (v *priceyCustomValue) Delete(ctx context.Context, id ID, key ID) (*Item, error) {
-	return v.store.DeleteItemCustomValue(ctx, id, key)
+	return repricedItem(ctx, v.store, id, func(item *Item) {
+		item.CustomValues = slices.DeleteFunc(slices.Clone(item.CustomValues), func(cv CustomValue) bool { return cv.Key == key })
+	}, func(ctx context.Context) (*Item, error) {
+		return v.store.DeleteItemCustomValue(ctx, id, key)
+	})
+}
+
+// repricedItem applies change to a copy of the item and checks that the
+// formulas that would apply to it still evaluate before write stores the
+// change. The item's formula price is then recalculated.
+func repricedItem(ctx context.Context, store Store, id ID, change func(item *Item), write func(ctx context.Context) (*Item, error)) (*Item, error) {
+	var item *Item
+	return item, store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		item, err = store.GetItem(ctx, id)
+		if err != nil {
+			return err
+		}
+		candidate := *item
+		change(&candidate)
+		err = checkItemPrice(ctx, store, &candidate)
+		if err != nil {
+			return err
+		}
+		item, err = write(ctx)
+		if err != nil {
+			return err
+		}
+		item, err = recalculateItemPrice(ctx, store, item)
+		return err
+	})
 }
 
 type priceySubItem struct {
@@ -370,6 +504,17 @@ func (v *priceyCustomValueConfig) Update(ctx context.Context, id ID, name string
 	return v.store.UpdateCustomValueConfigInfo(ctx, id, name, description)
 }
 
+// SetPriceFormula makes the formula descriptor with the given key drive the
+// default price of items using this config, repricing them. A nil key stops
+// driving prices.
+func (v *priceyCustomValueConfig) SetPriceFormula(ctx context.Context, id ID, key *ID) (*CustomValueConfig, error) {
+	return repricedConfig(ctx, v.store, id, func(c *CustomValueConfig) {
+		c.PriceFormulaKey = key
+	}, func(ctx context.Context) (*CustomValueConfig, error) {
+		return v.store.UpdateCustomValueConfigPriceFormula(ctx, id, key)
+	})
+}
+
 func (v *priceyCustomValueConfig) Delete(ctx context.Context, id ID) error {
 	err := v.store.ClearCategoryCustomValueConfig(ctx, id)
 	if err != nil {
@@ -390,16 +535,72 @@ type priceyCustomValueConfigDescriptor struct {
 	store Store
 }
 
+// New adds a descriptor to the config. For CustomValueTypeFormula descriptors
+// defaultValue is the formula, which must parse and must not create a cycle.
 func (v *priceyCustomValueConfigDescriptor) New(ctx context.Context, id ID, key ID, label string, defaultValue string, valueType CustomValueType) (*CustomValueConfig, error) {
-	return v.store.AddCustomValueConfigDescriptor(ctx, id, key, label, defaultValue, valueType)
+	return v.validated(ctx, id, func(c *CustomValueConfig) {
+		c.Descriptors = append(c.Descriptors, CustomValueDescriptor{Key: key, Label: label, DefaultValue: defaultValue, ValueType: valueType})
+	}, func(ctx context.Context) (*CustomValueConfig, error) {
+		return v.store.AddCustomValueConfigDescriptor(ctx, id, key, label, defaultValue, valueType)
+	})
 }
 
 func (v *priceyCustomValueConfigDescriptor) Update(ctx context.Context, id ID, key ID, label string, defaultValue string) (*CustomValueConfig, error) {
-	return v.store.UpdateCustomValueConfigDescriptor(ctx, id, key, label, defaultValue)
+	return v.validated(ctx, id, func(c *CustomValueConfig) {
+		for i, d := range c.Descriptors {
+			if d.Key == key {
+				c.Descriptors[i].Label = label
+				c.Descriptors[i].DefaultValue = defaultValue
+			}
+		}
+	}, func(ctx context.Context) (*CustomValueConfig, error) {
+		return v.store.UpdateCustomValueConfigDescriptor(ctx, id, key, label, defaultValue)
+	})
 }
 
+// Delete removes a descriptor from the config. It fails while a formula still
+// references the key.
 func (v *priceyCustomValueConfigDescriptor) Delete(ctx context.Context, id ID, key ID) (*CustomValueConfig, error) {
-	return v.store.DeleteCustomValueConfigDescriptor(ctx, id, key)
+	return v.validated(ctx, id, func(c *CustomValueConfig) {
+		c.Descriptors = slices.DeleteFunc(c.Descriptors, func(d CustomValueDescriptor) bool { return d.Key == key })
+	}, func(ctx context.Context) (*CustomValueConfig, error) {
+		return v.store.DeleteCustomValueConfigDescriptor(ctx, id, key)
+	})
+}
+
+// validated applies change to a copy of the config; see repricedConfig.
+func (v *priceyCustomValueConfigDescriptor) validated(ctx context.Context, id ID, change func(c *CustomValueConfig), write func(ctx context.Context) (*CustomValueConfig, error)) (*CustomValueConfig, error) {
+	return repricedConfig(ctx, v.store, id, change, write)
+}
+
+// repricedConfig applies change to a copy of the config and only runs write
+// when the formulas of the changed config are still valid and evaluate for
+// every item using it. Those items are then repriced.
+func repricedConfig(ctx context.Context, store Store, id ID, change func(c *CustomValueConfig), write func(ctx context.Context) (*CustomValueConfig, error)) (*CustomValueConfig, error) {
+	var c *CustomValueConfig
+	return c, store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		c, err = store.GetCustomValueConfig(ctx, id)
+		if err != nil {
+			return err
+		}
+		candidate := *c
+		candidate.Descriptors = slices.Clone(c.Descriptors)
+		change(&candidate)
+		err = validateFormulas(&candidate)
+		if err != nil {
+			return err
+		}
+		prices, err := configItemPrices(ctx, store, &candidate)
+		if err != nil {
+			return err
+		}
+		c, err = write(ctx)
+		if err != nil {
+			return err
+		}
+		return applyItemPrices(ctx, store, prices)
+	})
 }
 
 type priceyImage struct {
@@ -431,21 +632,218 @@ type priceyQuote struct {
 	LineItem   *priceyLineItem
 	Adjustment *priceyAdjustment
 	Contact    *priceyContact
+	Template   *priceyQuoteTemplate
+	Payment    *priceyPayment
 	Print      *priceyPrint
 }
 
-func (v *priceyQuote) New(ctx context.Context) (*Quote, error) {
-	return v.store.CreateQuote(ctx)
+// New creates a draft quote for actor, numbering it when the group's sequence
+// assigns numbers on create.
+func (v *priceyQuote) New(ctx context.Context, actor ID) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = v.store.CreateQuote(ctx, actor)
+		if err != nil {
+			return err
+		}
+		q, err = assignQuoteNumber(ctx, v.store, q, false)
+		return err
+	})
+}
+
+// SetNumberSequence configures how the group's quotes are numbered. pattern is
+// made up of text and the tokens {YYYY}, {YY}, {N} and {N:width}, e.g.
+// "EST-{YYYY}-{N:5}". Numbers are given out as quotes are created or as they
+// are first sent, per assignOn, and start again from 1 each year when
+// resetYearly is set. Changing the sequence carries on its counter.
+func (v *priceyQuote) SetNumberSequence(ctx context.Context, pattern string, assignOn QuoteNumberAssignment, resetYearly bool) (*QuoteNumberSequence, error) {
+	if !validQuoteNumberPattern(pattern) {
+		return nil, QuoteNumberPatternError
+	}
+	if assignOn != QuoteNumberOnCreate && assignOn != QuoteNumberOnSend {
+		return nil, QuoteNumberAssignmentError
+	}
+	return v.store.SetQuoteNumberSequence(ctx, QuoteNumberSequence{Pattern: pattern, AssignOn: assignOn, ResetYearly: resetYearly})
+}
+
+// NumberSequence returns the group's number sequence, or ErrNotFound when
+// quotes are numbered by hand.
+func (v *priceyQuote) NumberSequence(ctx context.Context) (*QuoteNumberSequence, error) {
+	return v.store.GetQuoteNumberSequence(ctx)
+}
+
+// SetPolicy configures the group's pricing policy. Quotes below
+// policy.MinMargin, or discounted by more than the sender's role may give in
+// policy.MaxDiscount, are held back from being sent or accepted until a user
+// with one of policy.ApproverRoles approves them. Limits outside 0-100 fail
+// with InvalidQuotePolicyError.
+func (v *priceyQuote) SetPolicy(ctx context.Context, policy QuotePolicy) (*QuotePolicy, error) {
+	if !validQuotePolicy(policy) {
+		return nil, InvalidQuotePolicyError
+	}
+	return v.store.SetQuotePolicy(ctx, policy)
+}
+
+// Policy returns the group's pricing policy, or ErrNotFound when it has none.
+func (v *priceyQuote) Policy(ctx context.Context) (*QuotePolicy, error) {
+	return v.store.GetQuotePolicy(ctx)
+}
+
+// RequestApproval asks for the quote to be approved at its current pricing,
+// recording the ways it breaks the group's policy. Discounts are held to the
+// limit of the quote's DiscountRole, which becomes actor's role when the quote
+// has none yet. A quote that breaks no rule, or is already approved as it
+// stands, is returned unchanged. Adding or changing an adjustment requests
+// approval on its own when the actor in ctx (see WithActor) gives more
+// discount than their role allows.
+func (v *priceyQuote) RequestApproval(ctx context.Context, id ID, actor Actor) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		if q.DiscountRole == "" && actor.Role != "" {
+			q, err = v.store.UpdateQuoteDiscountRole(ctx, id, actor.Role)
+			if err != nil {
+				return err
+			}
+		}
+		_, violations, fingerprint, err := quotePolicyViolations(ctx, v.store, q)
+		if err != nil || len(violations) == 0 || approvedAt(q, fingerprint) {
+			return err
+		}
+		q, err = v.store.UpdateQuoteApprovals(ctx, id, requestApproval(q.Approvals, violations, fingerprint, q.Total, actor, time.Now()))
+		return err
+	})
+}
+
+// Approve lets the quote be sent and accepted as it is priced now despite
+// the group's policy. approver's role must be one of the policy's
+// ApproverRoles, or it fails with ApproverRoleError, and the quote must have a
+// pending request, or it fails with NoApprovalRequestError.
+func (v *priceyQuote) Approve(ctx context.Context, id ID, approver Actor, note string) (*Quote, error) {
+	return v.decideApproval(ctx, id, ApprovalStatusApproved, approver, note)
+}
+
+// Reject turns down the quote's pending approval request, which keeps it from
+// being sent or accepted until its pricing changes. See Approve.
+func (v *priceyQuote) Reject(ctx context.Context, id ID, approver Actor, note string) (*Quote, error) {
+	return v.decideApproval(ctx, id, ApprovalStatusRejected, approver, note)
+}
+
+func (v *priceyQuote) decideApproval(ctx context.Context, id ID, status ApprovalStatus, approver Actor, note string) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		policy, err := loadQuotePolicy(ctx, v.store)
+		if err != nil {
+			return err
+		}
+		if !canApprove(policy, approver.Role) {
+			return ApproverRoleError
+		}
+		q, err = v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		approvals, err := decideApproval(q.Approvals, status, approver, note, time.Now())
+		if err != nil {
+			return err
+		}
+		q, err = v.store.UpdateQuoteApprovals(ctx, id, approvals)
+		return err
+	})
+}
+
+// Duplicate copies the quote into a new draft for actor, along with copies of
+// its line items, adjustments and contacts. The copy's code, dates and status
+// history start fresh.
+func (v *priceyQuote) Duplicate(ctx context.Context, id ID, actor ID) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		original, err := v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, original)
+		if err != nil {
+			return err
+		}
+		q, err = v.store.CreateDuplicateQuote(ctx, id, actor)
+		if err != nil {
+			return err
+		}
+		q, err = assignQuoteNumber(ctx, v.store, q, false)
+		if err != nil {
+			return err
+		}
+
+		visited := map[ID]bool{}
+		for _, lineItemId := range lineItemChildren(original, lineItems, nil) {
+			_, err = duplicateLineItemTree(ctx, v.store, original, lineItems, lineItems[lineItemId], q.Id, nil, visited)
+			if err != nil {
+				return err
+			}
+		}
+		for _, adjustmentId := range original.AdjustmentIds {
+			_, err = copyAdjustment(ctx, v.store, adjustments[adjustmentId], q.Id)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = duplicateQuoteContacts(ctx, v.store, original, q.Id)
+		if err != nil {
+			return err
+		}
+
+		q, err = recalculateQuote(ctx, v.store, q.Id)
+		return err
+	})
 }
 
-func (v *priceyQuote) Duplicate(ctx context.Context, id ID) (*Quote, error) {
-	return v.store.CreateDuplicateQuote(ctx, id)
+// duplicateQuoteContacts gives the quote its own copies of the original's
+// sender, bill to and ship to contacts, copying a contact used more than once
+// only once.
+func duplicateQuoteContacts(ctx context.Context, store Store, original *Quote, quoteId ID) error {
+	contacts := map[ID]ID{}
+	for _, contact := range []struct {
+		id  ID
+		set func(ctx context.Context, id ID, contactId ID) (*Quote, error)
+	}{
+		{original.SenderId, store.UpdateQuoteSenderId},
+		{original.BillToId, store.UpdateQuoteBillToId},
+		{original.ShipToId, store.UpdateQuoteShipToId},
+	} {
+		if contact.id == "" {
+			continue
+		}
+		if _, ok := contacts[contact.id]; !ok {
+			c, err := store.CreateDuplicateContact(ctx, contact.id)
+			if err != nil {
+				return err
+			}
+			contacts[contact.id] = c.Id
+		}
+		if _, err := contact.set(ctx, quoteId, contacts[contact.id]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (v *priceyQuote) Get(ctx context.Context, id ID) (*Quote, error) {
 	return v.store.GetQuote(ctx, id)
 }
 
+// List returns a page of the group's quotes matching the filter. Deleted
+// quotes are left out unless filter.IncludeDeleted is set.
+func (v *priceyQuote) List(ctx context.Context, filter QuoteFilter) (*QuotePage, error) {
+	return v.store.ListQuotes(ctx, filter)
+}
+
 func (v *priceyQuote) SetCode(ctx context.Context, id ID, code string) (*Quote, error) {
 	return v.store.UpdateQuoteCode(ctx, id, code)
 }
@@ -458,6 +856,10 @@ func (v *priceyQuote) SetLogoId(ctx context.Context, id ID, imageId ID) (*Quote,
 	return v.store.UpdateQuoteLogoId(ctx, id, imageId)
 }
 
+func (v *priceyQuote) SetColors(ctx context.Context, id ID, backgroundColor, textColor string) (*Quote, error) {
+	return v.store.UpdateQuoteColors(ctx, id, backgroundColor, textColor)
+}
+
 func (v *priceyQuote) SetIssueDate(ctx context.Context, id ID, issueDate *time.Time) (*Quote, error) {
 	return v.store.UpdateQuoteIssueDate(ctx, id, issueDate)
 }
@@ -482,175 +884,1289 @@ func (v *priceyQuote) SetBillToId(ctx context.Context, id ID, contactId ID) (*Qu
 	return v.store.UpdateQuoteBillToId(ctx, id, contactId)
 }
 
+// SetBillTo bills the quote to contact. An existing contact is picked by its
+// Id; without an Id the contact is created first.
+func (v *priceyQuote) SetBillTo(ctx context.Context, id ID, contact Contact) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		c := &contact
+		var err error
+		if contact.Id == "" {
+			c, err = v.store.CreateContact(ctx, contact)
+		} else {
+			c, err = v.store.GetContact(ctx, contact.Id)
+		}
+		if err != nil {
+			return err
+		}
+		q, err = v.store.UpdateQuoteBillToId(ctx, id, c.Id)
+		return err
+	})
+}
+
 func (v *priceyQuote) SetShipToId(ctx context.Context, id ID, contactId ID) (*Quote, error) {
 	return v.store.UpdateQuoteShipToId(ctx, id, contactId)
 }
 
+// SetCustomer links the quote to the customer. A quote without a bill to or
+// ship to takes the customer's primary contact and first service address.
+func (v *priceyQuote) SetCustomer(ctx context.Context, id ID, customerId ID) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		c, err := v.store.GetCustomer(ctx, customerId)
+		if err != nil {
+			return err
+		}
+		q, err = v.store.UpdateQuoteCustomerId(ctx, id, customerId)
+		if err != nil {
+			return err
+		}
+		if q.BillToId == "" && len(c.ContactIds) > 0 {
+			q, err = v.store.UpdateQuoteBillToId(ctx, id, c.ContactIds[0])
+			if err != nil {
+				return err
+			}
+		}
+		if q.ShipToId == "" && len(c.AddressIds) > 0 {
+			q, err = v.store.UpdateQuoteShipToId(ctx, id, c.AddressIds[0])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Deprecated: the subtotal is recalculated whenever the quote's line items or
+// adjustments change, overwriting any value set here.
 func (v *priceyQuote) SetSubTotal(ctx context.Context, id ID, subTotal int) (*Quote, error) {
 	return v.store.UpdateQuoteSubTotal(ctx, id, subTotal)
 }
 
+// Deprecated: the total is recalculated whenever the quote's line items or
+// adjustments change, overwriting any value set here.
 func (v *priceyQuote) SetTotal(ctx context.Context, id ID, total int) (*Quote, error) {
 	return v.store.UpdateQuoteTotal(ctx, id, total)
 }
 
-func (v *priceyQuote) SetBalanceDue(ctx context.Context, id ID, balanceDue int) (*Quote, error) {
-	return v.store.UpdateQuoteBalanceDue(ctx, id, balanceDue)
-}
-
-func (v *priceyQuote) SetBalancePercentDue(ctx context.Context, id ID, balancePercentDue int) (*Quote, error) {
-	return v.store.UpdateQuoteBalancePercentDue(ctx, id, balancePercentDue)
-}
-
-func (v *priceyQuote) SetBalanceDueOn(ctx context.Context, id ID, balanceDueOn *time.Time) (*Quote, error) {
-	return v.store.UpdateQuoteBalanceDueOn(ctx, id, balanceDueOn)
-}
-
-func (v *priceyQuote) SetPayUrl(ctx context.Context, id ID, payUrl string) (*Quote, error) {
-	return v.store.UpdateQuotePayUrl(ctx, id, payUrl)
-}
-
-func (v *priceyQuote) SetSent(ctx context.Context, id ID, sent bool) (*Quote, error) {
+// SetPaymentSchedule replaces the quote's payment schedule, such as a 30%
+// deposit, 40% at rough-in and 30% on completion. The milestones must add up to
+// the quote's total, failing with PaymentScheduleTotalError otherwise. An empty
+// schedule removes it.
+func (v *priceyQuote) SetPaymentSchedule(ctx context.Context, id ID, schedule []PaymentMilestone) (*Quote, error) {
 	var q *Quote
 	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
 		var err error
-		q, err = v.store.UpdateQuoteSent(ctx, id, sent)
+		q, err = v.store.GetQuote(ctx, id)
 		if err != nil {
 			return err
 		}
-		if sent {
-			now := time.Now()
-			q, err = v.store.UpdateQuoteSentOn(ctx, id, &now)
-			if err != nil {
-				return err
-			}
+		err = validatePaymentSchedule(schedule, q.Total)
+		if err != nil {
+			return err
 		}
-		return nil
+		q, err = v.store.UpdateQuotePaymentSchedule(ctx, id, schedule)
+		return err
 	})
 }
 
-func (v *priceyQuote) SetSold(ctx context.Context, id ID, sold bool) (*Quote, error) {
+// SetBalanceDue sets a fixed balance due, clearing any BalancePercentDue.
+func (v *priceyQuote) SetBalanceDue(ctx context.Context, id ID, balanceDue int) (*Quote, error) {
 	var q *Quote
 	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
 		var err error
-		q, err = v.store.UpdateQuoteSold(ctx, id, sold)
+		q, err = v.store.UpdateQuoteBalancePercentDue(ctx, id, 0)
 		if err != nil {
 			return err
 		}
-		if sold {
-			now := time.Now()
-			q, err = v.store.UpdateQuoteSoldOn(ctx, id, &now)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
+		q, err = v.store.UpdateQuoteBalanceDue(ctx, id, balanceDue)
+		return err
 	})
 }
 
+// SetBalancePercentDue makes the balance due a percentage of the total, which
+// is kept up to date as the total changes.
+func (v *priceyQuote) SetBalancePercentDue(ctx context.Context, id ID, balancePercentDue int) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		_, err = v.store.UpdateQuoteBalancePercentDue(ctx, id, balancePercentDue)
+		if err != nil {
+			return err
+		}
+		q, err = recalculateQuote(ctx, v.store, id)
+		return err
+	})
+}
+
+// Recalculate prices the quote from its line items and adjustments and saves
+// the subtotal, total and balance due.
+func (v *priceyQuote) Recalculate(ctx context.Context, id ID) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = recalculateQuote(ctx, v.store, id)
+		return err
+	})
+}
+
+// Balance works out what has been paid against the quote and what is still
+// outstanding, including how the payments cover its payment schedule.
+func (v *priceyQuote) Balance(ctx context.Context, id ID) (*QuoteBalance, error) {
+	var b *QuoteBalance
+	return b, v.store.Transaction(ctx, func(ctx context.Context) error {
+		q, err := v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		payments, err := v.store.GetQuotePayments(ctx, id)
+		if err != nil {
+			return err
+		}
+		changeOrdersTotal, err := acceptedChangeOrdersTotal(ctx, v.store, q)
+		if err != nil {
+			return err
+		}
+		b = quoteBalance(id, q.Total+changeOrdersTotal, q.PaymentSchedule, payments)
+		return nil
+	})
+}
+
+// Profitability reports the cost, price, gross margin and markup of each line
+// item on the quote, of each top level group and of the whole quote after
+// adjustments. It is for the business only; cost is never printed.
+func (v *priceyQuote) Profitability(ctx context.Context, id ID) (*QuoteProfitability, error) {
+	var report *QuoteProfitability
+	return report, v.store.Transaction(ctx, func(ctx context.Context) error {
+		q, err := v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, q)
+		if err != nil {
+			return err
+		}
+		report = quoteProfitability(q, lineItems, adjustments)
+		return nil
+	})
+}
+
+// NewChangeOrder starts a change order to an accepted quote. The change order
+// is a draft quote of its own, linked to the original and carrying its
+// details and contacts, so scope added on site is priced, signed and accepted
+// separately while the original stays locked. Given a change order, the new
+// one is made to the same original quote.
+func (v *priceyQuote) NewChangeOrder(ctx context.Context, quoteId ID) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		original, err := v.store.GetQuote(ctx, quoteId)
+		if err != nil {
+			return err
+		}
+		if original.ChangeOrderOf != "" {
+			original, err = v.store.GetQuote(ctx, original.ChangeOrderOf)
+			if err != nil {
+				return err
+			}
+		}
+		if !changeOrderAccepted(original) {
+			return ChangeOrderNotSoldError
+		}
+		number, err := v.store.AllocateChangeOrderNumber(ctx, original.Id)
+		if err != nil {
+			return err
+		}
+
+		q, err = v.store.CreateDuplicateQuote(ctx, original.Id, original.CreatedBy)
+		if err != nil {
+			return err
+		}
+		// a fixed balance due is for the original's work
+		_, err = v.store.UpdateQuoteBalanceDue(ctx, q.Id, 0)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.UpdateQuoteOptions(ctx, q.Id, nil)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.UpdateQuotePaymentSchedule(ctx, q.Id, nil)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.UpdateQuoteOrderNumber(ctx, q.Id, original.OrderNumber)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.UpdateQuoteCode(ctx, q.Id, changeOrderCode(original.Code, number))
+		if err != nil {
+			return err
+		}
+
+		err = duplicateQuoteContacts(ctx, v.store, original, q.Id)
+		if err != nil {
+			return err
+		}
+
+		q, err = v.store.UpdateQuoteChangeOrder(ctx, q.Id, original.Id, number)
+		return err
+	})
+}
+
+// RemoveOriginalLineItem removes one of the original quote's line items on a
+// change order by adding a line item crediting back its amount. It fails with
+// NotChangeOrderError when changeOrderId is not a change order, and with
+// AlreadyRemovedError when another open change order already removes it.
+func (v *priceyQuote) RemoveOriginalLineItem(ctx context.Context, changeOrderId ID, lineItemId ID) (*LineItem, error) {
+	var item *LineItem
+	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
+		co, err := v.store.GetQuote(ctx, changeOrderId)
+		if err != nil {
+			return err
+		}
+		if co.ChangeOrderOf == "" {
+			return NotChangeOrderError
+		}
+		original, err := v.store.GetQuote(ctx, co.ChangeOrderOf)
+		if err != nil {
+			return err
+		}
+		lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, original)
+		if err != nil {
+			return err
+		}
+		changeOrders, err := listChangeOrders(ctx, v.store, original.Id, false)
+		if err != nil {
+			return err
+		}
+		removed := map[ID]bool{}
+		for _, other := range changeOrders {
+			if !changeOrderOpen(other) {
+				continue
+			}
+			otherLineItems, _, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, other)
+			if err != nil {
+				return err
+			}
+			for _, l := range otherLineItems {
+				if l.RemovesLineItemId != "" && slices.Contains(other.LineItemIds, l.Id) {
+					removed[l.RemovesLineItemId] = true
+				}
+			}
+		}
+		credit, err := changeOrderRemoval(original, lineItems, adjustments, removed, lineItemId)
+		if err != nil {
+			return err
+		}
+
+		item, err = v.store.CreateLineItem(ctx, co.Id, credit.Description, 0, 0, credit.Amount)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.QuoteAddLineItem(ctx, co.Id, item.Id)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.UpdateLineItemName(ctx, item.Id, credit.Name)
+		if err != nil {
+			return err
+		}
+		item, err = v.store.UpdateLineItemRemovesLineItemId(ctx, item.Id, lineItemId)
+		if err != nil {
+			return err
+		}
+		_, err = recalculateQuote(ctx, v.store, co.Id)
+		return err
+	})
+}
+
+// ChangeOrders lists the change orders made to the quote in change order
+// number order. Deleted change orders are left out.
+func (v *priceyQuote) ChangeOrders(ctx context.Context, quoteId ID) ([]*Quote, error) {
+	return listChangeOrders(ctx, v.store, quoteId, false)
+}
+
+// ContractTotal is the quote's total plus its accepted change orders.
+func (v *priceyQuote) ContractTotal(ctx context.Context, quoteId ID) (*ContractTotal, error) {
+	var total *ContractTotal
+	return total, v.store.Transaction(ctx, func(ctx context.Context) error {
+		original, err := v.store.GetQuote(ctx, quoteId)
+		if err != nil {
+			return err
+		}
+		changeOrders, err := listChangeOrders(ctx, v.store, quoteId, false)
+		if err != nil {
+			return err
+		}
+		total = contractTotal(original, changeOrders)
+		return nil
+	})
+}
+
+func (v *priceyQuote) SetBalanceDueOn(ctx context.Context, id ID, balanceDueOn *time.Time) (*Quote, error) {
+	return v.store.UpdateQuoteBalanceDueOn(ctx, id, balanceDueOn)
+}
+
+func (v *priceyQuote) SetPayUrl(ctx context.Context, id ID, payUrl string) (*Quote, error) {
+	return v.store.UpdateQuotePayUrl(ctx, id, payUrl)
+}
+
+// ExpireOverdue moves every sent or viewed quote in the group whose expiration
+// date has passed to QuoteStatusExpired, recording the change in its status
+// history, and returns the quotes it expired. Each quote is expired by a single
+// conditional update, so sweeps may run at the same time from several
+// instances; a quote expired by another sweep is simply skipped. Only the org
+// and group in ctx are swept, so a scheduled sweep has to run once for every
+// group, each with its own ctx.
+func (v *priceyQuote) ExpireOverdue(ctx context.Context, sweep ExpirationSweep) ([]*Quote, error) {
+	if sweep.Actor == "" {
+		sweep.Actor = ExpirationSweepActor
+	}
+	if sweep.BatchSize <= 0 {
+		sweep.BatchSize = MaxQuoteLimit
+	}
+	now := time.Now()
+	expired := []*Quote{}
+	for {
+		overdue, err := v.store.ListOverdueQuotes(ctx, now, sweep.BatchSize)
+		if err != nil {
+			return expired, err
+		}
+		for _, q := range overdue {
+			change := QuoteStatusChange{From: quoteStatus(q), To: QuoteStatusExpired, Actor: sweep.Actor, On: now}
+			q, err = v.store.ExpireQuote(ctx, q.Id, change, sweep.LockPrices)
+			if errors.Is(err, InvalidQuoteStatusError) {
+				continue
+			}
+			if err != nil {
+				return expired, err
+			}
+			expired = append(expired, q)
+		}
+		if len(overdue) < sweep.BatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RunExpirationSweep runs ExpireOverdue straight away and then every interval
+// until ctx is done. Like ExpireOverdue it only sweeps the org and group in
+// ctx; run one per group. Errors from a run are passed to onError, when set, and
+// the sweep carries on with the next run.
+func (v *priceyQuote) RunExpirationSweep(ctx context.Context, every time.Duration, sweep ExpirationSweep, onError func(error)) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if _, err := v.ExpireOverdue(ctx, sweep); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reissue puts an expired quote back in front of the customer with a new
+// expiration date, unlocking it when the sweep locked its prices. A quote that
+// was already locked when it expired is not the sweep's to unlock, so it fails
+// with a *QuoteLockedError until it is unlocked with Unlock. The quote is
+// moved back through draft to sent so the status history shows it was
+// re-issued. A quote that is overdue but not yet swept only has its dates
+// moved. expirationDate must be in the future, or nil for a quote that does not
+// expire.
+func (v *priceyQuote) Reissue(ctx context.Context, id ID, expirationDate *time.Time, actor ID) (*Quote, error) {
+	now := time.Now()
+	if !validReissueDate(expirationDate, now) {
+		return nil, ReissueExpirationDateError
+	}
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !quoteExpired(q, now) {
+			return QuoteNotExpiredError
+		}
+		if q.Locked && !q.ExpiryLocked {
+			return &QuoteLockedError{QuoteId: id}
+		}
+		if q.Locked {
+			_, err = v.store.UnlockQuote(ctx, id, QuoteUnlock{Actor: actor, Reason: "re-issued", On: now})
+			if err != nil {
+				return err
+			}
+		}
+		_, err = v.store.UpdateQuoteIssueDate(ctx, id, &now)
+		if err != nil {
+			return err
+		}
+		q, err = v.store.UpdateQuoteExpirationDate(ctx, id, expirationDate)
+		if err != nil {
+			return err
+		}
+		if quoteStatus(q) != QuoteStatusExpired {
+			return nil
+		}
+		_, err = transitionQuote(ctx, v.store, id, QuoteStatusDraft, actor)
+		if err != nil {
+			return err
+		}
+		q, err = transitionQuote(ctx, v.store, id, QuoteStatusSent, actor)
+		return err
+	})
+}
+
+// Transition moves the quote to a new status. It fails with
+// InvalidQuoteStatusError when the move is not allowed from the quote's
+// current status. actor identifies who made the change. Quotes are only
+// accepted with the customer's signature through Accept, so moving to
+// QuoteStatusAccepted fails with SignatureRequiredError.
+func (v *priceyQuote) Transition(ctx context.Context, id ID, status QuoteStatus, actor ID) (*Quote, error) {
+	if status == QuoteStatusAccepted {
+		return nil, SignatureRequiredError
+	}
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = transitionQuote(ctx, v.store, id, status, actor)
+		return err
+	})
+}
+
+// SetSent marks a draft quote as sent to the customer.
+func (v *priceyQuote) SetSent(ctx context.Context, id ID, actor ID) (*Quote, error) {
+	return v.Transition(ctx, id, QuoteStatusSent, actor)
+}
+
+// SetSold marks a sent quote as accepted by the customer with their
+// signature. It is the same as Accept.
+func (v *priceyQuote) SetSold(ctx context.Context, id ID, signature Signature) (*Quote, error) {
+	return v.Accept(ctx, id, signature)
+}
+
+// Accept records the customer's signature and accepts the quote, which locks
+// it. The content the customer signed is kept as a new revision and its hash is
+// stored with the signature, so what was agreed to can be shown and checked
+// later with VerifyAcceptance. A signature without a name fails with
+// SignatureRequiredError, and one without a time is signed now. Everything
+// that could refuse the acceptance is checked before anything is written. The
+// status change is recorded against the actor in ctx (see WithActor), or the
+// signer when there is none.
+func (v *priceyQuote) Accept(ctx context.Context, id ID, signature Signature) (*Quote, error) {
+	if strings.TrimSpace(signature.Name) == "" {
+		return nil, SignatureRequiredError
+	}
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = v.accept(ctx, id, signature)
+		return err
+	})
+}
+
+func (v *priceyQuote) accept(ctx context.Context, id ID, signature Signature) (*Quote, error) {
+	if signature.SignedAt.IsZero() {
+		signature.SignedAt = time.Now()
+	}
+	q, err := v.store.GetQuote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = checkQuoteTransition(ctx, v.store, q, QuoteStatusAccepted)
+	if err != nil {
+		return nil, err
+	}
+	r, err := snapshotQuote(ctx, v.store, id)
+	if err != nil {
+		return nil, err
+	}
+	contentHash := signedContentHash(r)
+	r, err = v.store.CreateQuoteRevision(ctx, *r)
+	if err != nil {
+		return nil, err
+	}
+	_, err = v.store.UpdateQuoteAcceptance(ctx, id, &QuoteAcceptance{
+		Signature:      signature,
+		ContentHash:    contentHash,
+		RevisionNumber: r.Number,
+	})
+	if err != nil {
+		return nil, err
+	}
+	actor := actorFromContext(ctx).Id
+	if actor == "" {
+		actor = signature.Name
+	}
+	return applyQuoteTransition(ctx, v.store, q, QuoteStatusAccepted, actor)
+}
+
+// VerifyAcceptance reports whether the quote's content still matches what the
+// customer signed. Quotes that have not been accepted with a signature are
+// never verified.
+func (v *priceyQuote) VerifyAcceptance(ctx context.Context, id ID) (bool, error) {
+	var verified bool
+	return verified, v.store.Transaction(ctx, func(ctx context.Context) error {
+		q, err := v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		if q.Acceptance == nil {
+			return nil
+		}
+		r, err := snapshotQuote(ctx, v.store, id)
+		if err != nil {
+			return err
+		}
+		verified = signedContentHash(r) == q.Acceptance.ContentHash
+		return nil
+	})
+}
+
+// AddItem adds a pricebook item to the quote as a line item, nested under
+// parentLineItemId when it is set. The item's name, description, image and
+// price are copied onto the line item, so later changes to the pricebook do
+// not change the quote. The item's sub items become sub line items. When
+// priceId is nil the item's default price is used. quantity is in hundredths.
+func (v *priceyQuote) AddItem(ctx context.Context, quoteId, itemId ID, priceId *ID, quantity int, parentLineItemId *ID) (*LineItem, error) {
+	var li *LineItem
+	return li, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		li, err = addItemLineItem(ctx, v.store, quoteId, itemId, priceId, quantity, parentLineItemId, map[ID]bool{})
+		if err != nil {
+			return err
+		}
+		li, err = v.store.MoveLineItem(ctx, li.Id, parentLineItemId, nil)
+		if err != nil {
+			return err
+		}
+		_, err = recalculateQuote(ctx, v.store, quoteId)
+		return err
+	})
+}
+
+// PriceDrift lists the quote's line items whose description or unit price no
+// longer matches the pricebook item they were added from.
+func (v *priceyQuote) PriceDrift(ctx context.Context, quoteId ID) ([]*PriceDrift, error) {
+	var drifts []*PriceDrift
+	return drifts, v.store.Transaction(ctx, func(ctx context.Context) error {
+		q, err := v.store.GetQuote(ctx, quoteId)
+		if err != nil {
+			return err
+		}
+		drifts = []*PriceDrift{}
+		for _, lineItemId := range q.LineItemIds {
+			li, err := v.store.GetLineItem(ctx, lineItemId)
+			if err != nil {
+				return err
+			}
+			drift, _, err := priceDrift(ctx, v.store, li)
+			if err != nil {
+				return err
+			}
+			if drift != nil {
+				drifts = append(drifts, drift)
+			}
+		}
+		return nil
+	})
+}
+
+// RefreshPrices copies the current pricebook description and price onto the
+// given line items of the quote. Line items that have not drifted, or whose
+// source no longer exists, are left as they are. Locked quotes are refused with
+// QuoteLockedError.
+func (v *priceyQuote) RefreshPrices(ctx context.Context, quoteId ID, lineItemIds []ID) ([]*LineItem, error) {
+	var lineItems []*LineItem
+	return lineItems, v.store.Transaction(ctx, func(ctx context.Context) error {
+		q, err := v.store.GetQuote(ctx, quoteId)
+		if err != nil {
+			return err
+		}
+		if q.Locked {
+			return &QuoteLockedError{QuoteId: quoteId}
+		}
+		lineItems = []*LineItem{}
+		for _, lineItemId := range lineItemIds {
+			if !slices.Contains(q.LineItemIds, lineItemId) {
+				return LineItemNotOnQuoteError
+			}
+			li, err := v.store.GetLineItem(ctx, lineItemId)
+			if err != nil {
+				return err
+			}
+			li, err = refreshLineItemPrice(ctx, v.store, li)
+			if err != nil {
+				return err
+			}
+			lineItems = append(lineItems, li)
+		}
+		_, err = recalculateQuote(ctx, v.store, quoteId)
+		return err
+	})
+}
+
+// SetSelections records which optional and alternate line items the customer
+// has chosen. Optional and alternate line items not in selectedIds are
+// deselected, and the quote is repriced over the selected set.
+func (v *priceyQuote) SetSelections(ctx context.Context, id ID, selectedIds []ID) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		lineItems, _, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, q)
+		if err != nil {
+			return err
+		}
+		selections, err := lineItemSelections(q, lineItems, selectedIds)
+		if err != nil {
+			return err
+		}
+		for _, lineItemId := range q.LineItemIds {
+			selected, ok := selections[lineItemId]
+			if !ok || lineItems[lineItemId].Selected == selected {
+				continue
+			}
+			_, err = v.store.UpdateLineItemSelected(ctx, lineItemId, selected)
+			if err != nil {
+				return err
+			}
+		}
+		q, err = recalculateQuote(ctx, v.store, id)
+		return err
+	})
+}
+
+// AddOption adds a new option, such as Good, Better or Best, to the end of the
+// quote's options. Line items and adjustments are placed in it with
+// priceyLineItem.SetOption and priceyAdjustment.SetOption.
+func (v *priceyQuote) AddOption(ctx context.Context, id ID, name, description string) (*QuoteOption, error) {
+	var o *QuoteOption
+	return o, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		o, err = v.store.QuoteAddOption(ctx, id, name, description)
+		if err != nil {
+			return err
+		}
+		_, err = recalculateQuote(ctx, v.store, id)
+		return err
+	})
+}
+
+func (v *priceyQuote) SetOptionInfo(ctx context.Context, id ID, optionId ID, name, description string) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(q.Options, func(o QuoteOption) bool { return o.Id == optionId })
+		if i < 0 {
+			return QuoteOptionNotFoundError
+		}
+		options := slices.Clone(q.Options)
+		options[i].Name = name
+		options[i].Description = description
+		q, err = v.store.UpdateQuoteOptions(ctx, id, options)
+		return err
+	})
+}
+
+// DeleteOption removes the option from the quote along with the line items and
+// adjustments placed in it.
+func (v *priceyQuote) DeleteOption(ctx context.Context, id ID, optionId ID) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = removeQuoteOption(ctx, v.store, q, optionId)
+		if err != nil {
+			return err
+		}
+		q, err = recalculateQuote(ctx, v.store, id)
+		return err
+	})
+}
+
+// CompareOptions prices every option of the quote side by side, each including
+// the shared line items and adjustments.
+func (v *priceyQuote) CompareOptions(ctx context.Context, id ID) ([]*QuoteOptionTotals, error) {
+	var comparison []*QuoteOptionTotals
+	return comparison, v.store.Transaction(ctx, func(ctx context.Context) error {
+		q, err := v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, q)
+		if err != nil {
+			return err
+		}
+		comparison = compareQuoteOptions(q, lineItems, adjustments)
+		return nil
+	})
+}
+
+// ChooseOption records the option the customer chose, pricing the quote as that
+// option.
+func (v *priceyQuote) ChooseOption(ctx context.Context, id ID, optionId ID) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		q, err = v.chooseOption(ctx, id, optionId)
+		return err
+	})
+}
+
+func (v *priceyQuote) chooseOption(ctx context.Context, id ID, optionId ID) (*Quote, error) {
+	q, err := v.store.GetQuote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if quoteOption(q, optionId) == nil {
+		return nil, QuoteOptionNotFoundError
+	}
+	_, err = v.store.UpdateQuoteChosenOptionId(ctx, id, optionId)
+	if err != nil {
+		return nil, err
+	}
+	return recalculateQuote(ctx, v.store, id)
+}
+
+// AcceptOption records the option the customer chose and accepts the quote
+// with their signature, locking it in that option. When the acceptance is
+// refused the quote is left with the option it had chosen before, since
+// transactions are not atomic on every store.
+func (v *priceyQuote) AcceptOption(ctx context.Context, id ID, optionId ID, signature Signature) (*Quote, error) {
+	if strings.TrimSpace(signature.Name) == "" {
+		return nil, SignatureRequiredError
+	}
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		previous, err := v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = v.chooseOption(ctx, id, optionId)
+		if err != nil {
+			return err
+		}
+		q, err = v.accept(ctx, id, signature)
+		if err == nil || previous.ChosenOptionId == optionId {
+			return err
+		}
+		if _, restoreErr := v.store.UpdateQuoteChosenOptionId(ctx, id, previous.ChosenOptionId); restoreErr != nil {
+			return restoreErr
+		}
+		if _, restoreErr := recalculateQuote(ctx, v.store, id); restoreErr != nil {
+			return restoreErr
+		}
+		return err
+	})
+}
+
+// Revise freezes the quote as it is now, along with its line items,
+// adjustments and contacts, as the next numbered revision. The quote itself
+// can continue to be edited.
+func (v *priceyQuote) Revise(ctx context.Context, id ID) (*QuoteRevision, error) {
+	var r *QuoteRevision
+	return r, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		r, err = snapshotQuote(ctx, v.store, id)
+		if err != nil {
+			return err
+		}
+		r, err = v.store.CreateQuoteRevision(ctx, *r)
+		return err
+	})
+}
+
+// Revisions lists the quote's revisions, oldest first.
+func (v *priceyQuote) Revisions(ctx context.Context, id ID) ([]*QuoteRevision, error) {
+	return v.store.GetQuoteRevisions(ctx, id)
+}
+
+func (v *priceyQuote) Revision(ctx context.Context, revisionId ID) (*QuoteRevision, error) {
+	return v.store.GetQuoteRevision(ctx, revisionId)
+}
+
+// Diff compares two revisions of the same quote, reporting the line items
+// that were added, removed or changed going from revA to revB and the change
+// in total.
+func (v *priceyQuote) Diff(ctx context.Context, revA, revB ID) (*QuoteRevisionDiff, error) {
+	var diff *QuoteRevisionDiff
+	return diff, v.store.Transaction(ctx, func(ctx context.Context) error {
+		a, err := v.store.GetQuoteRevision(ctx, revA)
+		if err != nil {
+			return err
+		}
+		b, err := v.store.GetQuoteRevision(ctx, revB)
+		if err != nil {
+			return err
+		}
+		if a.QuoteId != b.QuoteId {
+			return RevisionQuoteMismatchError
+		}
+		diff = diffRevisions(a, b)
+		return nil
+	})
+}
+
+// SaveAsTemplate saves the quote's line items, adjustments, payment terms,
+// notes and colors as a template for the current group.
+func (v *priceyQuote) SaveAsTemplate(ctx context.Context, id ID, name, description string) (*QuoteTemplate, error) {
+	var t *QuoteTemplate
+	return t, v.store.Transaction(ctx, func(ctx context.Context) error {
+		q, err := v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, q)
+		if err != nil {
+			return err
+		}
+		t = templateFromQuote(q, lineItems, adjustments)
+		t.Name = name
+		t.Description = description
+		t, err = v.store.CreateQuoteTemplate(ctx, *t)
+		return err
+	})
+}
+
+// NewFromTemplate creates a draft quote for actor from the template, with
+// overrides replacing the template's values. Line items added from the
+// pricebook are repriced from it unless overrides.KeepTemplatePrices is set.
+func (v *priceyQuote) NewFromTemplate(ctx context.Context, templateId ID, overrides QuoteTemplateOverrides, actor ID) (*Quote, error) {
+	var q *Quote
+	return q, v.store.Transaction(ctx, func(ctx context.Context) error {
+		t, err := v.store.GetQuoteTemplate(ctx, templateId)
+		if err != nil {
+			return err
+		}
+		q, err = v.store.CreateQuote(ctx, actor)
+		if err != nil {
+			return err
+		}
+		q, err = assignQuoteNumber(ctx, v.store, q, false)
+		if err != nil {
+			return err
+		}
+
+		logoId := t.LogoId
+		if overrides.LogoId != nil {
+			logoId = *overrides.LogoId
+		}
+		backgroundColor := t.PrimaryBackgroundColor
+		if overrides.PrimaryBackgroundColor != nil {
+			backgroundColor = *overrides.PrimaryBackgroundColor
+		}
+		textColor := t.PrimaryTextColor
+		if overrides.PrimaryTextColor != nil {
+			textColor = *overrides.PrimaryTextColor
+		}
+		paymentTerms := t.PaymentTerms
+		if overrides.PaymentTerms != nil {
+			paymentTerms = *overrides.PaymentTerms
+		}
+		notes := t.Notes
+		if overrides.Notes != nil {
+			notes = *overrides.Notes
+		}
+		balancePercentDue := t.BalancePercentDue
+		if overrides.BalancePercentDue != nil {
+			balancePercentDue = *overrides.BalancePercentDue
+		}
+
+		_, err = v.store.UpdateQuoteLogoId(ctx, q.Id, logoId)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.UpdateQuoteColors(ctx, q.Id, backgroundColor, textColor)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.UpdateQuotePaymentTerms(ctx, q.Id, paymentTerms)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.UpdateQuoteNotes(ctx, q.Id, notes)
+		if err != nil {
+			return err
+		}
+		_, err = v.store.UpdateQuoteBalancePercentDue(ctx, q.Id, balancePercentDue)
+		if err != nil {
+			return err
+		}
+		if overrides.IssueDate != nil {
+			_, err = v.store.UpdateQuoteIssueDate(ctx, q.Id, overrides.IssueDate)
+			if err != nil {
+				return err
+			}
+		}
+		if overrides.ExpirationDate != nil {
+			_, err = v.store.UpdateQuoteExpirationDate(ctx, q.Id, overrides.ExpirationDate)
+			if err != nil {
+				return err
+			}
+		}
+		if overrides.SenderId != nil {
+			_, err = v.store.UpdateQuoteSenderId(ctx, q.Id, *overrides.SenderId)
+			if err != nil {
+				return err
+			}
+		}
+		if overrides.BillToId != nil {
+			_, err = v.store.UpdateQuoteBillToId(ctx, q.Id, *overrides.BillToId)
+			if err != nil {
+				return err
+			}
+		}
+		if overrides.ShipToId != nil {
+			_, err = v.store.UpdateQuoteShipToId(ctx, q.Id, *overrides.ShipToId)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(t.Options) > 0 {
+			_, err = v.store.UpdateQuoteOptions(ctx, q.Id, t.Options)
+			if err != nil {
+				return err
+			}
+		}
+		if len(t.PaymentSchedule) > 0 {
+			_, err = v.store.UpdateQuotePaymentSchedule(ctx, q.Id, t.PaymentSchedule)
+			if err != nil {
+				return err
+			}
+		}
+
+		source := &Quote{LineItemIds: t.LineItemIds}
+		lineItems := map[ID]*LineItem{}
+		for _, li := range t.LineItems {
+			lineItems[li.Id] = li
+		}
+		visited := map[ID]bool{}
+		for _, lineItemId := range lineItemChildren(source, lineItems, nil) {
+			_, err = restoreLineItemTree(ctx, v.store, source, lineItems, lineItems[lineItemId], q.Id, nil, !overrides.KeepTemplatePrices, visited)
+			if err != nil {
+				return err
+			}
+		}
+		for _, a := range t.Adjustments {
+			_, err = copyAdjustment(ctx, v.store, a, q.Id)
+			if err != nil {
+				return err
+			}
+		}
+
+		q, err = recalculateQuote(ctx, v.store, q.Id)
+		return err
+	})
+}
+
+// Lock prevents any further changes to the quote, its line items and its
+// adjustments. Changes to a locked quote fail with a *QuoteLockedError.
 func (v *priceyQuote) Lock(ctx context.Context, id ID) (*Quote, error) {
 	return v.store.LockQuote(ctx, id)
 }
 
-func (v *priceyQuote) Delete(ctx context.Context, id ID) (*Quote, error) {
-	return v.store.DeleteQuote(ctx, id)
+// Unlock allows a locked quote to be changed again. Sold quotes are contracts,
+// so a reason is required and the unlock is recorded on the quote along with
+// actor.
+func (v *priceyQuote) Unlock(ctx context.Context, id ID, actor ID, reason string) (*Quote, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, UnlockReasonRequiredError
+	}
+	return v.store.UnlockQuote(ctx, id, QuoteUnlock{Actor: actor, Reason: reason, On: time.Now()})
+}
+
+func (v *priceyQuote) Delete(ctx context.Context, id ID) (*Quote, error) {
+	return v.store.DeleteQuote(ctx, id)
+}
+
+type priceyQuoteTemplate struct {
+	store Store
+}
+
+func (v *priceyQuoteTemplate) Get(ctx context.Context, id ID) (*QuoteTemplate, error) {
+	return v.store.GetQuoteTemplate(ctx, id)
+}
+
+// List returns the group's templates ordered by name.
+func (v *priceyQuoteTemplate) List(ctx context.Context) ([]*QuoteTemplate, error) {
+	return v.store.GetQuoteTemplates(ctx)
+}
+
+// Search returns the group's templates whose name or description contains search.
+func (v *priceyQuoteTemplate) Search(ctx context.Context, search string) ([]*QuoteTemplate, error) {
+	return v.store.SearchQuoteTemplates(ctx, search)
+}
+
+func (v *priceyQuoteTemplate) SetInfo(ctx context.Context, id ID, name, description string) (*QuoteTemplate, error) {
+	return v.store.UpdateQuoteTemplateInfo(ctx, id, name, description)
+}
+
+func (v *priceyQuoteTemplate) Delete(ctx context.Context, id ID) error {
+	return v.store.DeleteQuoteTemplate(ctx, id)
+}
+
+type priceyLineItem struct {
+	store Store
+}
+
+func (v *priceyLineItem) New(ctx context.Context, quoteId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
+	return v.Insert(ctx, quoteId, nil, nil, description, quantity, unitPrice, amount)
+}
+
+func (v *priceyLineItem) NewSub(ctx context.Context, quoteId, parentId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
+	return v.Insert(ctx, quoteId, &parentId, nil, description, quantity, unitPrice, amount)
+}
+
+// Insert creates a line item under parentId (or at the top level when nil) at
+// index among its siblings. A nil index adds it after the last sibling.
+func (v *priceyLineItem) Insert(ctx context.Context, quoteId ID, parentId *ID, index *int, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
+	var item *LineItem
+	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if parentId == nil {
+			item, err = v.store.CreateLineItem(ctx, quoteId, description, quantity, unitPrice, amount)
+		} else {
+			item, err = v.store.CreateSubLineItem(ctx, quoteId, *parentId, description, quantity, unitPrice, amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = v.store.QuoteAddLineItem(ctx, quoteId, item.Id)
+		if err != nil {
+			return err
+		}
+
+		if parentId != nil {
+			_, err = v.store.LineItemAddSubItem(ctx, *parentId, item.Id)
+			if err != nil {
+				return err
+			}
+		}
+
+		item, err = v.store.MoveLineItem(ctx, item.Id, parentId, index)
+		if err != nil {
+			return err
+		}
+
+		_, err = recalculateQuote(ctx, v.store, quoteId)
+		return err
+	})
+}
+
+// Duplicate copies the line item along with all of its sub line items and
+// places the copy directly after it.
+func (v *priceyLineItem) Duplicate(ctx context.Context, id ID) (*LineItem, error) {
+	var item *LineItem
+	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
+		original, err := v.store.GetLineItem(ctx, id)
+		if err != nil {
+			return err
+		}
+		q, err := v.store.GetQuote(ctx, original.QuoteId)
+		if err != nil {
+			return err
+		}
+		lineItems, _, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, q)
+		if err != nil {
+			return err
+		}
+		index := slices.Index(lineItemChildren(q, lineItems, original.ParentId), id) + 1
+
+		item, err = duplicateLineItemTree(ctx, v.store, q, lineItems, original, original.QuoteId, original.ParentId, map[ID]bool{})
+		if err != nil {
+			return err
+		}
+
+		item, err = v.store.MoveLineItem(ctx, item.Id, item.ParentId, &index)
+		if err != nil {
+			return err
+		}
+
+		_, err = recalculateQuote(ctx, v.store, item.QuoteId)
+		return err
+	})
+}
+
+func (v *priceyLineItem) Get(ctx context.Context, id ID) (*LineItem, error) {
+	return v.store.GetLineItem(ctx, id)
+}
+
+// Move reparents the line item under parentId (or to the top level when nil)
+// at index among its new siblings, carrying its sub line items with it. Moving
+// a line item beneath itself or one of its own sub line items fails with
+// LineItemCycleError. A line item moved beneath another leaves its option and
+// follows its new parent's.
+func (v *priceyLineItem) Move(ctx context.Context, id ID, parentId *ID, index *int) (*LineItem, error) {
+	var item *LineItem
+	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		item, err = v.store.MoveLineItem(ctx, id, parentId, index)
+		if err != nil {
+			return err
+		}
+		if parentId != nil && item.OptionId != "" {
+			item, err = v.store.UpdateLineItemOptionId(ctx, id, "")
+			if err != nil {
+				return err
+			}
+		}
+		_, err = recalculateQuote(ctx, v.store, item.QuoteId)
+		return err
+	})
+}
+
+func (v *priceyLineItem) SetImage(ctx context.Context, id ID, imageId *ID) (*LineItem, error) {
+	return v.store.UpdateLineItemImage(ctx, id, imageId)
 }
 
-type priceyLineItem struct {
-	store Store
+func (v *priceyLineItem) SetDescription(ctx context.Context, id ID, description string) (*LineItem, error) {
+	return v.store.UpdateLineItemDescription(ctx, id, description)
 }
 
-func (v *priceyLineItem) New(ctx context.Context, quoteId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
+func (v *priceyLineItem) SetQuantity(ctx context.Context, id ID, quantity int, prefix, suffix string) (*LineItem, error) {
 	var item *LineItem
 	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
 		var err error
-		item, err = v.store.CreateLineItem(ctx, quoteId, description, quantity, unitPrice, amount)
+		item, err = v.store.UpdateLineItemQuantity(ctx, id, quantity, prefix, suffix)
 		if err != nil {
 			return err
 		}
+		_, err = recalculateQuote(ctx, v.store, item.QuoteId)
+		return err
+	})
+}
 
-		// TODO: need to figure out how the line items are ordered in the list
-		_, err = v.store.QuoteAddLineItem(ctx, quoteId, item.Id)
+func (v *priceyLineItem) SetUnitPrice(ctx context.Context, id ID, unitPrice int, prefix, suffix string) (*LineItem, error) {
+	var item *LineItem
+	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		item, err = v.store.UpdateLineItemUnitPrice(ctx, id, unitPrice, prefix, suffix)
 		if err != nil {
 			return err
 		}
-
-		return nil
+		_, err = recalculateQuote(ctx, v.store, item.QuoteId)
+		return err
 	})
 }
 
-func (v *priceyLineItem) NewSub(ctx context.Context, quoteId, parentId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
+// SetUnitCost changes what one unit of the line item costs the business. It
+// only feeds the profitability report, so it can be set on locked quotes.
+func (v *priceyLineItem) SetUnitCost(ctx context.Context, id ID, unitCost int) (*LineItem, error) {
+	return v.store.UpdateLineItemUnitCost(ctx, id, unitCost)
+}
+
+func (v *priceyLineItem) SetAmount(ctx context.Context, id ID, amount *int, prefix, suffix string) (*LineItem, error) {
 	var item *LineItem
 	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
 		var err error
-		item, err = v.store.CreateSubLineItem(ctx, quoteId, parentId, description, quantity, unitPrice, amount)
+		item, err = v.store.UpdateLineItemAmount(ctx, id, amount, prefix, suffix)
 		if err != nil {
 			return err
 		}
+		_, err = recalculateQuote(ctx, v.store, item.QuoteId)
+		return err
+	})
+}
 
-		// TODO: need to figure out how the line items are ordered in the list
-		_, err = v.store.QuoteAddLineItem(ctx, item.QuoteId, item.Id)
+// SetOption places a top level line item, along with its sub line items, in one
+// of the quote's options. An empty optionId shares it between every option.
+func (v *priceyLineItem) SetOption(ctx context.Context, id ID, optionId ID) (*LineItem, error) {
+	var item *LineItem
+	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		item, err = v.store.GetLineItem(ctx, id)
 		if err != nil {
 			return err
 		}
-
-		return nil
+		if item.ParentId != nil {
+			return LineItemOptionError
+		}
+		q, err := v.store.GetQuote(ctx, item.QuoteId)
+		if err != nil {
+			return err
+		}
+		if optionId != "" && quoteOption(q, optionId) == nil {
+			return QuoteOptionNotFoundError
+		}
+		item, err = v.store.UpdateLineItemOptionId(ctx, id, optionId)
+		if err != nil {
+			return err
+		}
+		_, err = recalculateQuote(ctx, v.store, item.QuoteId)
+		return err
 	})
 }
 
-func (v *priceyLineItem) Duplicate(ctx context.Context, id ID) (*LineItem, error) {
+// SetOptional marks the line item as optional, leaving it out of the quote's
+// totals until the customer selects it.
+func (v *priceyLineItem) SetOptional(ctx context.Context, id ID, optional bool) (*LineItem, error) {
 	var item *LineItem
 	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
 		var err error
-		item, err = v.store.CreateDuplicateLineItem(ctx, id)
+		item, err = v.store.UpdateLineItemOptional(ctx, id, optional)
 		if err != nil {
 			return err
 		}
+		_, err = recalculateQuote(ctx, v.store, item.QuoteId)
+		return err
+	})
+}
 
-		// TODO: need to figure out how the line items are ordered in the list
-		_, err = v.store.QuoteAddLineItem(ctx, item.QuoteId, item.Id)
+// SetAlternateGroup puts the line item in an alternate group, only one line
+// item of which is priced. An empty group takes it out of any group.
+func (v *priceyLineItem) SetAlternateGroup(ctx context.Context, id ID, alternateGroup string) (*LineItem, error) {
+	var item *LineItem
+	return item, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		item, err = v.store.UpdateLineItemAlternateGroup(ctx, id, alternateGroup)
 		if err != nil {
 			return err
 		}
-
-		return nil
+		_, err = recalculateQuote(ctx, v.store, item.QuoteId)
+		return err
 	})
 }
 
-func (v *priceyLineItem) Get(ctx context.Context, id ID) (*LineItem, error) {
-	return v.store.GetLineItem(ctx, id)
-}
-
-func (v *priceyLineItem) Move(ctx context.Context, id ID, parentId *ID, index *int) (*LineItem, error) {
-	return v.store.MoveLineItem(ctx, id, parentId, index)
-}
-
-func (v *priceyLineItem) SetImage(ctx context.Context, id ID, imageId *ID) (*LineItem, error) {
-	return v.store.UpdateLineItemImage(ctx, id, imageId)
-}
-
-func (v *priceyLineItem) SetDescription(ctx context.Context, id ID, description string) (*LineItem, error) {
-	return v.store.UpdateLineItemDescription(ctx, id, description)
-}
-
-func (v *priceyLineItem) SetQuantity(ctx context.Context, id ID, quantity int, prefix, suffix string) (*LineItem, error) {
-	return v.store.UpdateLineItemQuantity(ctx, id, quantity, prefix, suffix)
-}
-
-func (v *priceyLineItem) SetUnitPrice(ctx context.Context, id ID, unitPrice int, prefix, suffix string) (*LineItem, error) {
-	return v.store.UpdateLineItemUnitPrice(ctx, id, unitPrice, prefix, suffix)
-}
-
-func (v *priceyLineItem) SetAmount(ctx context.Context, id ID, amount *int, prefix, suffix string) (*LineItem, error) {
-	return v.store.UpdateLineItemAmount(ctx, id, amount, prefix, suffix)
-}
-
 func (v *priceyLineItem) SetOpen(ctx context.Context, id ID, open bool) (*LineItem, error) {
 	return v.store.UpdateLineItemOpen(ctx, id, open)
 }
 
+// Delete removes the line item and its sub line items from the quote.
 func (v *priceyLineItem) Delete(ctx context.Context, id ID) error {
-	return v.store.DeleteLineItem(ctx, id)
+	return v.store.Transaction(ctx, func(ctx context.Context) error {
+		item, err := v.store.GetLineItem(ctx, id)
+		if err != nil {
+			return err
+		}
+		if item.ParentId != nil {
+			_, err = v.store.LineItemRemoveSubItem(ctx, *item.ParentId, id)
+			if err != nil {
+				return err
+			}
+		}
+		err = deleteLineItemTree(ctx, v.store, item, map[ID]bool{})
+		if err != nil {
+			return err
+		}
+		_, err = recalculateQuote(ctx, v.store, item.QuoteId)
+		return err
+	})
 }
 
 type priceyAdjustment struct {
@@ -671,7 +2187,11 @@ func (v *priceyAdjustment) New(ctx context.Context, quoteId ID, description stri
 			return err
 		}
 
-		return nil
+		q, err := recalculateQuote(ctx, v.store, quoteId)
+		if err != nil {
+			return err
+		}
+		return flagQuotePolicy(ctx, v.store, q)
 	})
 }
 
@@ -680,7 +2200,45 @@ func (v *priceyAdjustment) Get(ctx context.Context, id ID) (*Adjustment, error)
 }
 
 func (v *priceyAdjustment) Update(ctx context.Context, id ID, description string, amount int, adjustmentType AdjustmentType) (*Adjustment, error) {
-	return v.store.UpdateAdjustment(ctx, id, description, amount, adjustmentType)
+	var a *Adjustment
+	return a, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		a, err = v.store.UpdateAdjustment(ctx, id, description, amount, adjustmentType)
+		if err != nil {
+			return err
+		}
+		q, err := recalculateQuote(ctx, v.store, a.QuoteId)
+		if err != nil {
+			return err
+		}
+		return flagQuotePolicy(ctx, v.store, q)
+	})
+}
+
+// SetOption places the adjustment in one of the quote's options, applying it
+// only to that option's subtotal. An empty optionId applies it to every option.
+func (v *priceyAdjustment) SetOption(ctx context.Context, id ID, optionId ID) (*Adjustment, error) {
+	var a *Adjustment
+	return a, v.store.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		a, err = v.store.GetAdjustment(ctx, id)
+		if err != nil {
+			return err
+		}
+		q, err := v.store.GetQuote(ctx, a.QuoteId)
+		if err != nil {
+			return err
+		}
+		if optionId != "" && quoteOption(q, optionId) == nil {
+			return QuoteOptionNotFoundError
+		}
+		a, err = v.store.UpdateAdjustmentOptionId(ctx, id, optionId)
+		if err != nil {
+			return err
+		}
+		_, err = recalculateQuote(ctx, v.store, a.QuoteId)
+		return err
+	})
 }
 
 func (v *priceyAdjustment) Delete(ctx context.Context, id ID) error {
@@ -703,7 +2261,70 @@ func (v *priceyAdjustment) Delete(ctx context.Context, id ID) error {
 			return err
 		}
 
-		return nil
+		_, err = recalculateQuote(ctx, v.store, a.QuoteId)
+		return err
+	})
+}
+
+type priceyPayment struct {
+	store Store
+}
+
+// New records money received against payment.QuoteId, or paid back to the
+// customer when payment.Refund is set. A payment may be for one of the quote's
+// payment schedule milestones. ReceivedOn defaults to now. Payments more than
+// the total are kept and reported as overpaid on the quote's balance, while a
+// refund of more than has been paid fails with RefundExceedsPaymentsError.
+func (v *priceyPayment) New(ctx context.Context, payment Payment) (*Payment, error) {
+	var pm *Payment
+	return pm, v.store.Transaction(ctx, func(ctx context.Context) error {
+		q, err := v.store.GetQuote(ctx, payment.QuoteId)
+		if err != nil {
+			return err
+		}
+		payments, err := v.store.GetQuotePayments(ctx, payment.QuoteId)
+		if err != nil {
+			return err
+		}
+		err = validatePayment(q, payment, payments)
+		if err != nil {
+			return err
+		}
+		if payment.ReceivedOn.IsZero() {
+			payment.ReceivedOn = time.Now()
+		}
+		pm, err = v.store.CreatePayment(ctx, payment)
+		return err
+	})
+}
+
+func (v *priceyPayment) Get(ctx context.Context, id ID) (*Payment, error) {
+	return v.store.GetPayment(ctx, id)
+}
+
+// List returns the quote's payments and refunds in the order they were
+// received.
+func (v *priceyPayment) List(ctx context.Context, quoteId ID) ([]*Payment, error) {
+	return v.store.GetQuotePayments(ctx, quoteId)
+}
+
+// Delete removes a payment recorded in error. A payment cannot be removed when
+// the quote's refunds would then be more than has been paid.
+func (v *priceyPayment) Delete(ctx context.Context, id ID) error {
+	return v.store.Transaction(ctx, func(ctx context.Context) error {
+		pm, err := v.store.GetPayment(ctx, id)
+		if err != nil {
+			return err
+		}
+		payments, err := v.store.GetQuotePayments(ctx, pm.QuoteId)
+		if err != nil {
+			return err
+		}
+		remaining := slices.DeleteFunc(payments, func(p *Payment) bool { return p.Id == id })
+		if quoteBalance(pm.QuoteId, 0, nil, remaining).Paid < 0 {
+			return RefundExceedsPaymentsError
+		}
+		return v.store.DeletePayment(ctx, id)
 	})
 }
 
@@ -711,6 +2332,231 @@ type priceyContact struct {
 	store Store
 }
 
+func (v *priceyContact) New(ctx context.Context, contact Contact) (*Contact, error) {
+	return v.store.CreateContact(ctx, contact)
+}
+
 func (v *priceyContact) Get(ctx context.Context, id ID) (*Contact, error) {
 	return v.store.GetContact(ctx, id)
 }
+
+// List returns the group's contacts ordered by name.
+func (v *priceyContact) List(ctx context.Context) ([]*Contact, error) {
+	return v.store.GetContacts(ctx)
+}
+
+// Search returns the group's contacts whose name, company name, emails or
+// phones contain search.
+func (v *priceyContact) Search(ctx context.Context, search string) ([]*Contact, error) {
+	return v.store.SearchContacts(ctx, search)
+}
+
+func (v *priceyContact) Set(ctx context.Context, contact Contact) (*Contact, error) {
+	return v.store.UpdateContact(ctx, contact)
+}
+
+func (v *priceyContact) Delete(ctx context.Context, id ID) error {
+	return v.store.DeleteContact(ctx, id)
+}
+
+// Duplicates lists the group's contacts that look like the same person or
+// company as contact, so they can be offered before a new contact is created.
+// See contactMatches for what counts as a match.
+func (v *priceyContact) Duplicates(ctx context.Context, contact Contact) ([]*ContactMatch, error) {
+	searches := []string{}
+	searches = append(searches, contact.Emails...)
+	for _, phone := range contact.Phones {
+		searches = append(searches, phoneDigits(phone))
+	}
+	searches = append(searches, contact.Name, contact.CompanyName)
+
+	candidates := []*Contact{}
+	seen := map[ID]bool{}
+	for _, search := range searches {
+		search = strings.TrimSpace(search)
+		if search == "" {
+			continue
+		}
+		found, err := v.store.SearchContacts(ctx, search)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range found {
+			if !seen[c.Id] {
+				seen[c.Id] = true
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return contactMatches(contact, candidates), nil
+}
+
+type priceyCustomer struct {
+	store Store
+}
+
+func (v *priceyCustomer) New(ctx context.Context, customer Customer) (*Customer, error) {
+	return v.store.CreateCustomer(ctx, customer)
+}
+
+func (v *priceyCustomer) Get(ctx context.Context, id ID) (*Customer, error) {
+	return v.store.GetCustomer(ctx, id)
+}
+
+// List returns the group's customers ordered by name.
+func (v *priceyCustomer) List(ctx context.Context) ([]*Customer, error) {
+	return v.store.GetCustomers(ctx)
+}
+
+// Search returns the group's customers whose name, notes, tags, contacts or
+// addresses contain search.
+func (v *priceyCustomer) Search(ctx context.Context, search string) ([]*Customer, error) {
+	return v.store.SearchCustomers(ctx, search)
+}
+
+func (v *priceyCustomer) SetInfo(ctx context.Context, id ID, name string, tags []string, notes string) (*Customer, error) {
+	return v.store.UpdateCustomerInfo(ctx, id, name, tags, notes)
+}
+
+func (v *priceyCustomer) AddContact(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	return v.store.CustomerAddContact(ctx, id, contactId)
+}
+
+func (v *priceyCustomer) RemoveContact(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	return v.store.CustomerRemoveContact(ctx, id, contactId)
+}
+
+// AddAddress adds a service address to the customer. Addresses are contacts,
+// so they can be used as a quote's ship to.
+func (v *priceyCustomer) AddAddress(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	return v.store.CustomerAddAddress(ctx, id, contactId)
+}
+
+func (v *priceyCustomer) RemoveAddress(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	return v.store.CustomerRemoveAddress(ctx, id, contactId)
+}
+
+func (v *priceyCustomer) Delete(ctx context.Context, id ID) error {
+	return v.store.DeleteCustomer(ctx, id)
+}
+
+// History lists every quote made for the customer, newest first, along with
+// what was quoted and accepted in total. Deleted quotes are left out.
+func (v *priceyCustomer) History(ctx context.Context, id ID) (*CustomerHistory, error) {
+	var history *CustomerHistory
+	return history, v.store.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := v.store.GetCustomer(ctx, id); err != nil {
+			return err
+		}
+		quotes := []*Quote{}
+		for {
+			page, err := v.store.ListQuotes(ctx, QuoteFilter{
+				CustomerId: &id,
+				Sort:       QuoteSortCreated,
+				Descending: true,
+				Limit:      MaxQuoteLimit,
+				Offset:     len(quotes),
+			})
+			if err != nil {
+				return err
+			}
+			quotes = append(quotes, page.Quotes...)
+			if len(page.Quotes) == 0 || len(quotes) >= page.Total {
+				break
+			}
+		}
+		history = customerHistory(id, quotes)
+		return nil
+	})
+}
+
+type priceyInvoice struct {
+	store Store
+	Print *priceyPrint
+}
+
+// New invoices an accepted quote, or part of it as set out in req (see
+// buildInvoice), numbering the invoice from the group's invoice sequence. The
+// first invoice moves the quote on to converted, recording actor as who moved
+// it.
+func (v *priceyInvoice) New(ctx context.Context, quoteId ID, req InvoiceRequest, actor ID) (*Invoice, error) {
+	var inv *Invoice
+	return inv, v.store.Transaction(ctx, func(ctx context.Context) error {
+		quote, err := v.store.GetQuote(ctx, quoteId)
+		if err != nil {
+			return err
+		}
+		lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, quote)
+		if err != nil {
+			return err
+		}
+		contacts, err := loadQuoteContacts(ctx, v.store, quote)
+		if err != nil {
+			return err
+		}
+		invoiced, err := v.store.GetQuoteInvoices(ctx, quoteId)
+		if err != nil {
+			return err
+		}
+		inv, err = buildInvoice(quote, lineItems, adjustments, contacts, invoiced, req)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		seq, err := v.store.AllocateInvoiceNumber(ctx, now.Year())
+		if err != nil {
+			return err
+		}
+		inv.Number = formatQuoteNumber(seq.Pattern, seq.Year, seq.Counter)
+		inv.IssueDate = now
+		inv, err = v.store.CreateInvoice(ctx, *inv)
+		if err != nil {
+			return err
+		}
+
+		if quoteStatus(quote) == QuoteStatusAccepted {
+			_, err = transitionQuote(ctx, v.store, quoteId, QuoteStatusConverted, actor)
+		}
+		return err
+	})
+}
+
+func (v *priceyInvoice) Get(ctx context.Context, id ID) (*Invoice, error) {
+	return v.store.GetInvoice(ctx, id)
+}
+
+// List returns the invoices made from the quote, oldest first.
+func (v *priceyInvoice) List(ctx context.Context, quoteId ID) ([]*Invoice, error) {
+	return v.store.GetQuoteInvoices(ctx, quoteId)
+}
+
+func (v *priceyInvoice) SetDueDate(ctx context.Context, id ID, dueDate *time.Time) (*Invoice, error) {
+	return v.store.UpdateInvoiceDueDate(ctx, id, dueDate)
+}
+
+func (v *priceyInvoice) SetPaymentTerms(ctx context.Context, id ID, paymentTerms string) (*Invoice, error) {
+	return v.store.UpdateInvoicePaymentTerms(ctx, id, paymentTerms)
+}
+
+// Delete voids the invoice, leaving what it covered free to be invoiced again.
+// Its number is not reused.
+func (v *priceyInvoice) Delete(ctx context.Context, id ID) error {
+	return v.store.DeleteInvoice(ctx, id)
+}
+
+// SetNumberSequence configures how the group's invoices are numbered, using the
+// same pattern tokens as quote numbers, e.g. "INV-{YYYY}-{N:5}". Until it is
+// set invoices are numbered with DefaultInvoiceNumberPattern.
+func (v *priceyInvoice) SetNumberSequence(ctx context.Context, pattern string, resetYearly bool) (*InvoiceNumberSequence, error) {
+	if !validQuoteNumberPattern(pattern) {
+		return nil, QuoteNumberPatternError
+	}
+	return v.store.SetInvoiceNumberSequence(ctx, InvoiceNumberSequence{Pattern: pattern, ResetYearly: resetYearly})
+}
+
+// NumberSequence returns the group's invoice number sequence, or ErrNotFound
+// when no invoice has been numbered yet.
+func (v *priceyInvoice) NumberSequence(ctx context.Context) (*InvoiceNumberSequence, error) {
+	return v.store.GetInvoiceNumberSequence(ctx)
+}
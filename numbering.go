@@ -0,0 +1,77 @@
+package pricey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var quoteNumberToken = regexp.MustCompile(`\{(YYYY|YY|N(?::(\d+))?)\}`)
+
+// validQuoteNumberPattern reports whether the pattern has a counter, without
+// which every quote would get the same number.
+func validQuoteNumberPattern(pattern string) bool {
+	for _, m := range quoteNumberToken.FindAllStringSubmatch(pattern, -1) {
+		if m[1][0] == 'N' {
+			return true
+		}
+	}
+	return false
+}
+
+// formatQuoteNumber expands the pattern's tokens: {YYYY} and {YY} become the
+// year, {N} the counter and {N:width} the counter zero padded to width.
+// Anything else in the pattern is kept as is.
+func formatQuoteNumber(pattern string, year, counter int) string {
+	return quoteNumberToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		m := quoteNumberToken.FindStringSubmatch(token)
+		switch m[1] {
+		case "YYYY":
+			return fmt.Sprintf("%04d", year)
+		case "YY":
+			return fmt.Sprintf("%02d", year%100)
+		}
+		width, _ := strconv.Atoi(m[2])
+		return fmt.Sprintf("%0*d", width, counter)
+	})
+}
+
+// advanceQuoteNumberSequence moves the sequence on to its next number for a
+// quote numbered in year, starting again from 1 in a new year when the
+// sequence resets yearly.
+func advanceQuoteNumberSequence(seq *QuoteNumberSequence, year int) {
+	if seq.ResetYearly && seq.Year != year {
+		seq.Counter = 0
+	}
+	seq.Counter++
+	seq.Year = year
+}
+
+// assignQuoteNumber gives the quote the next number from its group's sequence
+// when it does not have a code yet. Quotes are numbered as they are created
+// when the sequence is set to QuoteNumberOnCreate, and any quote still without
+// a number is numbered as it is sent. Without a sequence the quote is left as
+// it is.
+func assignQuoteNumber(ctx context.Context, store Store, q *Quote, sending bool) (*Quote, error) {
+	if q.Code != "" {
+		return q, nil
+	}
+	seq, err := store.GetQuoteNumberSequence(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !sending && seq.AssignOn != QuoteNumberOnCreate {
+		return q, nil
+	}
+	seq, err = store.AllocateQuoteNumber(ctx, time.Now().Year())
+	if err != nil {
+		return nil, err
+	}
+	return store.UpdateQuoteCode(ctx, q.Id, formatQuoteNumber(seq.Pattern, seq.Year, seq.Counter))
+}
@@ -0,0 +1,65 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatQuoteNumber(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pattern  string
+		year     int
+		counter  int
+		expected string
+	}{
+		{name: "padded", pattern: "EST-{YYYY}-{N:5}", year: 2026, counter: 42, expected: "EST-2026-00042"},
+		{name: "unpadded", pattern: "Q{N}", year: 2026, counter: 42, expected: "Q42"},
+		{name: "short year", pattern: "{YY}/{N:3}", year: 2026, counter: 7, expected: "26/007"},
+		{name: "counter wider than padding", pattern: "{N:2}", year: 2026, counter: 1234, expected: "1234"},
+		{name: "unknown tokens kept", pattern: "{X}-{N}", year: 2026, counter: 1, expected: "{X}-1"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, formatQuoteNumber(tc.pattern, tc.year, tc.counter))
+		})
+	}
+}
+
+func TestValidQuoteNumberPattern(t *testing.T) {
+	assert.True(t, validQuoteNumberPattern("EST-{YYYY}-{N:5}"))
+	assert.True(t, validQuoteNumberPattern("{N}"))
+	assert.False(t, validQuoteNumberPattern("EST-{YYYY}"))
+	assert.False(t, validQuoteNumberPattern("N"))
+}
+
+func TestAdvanceQuoteNumberSequence(t *testing.T) {
+	testCases := []struct {
+		name            string
+		seq             QuoteNumberSequence
+		year            int
+		expectedCounter int
+	}{
+		{name: "first number", seq: QuoteNumberSequence{}, year: 2026, expectedCounter: 1},
+		{name: "same year", seq: QuoteNumberSequence{Counter: 41, Year: 2026, ResetYearly: true}, year: 2026, expectedCounter: 42},
+		{name: "new year resets", seq: QuoteNumberSequence{Counter: 41, Year: 2025, ResetYearly: true}, year: 2026, expectedCounter: 1},
+		{name: "new year continues", seq: QuoteNumberSequence{Counter: 41, Year: 2025}, year: 2026, expectedCounter: 42},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			seq := tc.seq
+			advanceQuoteNumberSequence(&seq, tc.year)
+			assert.Equal(t, tc.expectedCounter, seq.Counter)
+			assert.Equal(t, tc.year, seq.Year)
+		})
+	}
+}
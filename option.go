@@ -0,0 +1,111 @@
+package pricey
+
+import (
+	"context"
+	"slices"
+)
+
+// quoteOption returns the quote's option with optionId, or nil when the quote
+// has no such option.
+func quoteOption(quote *Quote, optionId ID) *QuoteOption {
+	for i := range quote.Options {
+		if quote.Options[i].Id == optionId {
+			return &quote.Options[i]
+		}
+	}
+	return nil
+}
+
+// activeQuoteOptionId is the option a quote is priced as: the chosen option, or
+// the first until one is chosen. Quotes without options have none.
+func activeQuoteOptionId(quote *Quote) ID {
+	if quote.ChosenOptionId != "" && quoteOption(quote, quote.ChosenOptionId) != nil {
+		return quote.ChosenOptionId
+	}
+	if len(quote.Options) > 0 {
+		return quote.Options[0].Id
+	}
+	return ""
+}
+
+// inQuoteOption reports whether something placed in placedIn is part of
+// optionId. Anything not placed in one of the quote's options is shared.
+func inQuoteOption(quote *Quote, placedIn, optionId ID) bool {
+	return placedIn == "" || placedIn == optionId || quoteOption(quote, placedIn) == nil
+}
+
+// compareQuoteOptions prices each of the quote's options in order.
+func compareQuoteOptions(quote *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) []*QuoteOptionTotals {
+	comparison := []*QuoteOptionTotals{}
+	for _, option := range quote.Options {
+		comparison = append(comparison, &QuoteOptionTotals{
+			Option: option,
+			Totals: calculateOptionTotals(quote, lineItems, adjustments, option.Id),
+			Chosen: option.Id == quote.ChosenOptionId,
+		})
+	}
+	return comparison
+}
+
+// copyAdjustment creates a copy of the adjustment on quoteId, in the same
+// option, and adds it to the quote.
+func copyAdjustment(ctx context.Context, store Store, a *Adjustment, quoteId ID) (*Adjustment, error) {
+	dup, err := store.CreateAdjustment(ctx, quoteId, a.Description, a.Amount, a.Type)
+	if err != nil {
+		return nil, err
+	}
+	if a.OptionId != "" {
+		dup, err = store.UpdateAdjustmentOptionId(ctx, dup.Id, a.OptionId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = store.QuoteAddAdjustment(ctx, quoteId, dup.Id)
+	if err != nil {
+		return nil, err
+	}
+	return dup, nil
+}
+
+// removeQuoteOption deletes the option's line items and adjustments along with
+// the option itself. A quote that had chosen the option is left unchosen.
+func removeQuoteOption(ctx context.Context, store Store, quote *Quote, optionId ID) (*Quote, error) {
+	if quoteOption(quote, optionId) == nil {
+		return nil, QuoteOptionNotFoundError
+	}
+	lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, store, quote)
+	if err != nil {
+		return nil, err
+	}
+	visited := map[ID]bool{}
+	for _, lineItemId := range lineItemChildren(quote, lineItems, nil) {
+		if li := lineItems[lineItemId]; li.OptionId == optionId {
+			err = deleteLineItemTree(ctx, store, li, visited)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, adjustmentId := range quote.AdjustmentIds {
+		if a := adjustments[adjustmentId]; a.OptionId == optionId {
+			err = store.RemoveAdjustment(ctx, adjustmentId)
+			if err != nil {
+				return nil, err
+			}
+			_, err = store.QuoteRemoveAdjustment(ctx, quote.Id, adjustmentId)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if quote.ChosenOptionId == optionId {
+		_, err = store.UpdateQuoteChosenOptionId(ctx, quote.Id, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	options := slices.DeleteFunc(slices.Clone(quote.Options), func(o QuoteOption) bool {
+		return o.Id == optionId
+	})
+	return store.UpdateQuoteOptions(ctx, quote.Id, options)
+}
@@ -0,0 +1,71 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActiveQuoteOptionId(t *testing.T) {
+	options := []QuoteOption{{Id: "good"}, {Id: "better"}, {Id: "best"}}
+	testCases := []struct {
+		name     string
+		quote    *Quote
+		expected ID
+	}{
+		{name: "no options", quote: &Quote{}, expected: ""},
+		{name: "first until chosen", quote: &Quote{Options: options}, expected: "good"},
+		{name: "chosen", quote: &Quote{Options: options, ChosenOptionId: "best"}, expected: "best"},
+		{name: "chosen option removed", quote: &Quote{Options: options, ChosenOptionId: "missing"}, expected: "good"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, activeQuoteOptionId(tc.quote))
+		})
+	}
+}
+
+func TestCompareQuoteOptions(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	quote := &Quote{
+		LineItemIds:    []ID{"1", "2", "3", "4", "5", "6"},
+		AdjustmentIds:  []ID{"1", "2"},
+		Options:        []QuoteOption{{Id: "good", Name: "Good"}, {Id: "better", Name: "Better"}},
+		ChosenOptionId: "better",
+	}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Quantity: 100, UnitPrice: 10000},
+		"2": {Id: "2", OptionId: "good", Quantity: 100, UnitPrice: 50000},
+		"3": {Id: "3", OptionId: "better", SubItemIds: []ID{"4", "5"}},
+		"4": {Id: "4", ParentId: s("3"), Quantity: 100, UnitPrice: 60000},
+		"5": {Id: "5", ParentId: s("3"), OptionId: "good", Quantity: 100, UnitPrice: 20000},
+		"6": {Id: "6", OptionId: "removed", Quantity: 100, UnitPrice: 500},
+	}
+	adjustments := map[ID]*Adjustment{
+		"1": {Id: "1", Type: AdjustmentTypeFlat, Amount: -5000},
+		"2": {Id: "2", OptionId: "better", Type: AdjustmentTypePercent, Amount: 10},
+	}
+
+	comparison := compareQuoteOptions(quote, lineItems, adjustments)
+	assert.Len(t, comparison, 2)
+
+	assert.Equal(t, "Good", comparison[0].Option.Name)
+	assert.False(t, comparison[0].Chosen)
+	assert.Equal(t, 60500, comparison[0].Totals.SubTotal)
+	assert.Equal(t, map[ID]int{"1": -5000}, comparison[0].Totals.AdjustmentAmounts)
+	assert.Equal(t, 55500, comparison[0].Totals.Total)
+
+	assert.Equal(t, "Better", comparison[1].Option.Name)
+	assert.True(t, comparison[1].Chosen)
+	assert.Equal(t, 90500, comparison[1].Totals.SubTotal)
+	assert.Equal(t, map[ID]int{"1": -5000, "2": 9050}, comparison[1].Totals.AdjustmentAmounts)
+	assert.Equal(t, 94550, comparison[1].Totals.Total)
+
+	assert.Equal(t, comparison[1].Totals, calculateQuoteTotals(quote, lineItems, adjustments))
+}
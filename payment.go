@@ -0,0 +1,129 @@
+package pricey
+
+import (
+	"time"
+)
+
+type PaymentMethod = string
+
+const (
+	PaymentMethodCash  PaymentMethod = "cash"
+	PaymentMethodCheck PaymentMethod = "check"
+	PaymentMethodCard  PaymentMethod = "card"
+	PaymentMethodBank  PaymentMethod = "bank"
+	PaymentMethodOther PaymentMethod = "other"
+)
+
+// Payment is money received from the customer against a quote, or when Refund
+// is set money paid back to them. Amount is always positive.
+type Payment struct {
+	Id        ID            `json:"id" firestore:"id"`
+	OrgId     ID            `json:"orgId" firestore:"orgId"`
+	GroupId   ID            `json:"groupId" firestore:"groupId"`
+	QuoteId   ID            `json:"quoteId" firestore:"quoteId"`
+	Amount    int           `json:"amount" firestore:"amount"`
+	Refund    bool          `json:"refund" firestore:"refund"`
+	Method    PaymentMethod `json:"method" firestore:"method"`
+	Reference string        `json:"reference" firestore:"reference"`
+	// ReceivedOn when the money was received, or refunded
+	ReceivedOn time.Time `json:"receivedOn" firestore:"receivedOn"`
+	// MilestoneId the payment schedule milestone the payment is for, if any
+	MilestoneId ID        `json:"milestoneId" firestore:"milestoneId"`
+	Created     time.Time `json:"created" firestore:"created"`
+	Updated     time.Time `json:"updated" firestore:"updated"`
+	Hidden      bool      `json:"hidden" firestore:"hidden"`
+}
+
+// QuoteBalance is what has been paid against a quote and what is left to pay.
+type QuoteBalance struct {
+	QuoteId  ID  `json:"quoteId" firestore:"quoteId"`
+	Total    int `json:"total" firestore:"total"`
+	Received int `json:"received" firestore:"received"`
+	Refunded int `json:"refunded" firestore:"refunded"`
+	// Paid received less refunded
+	Paid int `json:"paid" firestore:"paid"`
+	// Outstanding what is still owed, never less than zero
+	Outstanding int `json:"outstanding" firestore:"outstanding"`
+	// Overpaid how much more than the total has been paid, owed back to the customer
+	Overpaid   int                 `json:"overpaid" firestore:"overpaid"`
+	Milestones []*MilestoneBalance `json:"milestones" firestore:"milestones"`
+}
+
+// MilestoneBalance is how much of a payment schedule milestone has been paid.
+type MilestoneBalance struct {
+	MilestoneId ID  `json:"milestoneId" firestore:"milestoneId"`
+	Amount      int `json:"amount" firestore:"amount"`
+	Paid        int `json:"paid" firestore:"paid"`
+}
+
+// quoteBalance totals the payments against total. What has been paid is
+// applied to the payment schedule: payments for a milestone go to that
+// milestone first, and everything else fills the milestones in order.
+// Refunds come off the last milestones paid.
+func quoteBalance(quoteId ID, total int, schedule []PaymentMilestone, payments []*Payment) *QuoteBalance {
+	b := &QuoteBalance{QuoteId: quoteId, Total: total, Milestones: []*MilestoneBalance{}}
+	index := map[ID]int{}
+	for i, amount := range paymentScheduleAmounts(schedule, total) {
+		index[schedule[i].Id] = i
+		b.Milestones = append(b.Milestones, &MilestoneBalance{MilestoneId: schedule[i].Id, Amount: amount})
+	}
+
+	for _, p := range payments {
+		if p.Refund {
+			b.Refunded += p.Amount
+			continue
+		}
+		b.Received += p.Amount
+		if i, ok := index[p.MilestoneId]; ok && p.MilestoneId != "" {
+			m := b.Milestones[i]
+			m.Paid += min(p.Amount, m.Amount-m.Paid)
+		}
+	}
+	b.Paid = b.Received - b.Refunded
+	b.Outstanding = max(total-b.Paid, 0)
+	b.Overpaid = max(b.Paid-total, 0)
+
+	unapplied := b.Paid
+	for _, m := range b.Milestones {
+		unapplied -= m.Paid
+	}
+	for i := len(b.Milestones) - 1; i >= 0 && unapplied < 0; i-- {
+		m := b.Milestones[i]
+		taken := min(m.Paid, -unapplied)
+		m.Paid -= taken
+		unapplied += taken
+	}
+	for _, m := range b.Milestones {
+		applied := min(unapplied, m.Amount-m.Paid)
+		m.Paid += applied
+		unapplied -= applied
+	}
+	return b
+}
+
+// validatePayment checks a payment can be recorded against the quote: it must
+// be for a positive amount, for one of the quote's milestones when it names
+// one, and a refund cannot pay back more than has been paid.
+func validatePayment(quote *Quote, payment Payment, payments []*Payment) error {
+	if payment.Amount <= 0 {
+		return InvalidPaymentAmountError
+	}
+	if payment.MilestoneId != "" && paymentMilestone(quote, payment.MilestoneId) == nil {
+		return PaymentMilestoneNotFoundError
+	}
+	if payment.Refund && payment.Amount > quoteBalance(quote.Id, quote.Total, nil, payments).Paid {
+		return RefundExceedsPaymentsError
+	}
+	return nil
+}
+
+// paymentMilestone returns the milestone from the quote's payment schedule, or
+// nil when the quote has no such milestone.
+func paymentMilestone(quote *Quote, milestoneId ID) *PaymentMilestone {
+	for i := range quote.PaymentSchedule {
+		if quote.PaymentSchedule[i].Id == milestoneId {
+			return &quote.PaymentSchedule[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteBalance(t *testing.T) {
+	schedule := []PaymentMilestone{
+		{Id: "deposit", Type: AdjustmentTypePercent, Amount: 30},
+		{Id: "rough-in", Type: AdjustmentTypePercent, Amount: 40},
+		{Id: "completion", Type: AdjustmentTypePercent, Amount: 30},
+	}
+	testCases := []struct {
+		name     string
+		schedule []PaymentMilestone
+		payments []*Payment
+		expected *QuoteBalance
+	}{
+		{
+			name: "nothing paid",
+			expected: &QuoteBalance{
+				QuoteId: "1", Total: 10000, Outstanding: 10000,
+				Milestones: []*MilestoneBalance{},
+			},
+		},
+		{
+			name: "partly paid",
+			payments: []*Payment{
+				{Amount: 2500},
+				{Amount: 1500},
+			},
+			expected: &QuoteBalance{
+				QuoteId: "1", Total: 10000, Received: 4000, Paid: 4000, Outstanding: 6000,
+				Milestones: []*MilestoneBalance{},
+			},
+		},
+		{
+			name: "overpaid then refunded",
+			payments: []*Payment{
+				{Amount: 10000},
+				{Amount: 500},
+				{Amount: 300, Refund: true},
+			},
+			expected: &QuoteBalance{
+				QuoteId: "1", Total: 10000, Received: 10500, Refunded: 300, Paid: 10200, Overpaid: 200,
+				Milestones: []*MilestoneBalance{},
+			},
+		},
+		{
+			name:     "payments fill milestones in order",
+			schedule: schedule,
+			payments: []*Payment{
+				{Amount: 3000},
+				{Amount: 1000},
+			},
+			expected: &QuoteBalance{
+				QuoteId: "1", Total: 10000, Received: 4000, Paid: 4000, Outstanding: 6000,
+				Milestones: []*MilestoneBalance{
+					{MilestoneId: "deposit", Amount: 3000, Paid: 3000},
+					{MilestoneId: "rough-in", Amount: 4000, Paid: 1000},
+					{MilestoneId: "completion", Amount: 3000},
+				},
+			},
+		},
+		{
+			name:     "payments for a milestone go to it first",
+			schedule: schedule,
+			payments: []*Payment{
+				{Amount: 3000, MilestoneId: "completion"},
+				{Amount: 1000},
+			},
+			expected: &QuoteBalance{
+				QuoteId: "1", Total: 10000, Received: 4000, Paid: 4000, Outstanding: 6000,
+				Milestones: []*MilestoneBalance{
+					{MilestoneId: "deposit", Amount: 3000, Paid: 1000},
+					{MilestoneId: "rough-in", Amount: 4000},
+					{MilestoneId: "completion", Amount: 3000, Paid: 3000},
+				},
+			},
+		},
+		{
+			name:     "refunds come off the last milestones paid",
+			schedule: schedule,
+			payments: []*Payment{
+				{Amount: 3000, MilestoneId: "deposit"},
+				{Amount: 4000, MilestoneId: "rough-in"},
+				{Amount: 5000, Refund: true},
+			},
+			expected: &QuoteBalance{
+				QuoteId: "1", Total: 10000, Received: 7000, Refunded: 5000, Paid: 2000, Outstanding: 8000,
+				Milestones: []*MilestoneBalance{
+					{MilestoneId: "deposit", Amount: 3000, Paid: 2000},
+					{MilestoneId: "rough-in", Amount: 4000},
+					{MilestoneId: "completion", Amount: 3000},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, quoteBalance("1", 10000, tc.schedule, tc.payments))
+		})
+	}
+}
+
+func TestValidatePayment(t *testing.T) {
+	quote := &Quote{
+		Id:              "1",
+		Total:           10000,
+		PaymentSchedule: []PaymentMilestone{{Id: "deposit", Type: AdjustmentTypePercent, Amount: 100}},
+	}
+	paid := []*Payment{{Amount: 4000}, {Amount: 1000, Refund: true}}
+	testCases := []struct {
+		name     string
+		payment  Payment
+		expected error
+	}{
+		{name: "payment", payment: Payment{Amount: 6000}, expected: nil},
+		{name: "overpayment", payment: Payment{Amount: 20000}, expected: nil},
+		{name: "milestone payment", payment: Payment{Amount: 6000, MilestoneId: "deposit"}, expected: nil},
+		{name: "zero amount", payment: Payment{Amount: 0}, expected: InvalidPaymentAmountError},
+		{name: "negative amount", payment: Payment{Amount: -500}, expected: InvalidPaymentAmountError},
+		{name: "unknown milestone", payment: Payment{Amount: 500, MilestoneId: "missing"}, expected: PaymentMilestoneNotFoundError},
+		{name: "refund", payment: Payment{Amount: 3000, Refund: true}, expected: nil},
+		{name: "refund more than paid", payment: Payment{Amount: 3001, Refund: true}, expected: RefundExceedsPaymentsError},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, validatePayment(quote, tc.payment, paid))
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package pricey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"time"
+)
+
+// Role is a user's role within a group, such as "tech" or "manager". Roles are
+// defined by the application; pricey only compares them.
+type Role = string
+
+// AnyRole in QuotePolicy.MaxDiscount applies to every role not listed on its own.
+const AnyRole Role = "*"
+
+// QuotePolicy holds a group's pricing guardrails. Quotes that break it can
+// only be sent or accepted once a user with one of the ApproverRoles approves
+// them.
+type QuotePolicy struct {
+	OrgId   ID `json:"orgId" firestore:"orgId"`
+	GroupId ID `json:"groupId" firestore:"groupId"`
+	// MinMargin smallest gross margin percentage, after adjustments, a quote may go out at; 0 turns the check off
+	MinMargin int `json:"minMargin" firestore:"minMargin"`
+	// MaxDiscount largest discount each role may give, as a percentage of the subtotal. Roles not listed, and not covered by AnyRole, may give any discount
+	MaxDiscount map[Role]int `json:"maxDiscount" firestore:"maxDiscount"`
+	// ApproverRoles roles allowed to approve quotes that break the policy
+	ApproverRoles []Role    `json:"approverRoles" firestore:"approverRoles"`
+	Updated       time.Time `json:"updated" firestore:"updated"`
+}
+
+type PolicyRule = string
+
+const (
+	PolicyRuleMinMargin   PolicyRule = "minMargin"
+	PolicyRuleMaxDiscount PolicyRule = "maxDiscount"
+)
+
+// PolicyViolation is one way a quote breaks its group's policy.
+type PolicyViolation struct {
+	Rule PolicyRule `json:"rule" firestore:"rule"`
+	// Role whose discount limit was exceeded, for PolicyRuleMaxDiscount
+	Role Role `json:"role" firestore:"role"`
+	// Limit the percentage allowed
+	Limit int `json:"limit" firestore:"limit"`
+	// Actual the quote's margin or discount percentage
+	Actual float64 `json:"actual" firestore:"actual"`
+}
+
+type ApprovalStatus = string
+
+const (
+	ApprovalStatusPending  ApprovalStatus = "pending"
+	ApprovalStatusApproved ApprovalStatus = "approved"
+	ApprovalStatusRejected ApprovalStatus = "rejected"
+)
+
+// QuoteApproval is a request to let a quote that breaks its group's policy go
+// out, and the decision made on it. A decision only covers the violations and
+// pricing it was made on; once either changes the policy is checked again.
+type QuoteApproval struct {
+	Id         ID                `json:"id" firestore:"id"`
+	Status     ApprovalStatus    `json:"status" firestore:"status"`
+	Violations []PolicyViolation `json:"violations" firestore:"violations"`
+	// Total the quote's total when the request was made or last brought up to date
+	Total int `json:"total" firestore:"total"`
+	// Fingerprint identifies the violations and the quote's pricing the request covers, see approvalFingerprint
+	Fingerprint   string     `json:"fingerprint" firestore:"fingerprint"`
+	RequestedBy   ID         `json:"requestedBy" firestore:"requestedBy"`
+	RequestedRole Role       `json:"requestedRole" firestore:"requestedRole"`
+	RequestedOn   time.Time  `json:"requestedOn" firestore:"requestedOn"`
+	DecidedBy     ID         `json:"decidedBy" firestore:"decidedBy"`
+	DecidedRole   Role       `json:"decidedRole" firestore:"decidedRole"`
+	DecidedOn     *time.Time `json:"decidedOn" firestore:"decidedOn"`
+	Note          string     `json:"note" firestore:"note"`
+}
+
+type actorKey struct{}
+
+// Actor is the user making a change, along with their role.
+type Actor struct {
+	Id   ID   `json:"id" firestore:"id"`
+	Role Role `json:"role" firestore:"role"`
+}
+
+// WithActor returns a context carrying the user making changes, so that
+// discounts they give through adjustments are held to their role's limit.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// validQuotePolicy reports whether every limit in the policy is a percentage.
+func validQuotePolicy(policy QuotePolicy) bool {
+	if policy.MinMargin < 0 || policy.MinMargin > 100 {
+		return false
+	}
+	for _, limit := range policy.MaxDiscount {
+		if limit < 0 || limit > 100 {
+			return false
+		}
+	}
+	return true
+}
+
+// maxDiscount is the discount limit for role and whether it has one.
+func maxDiscount(policy *QuotePolicy, role Role) (int, bool) {
+	if limit, ok := policy.MaxDiscount[role]; ok {
+		return limit, true
+	}
+	limit, ok := policy.MaxDiscount[AnyRole]
+	return limit, ok
+}
+
+// discountPercent is how much the quote's negative adjustments take off its
+// subtotal, as a percentage of the subtotal.
+func discountPercent(totals *QuoteTotals) float64 {
+	if totals.SubTotal <= 0 {
+		return 0
+	}
+	discount := 0
+	for _, amount := range totals.AdjustmentAmounts {
+		if amount < 0 {
+			discount -= amount
+		}
+	}
+	return float64(discount) * 100 / float64(totals.SubTotal)
+}
+
+// evaluateQuotePolicy lists the ways the quote breaks the policy: a gross
+// margin after adjustments below MinMargin (see quoteProfitability), and a
+// discount above what role may give. A nil policy is never broken.
+func evaluateQuotePolicy(policy *QuotePolicy, quote *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment, role Role) []PolicyViolation {
+	violations := []PolicyViolation{}
+	if policy == nil {
+		return violations
+	}
+	if policy.MinMargin > 0 {
+		report := quoteProfitability(quote, lineItems, adjustments)
+		if report.Total.Price > 0 && report.Total.Margin < float64(policy.MinMargin) {
+			violations = append(violations, PolicyViolation{Rule: PolicyRuleMinMargin, Limit: policy.MinMargin, Actual: report.Total.Margin})
+		}
+	}
+	if limit, ok := maxDiscount(policy, role); ok {
+		discount := discountPercent(calculateQuoteTotals(quote, lineItems, adjustments))
+		if discount > float64(limit) {
+			violations = append(violations, PolicyViolation{Rule: PolicyRuleMaxDiscount, Role: role, Limit: limit, Actual: discount})
+		}
+	}
+	return violations
+}
+
+// latestApproval is the quote's most recent approval request, or nil.
+func latestApproval(q *Quote) *QuoteApproval {
+	if len(q.Approvals) == 0 {
+		return nil
+	}
+	return &q.Approvals[len(q.Approvals)-1]
+}
+
+// approvedAt reports whether the quote's latest approval request was approved
+// for exactly the violations and pricing fingerprint identifies.
+func approvedAt(q *Quote, fingerprint string) bool {
+	latest := latestApproval(q)
+	return latest != nil && latest.Status == ApprovalStatusApproved && latest.Fingerprint == fingerprint
+}
+
+// approvalFingerprint identifies the violations together with the pricing of
+// the quote that leads to them, so that an approval stops covering the quote
+// as soon as a line item, its cost or an adjustment changes, even when the
+// total stays the same.
+func approvalFingerprint(q *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment, violations []PolicyViolation) string {
+	type linePricing struct {
+		Quantity  int  `json:"quantity"`
+		UnitPrice int  `json:"unitPrice"`
+		UnitCost  int  `json:"unitCost"`
+		Optional  bool `json:"optional"`
+		Selected  bool `json:"selected"`
+	}
+	type adjustmentPricing struct {
+		Type   AdjustmentType `json:"type"`
+		Amount int            `json:"amount"`
+	}
+	lines := map[ID]linePricing{}
+	for id, li := range lineItems {
+		lines[id] = linePricing{li.Quantity, li.UnitPrice, li.UnitCost, li.Optional, li.Selected}
+	}
+	adjusted := map[ID]adjustmentPricing{}
+	for id, a := range adjustments {
+		adjusted[id] = adjustmentPricing{a.Type, a.Amount}
+	}
+	sum := sha256.Sum256(mustMarshal(struct {
+		Violations  []PolicyViolation        `json:"violations"`
+		SubTotal    int                      `json:"subTotal"`
+		Total       int                      `json:"total"`
+		LineItems   map[ID]linePricing       `json:"lineItems"`
+		Adjustments map[ID]adjustmentPricing `json:"adjustments"`
+	}{violations, q.SubTotal, q.Total, lines, adjusted}))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkQuoteApproval decides whether a quote may be sent or accepted. A quote
+// without violations always goes out, whatever requests are open on it. One
+// with violations goes out only when its latest request was approved for the
+// same violations and pricing.
+func checkQuoteApproval(q *Quote, violations []PolicyViolation, fingerprint string) error {
+	if len(violations) == 0 || approvedAt(q, fingerprint) {
+		return nil
+	}
+	return &ApprovalRequiredError{QuoteId: q.Id, Violations: violations}
+}
+
+// requestApproval records a pending approval request for the violations, or
+// brings the open request up to date when there already is one.
+func requestApproval(approvals []QuoteApproval, violations []PolicyViolation, fingerprint string, total int, actor Actor, now time.Time) []QuoteApproval {
+	approvals = append([]QuoteApproval{}, approvals...)
+	if n := len(approvals); n > 0 && approvals[n-1].Status == ApprovalStatusPending {
+		approvals[n-1].Violations = violations
+		approvals[n-1].Fingerprint = fingerprint
+		approvals[n-1].Total = total
+		return approvals
+	}
+	return append(approvals, QuoteApproval{
+		Status:        ApprovalStatusPending,
+		Violations:    violations,
+		Fingerprint:   fingerprint,
+		Total:         total,
+		RequestedBy:   actor.Id,
+		RequestedRole: actor.Role,
+		RequestedOn:   now,
+	})
+}
+
+// canApprove reports whether role may approve quotes under the policy.
+func canApprove(policy *QuotePolicy, role Role) bool {
+	return policy != nil && role != "" && slices.Contains(policy.ApproverRoles, role)
+}
+
+// decideApproval records approver's decision on the quote's pending approval
+// request.
+func decideApproval(approvals []QuoteApproval, status ApprovalStatus, approver Actor, note string, now time.Time) ([]QuoteApproval, error) {
+	n := len(approvals)
+	if n == 0 || approvals[n-1].Status != ApprovalStatusPending {
+		return nil, NoApprovalRequestError
+	}
+	approvals = append([]QuoteApproval{}, approvals...)
+	approvals[n-1].Status = status
+	approvals[n-1].DecidedBy = approver.Id
+	approvals[n-1].DecidedRole = approver.Role
+	approvals[n-1].DecidedOn = &now
+	approvals[n-1].Note = note
+	return approvals, nil
+}
+
+// loadQuotePolicy returns the group's policy, or nil when it has none.
+func loadQuotePolicy(ctx context.Context, store Store) (*QuotePolicy, error) {
+	policy, err := store.GetQuotePolicy(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return policy, err
+}
+
+// quotePolicyViolations loads what is needed to check the quote against its
+// group's policy, and returns the violations along with their
+// approvalFingerprint. Discounts are held to the limit of the quote's
+// DiscountRole, whoever is asking, so that the same quote always breaks the
+// policy in the same ways.
+func quotePolicyViolations(ctx context.Context, store Store, q *Quote) (*QuotePolicy, []PolicyViolation, string, error) {
+	policy, err := loadQuotePolicy(ctx, store)
+	if err != nil || policy == nil {
+		return nil, []PolicyViolation{}, "", err
+	}
+	lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, store, q)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	violations := evaluateQuotePolicy(policy, q, lineItems, adjustments, q.DiscountRole)
+	return policy, violations, approvalFingerprint(q, lineItems, adjustments, violations), nil
+}
+
+// enforceQuotePolicy holds back sending or accepting a quote that breaks its
+// group's policy until it is approved.
+func enforceQuotePolicy(ctx context.Context, store Store, q *Quote) error {
+	_, violations, fingerprint, err := quotePolicyViolations(ctx, store, q)
+	if err != nil {
+		return err
+	}
+	return checkQuoteApproval(q, violations, fingerprint)
+}
+
+// flagQuotePolicy is run after an adjustment changes the quote's pricing. The
+// role of the actor in ctx becomes the quote's DiscountRole, and when the
+// change takes the quote outside its group's policy, an approval request is
+// recorded on the quote so an approver can decide on it before the quote goes
+// out.
+func flagQuotePolicy(ctx context.Context, store Store, q *Quote) error {
+	actor := actorFromContext(ctx)
+	if actor.Role != "" && actor.Role != q.DiscountRole {
+		var err error
+		q, err = store.UpdateQuoteDiscountRole(ctx, q.Id, actor.Role)
+		if err != nil {
+			return err
+		}
+	}
+	_, violations, fingerprint, err := quotePolicyViolations(ctx, store, q)
+	if err != nil || len(violations) == 0 || approvedAt(q, fingerprint) {
+		return err
+	}
+	_, err = store.UpdateQuoteApprovals(ctx, q.Id, requestApproval(q.Approvals, violations, fingerprint, q.Total, actor, time.Now()))
+	return err
+}
@@ -0,0 +1,298 @@
+package pricey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidQuotePolicy(t *testing.T) {
+	assert.True(t, validQuotePolicy(QuotePolicy{}))
+	assert.True(t, validQuotePolicy(QuotePolicy{MinMargin: 30, MaxDiscount: map[Role]int{"tech": 10, AnyRole: 0}}))
+	assert.False(t, validQuotePolicy(QuotePolicy{MinMargin: -1}))
+	assert.False(t, validQuotePolicy(QuotePolicy{MinMargin: 101}))
+	assert.False(t, validQuotePolicy(QuotePolicy{MaxDiscount: map[Role]int{"tech": 150}}))
+}
+
+func TestEvaluateQuotePolicy(t *testing.T) {
+	// $100 of work costing $60, less a flat discount
+	pricedQuote := func(discount int) (*Quote, map[ID]*LineItem, map[ID]*Adjustment) {
+		quote := &Quote{Id: "q", LineItemIds: []ID{"1"}, AdjustmentIds: []ID{"discount"}}
+		lineItems := map[ID]*LineItem{
+			"1": {Id: "1", Quantity: 100, UnitPrice: 10000, UnitCost: 6000},
+		}
+		adjustments := map[ID]*Adjustment{
+			"discount": {Id: "discount", Type: AdjustmentTypeFlat, Amount: -discount},
+		}
+		return quote, lineItems, adjustments
+	}
+	policy := &QuotePolicy{MinMargin: 30, MaxDiscount: map[Role]int{"tech": 10, AnyRole: 5}}
+
+	testCases := []struct {
+		name       string
+		policy     *QuotePolicy
+		discount   int
+		role       Role
+		violations []PolicyViolation
+	}{
+		{name: "no policy", policy: nil, discount: 5000, role: "tech", violations: []PolicyViolation{}},
+		{name: "within policy", policy: policy, discount: 1000, role: "tech", violations: []PolicyViolation{}},
+		{
+			name: "falls back to any role", policy: policy, discount: 1000, role: "apprentice",
+			violations: []PolicyViolation{{Rule: PolicyRuleMaxDiscount, Role: "apprentice", Limit: 5, Actual: 10}},
+		},
+		{
+			name: "role without a limit", policy: &QuotePolicy{MinMargin: 30, MaxDiscount: map[Role]int{"tech": 10}}, discount: 2000, role: "manager",
+			violations: []PolicyViolation{{Rule: PolicyRuleMinMargin, Limit: 30, Actual: 25}},
+		},
+		{
+			name: "margin and discount", policy: policy, discount: 2000, role: "tech",
+			violations: []PolicyViolation{
+				{Rule: PolicyRuleMinMargin, Limit: 30, Actual: 25},
+				{Rule: PolicyRuleMaxDiscount, Role: "tech", Limit: 10, Actual: 20},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			quote, lineItems, adjustments := pricedQuote(tc.discount)
+			assert.Equal(t, tc.violations, evaluateQuotePolicy(tc.policy, quote, lineItems, adjustments, tc.role))
+		})
+	}
+}
+
+func TestCheckQuoteApproval(t *testing.T) {
+	violations := []PolicyViolation{{Rule: PolicyRuleMinMargin, Limit: 30, Actual: 25}}
+	approval := func(status ApprovalStatus, fingerprint string) []QuoteApproval {
+		return []QuoteApproval{{Status: status, Violations: violations, Fingerprint: fingerprint, Total: 8000}}
+	}
+
+	testCases := []struct {
+		name       string
+		approvals  []QuoteApproval
+		violations []PolicyViolation
+		required   bool
+	}{
+		{name: "within policy", violations: []PolicyViolation{}},
+		{name: "breaks policy", violations: violations, required: true},
+		{name: "pending", approvals: approval(ApprovalStatusPending, "a"), violations: violations, required: true},
+		{name: "pending without violations", approvals: approval(ApprovalStatusPending, "a"), violations: []PolicyViolation{}},
+		{name: "approved", approvals: approval(ApprovalStatusApproved, "a"), violations: violations},
+		{name: "rejected", approvals: approval(ApprovalStatusRejected, "a"), violations: violations, required: true},
+		{name: "approved at other pricing", approvals: approval(ApprovalStatusApproved, "b"), violations: violations, required: true},
+		{name: "rejected then brought within policy", approvals: approval(ApprovalStatusRejected, "b"), violations: []PolicyViolation{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &Quote{Id: "q", Total: 8000, Approvals: tc.approvals}
+			err := checkQuoteApproval(q, tc.violations, "a")
+			if !tc.required {
+				assert.NoError(t, err)
+				return
+			}
+			var approvalErr *ApprovalRequiredError
+			require.ErrorAs(t, err, &approvalErr)
+			assert.Equal(t, ID("q"), approvalErr.QuoteId)
+			assert.Equal(t, violations, approvalErr.Violations)
+		})
+	}
+}
+
+func TestApprovalFingerprint(t *testing.T) {
+	violations := []PolicyViolation{{Rule: PolicyRuleMinMargin, Limit: 30, Actual: 25}}
+	priced := func() (*Quote, map[ID]*LineItem, map[ID]*Adjustment) {
+		quote := &Quote{Id: "q", SubTotal: 10000, Total: 8000}
+		lineItems := map[ID]*LineItem{"1": {Id: "1", Quantity: 100, UnitPrice: 10000, UnitCost: 6000}}
+		adjustments := map[ID]*Adjustment{"discount": {Id: "discount", Type: AdjustmentTypeFlat, Amount: -2000}}
+		return quote, lineItems, adjustments
+	}
+	quote, lineItems, adjustments := priced()
+	approved := approvalFingerprint(quote, lineItems, adjustments, violations)
+
+	testCases := []struct {
+		name       string
+		change     func(q *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment)
+		violations []PolicyViolation
+		same       bool
+	}{
+		{name: "unchanged", change: func(q *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) {
+			lineItems["1"].Description = "Water heater"
+		}, violations: violations, same: true},
+		{name: "cost raised at the same total", change: func(q *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) {
+			lineItems["1"].UnitCost = 7000
+		}, violations: []PolicyViolation{{Rule: PolicyRuleMinMargin, Limit: 30, Actual: 12.5}}},
+		{name: "cost raised, violations not re-evaluated", change: func(q *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) {
+			lineItems["1"].UnitCost = 7000
+		}, violations: violations},
+		{name: "discount moved to the price", change: func(q *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) {
+			lineItems["1"].UnitPrice = 8000
+			adjustments["discount"].Amount = 0
+			q.SubTotal = 8000
+		}, violations: violations},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			quote, lineItems, adjustments := priced()
+			tc.change(quote, lineItems, adjustments)
+			assert.Equal(t, tc.same, approvalFingerprint(quote, lineItems, adjustments, tc.violations) == approved)
+		})
+	}
+}
+
+func TestRequestAndDecideApproval(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tech := Actor{Id: "u1", Role: "tech"}
+	manager := Actor{Id: "u2", Role: "manager"}
+	violations := []PolicyViolation{{Rule: PolicyRuleMaxDiscount, Role: "tech", Limit: 10, Actual: 20}}
+
+	_, err := decideApproval([]QuoteApproval{}, ApprovalStatusApproved, manager, "", now)
+	assert.ErrorIs(t, err, NoApprovalRequestError)
+
+	approvals := requestApproval([]QuoteApproval{}, violations, "a", 8000, tech, now)
+	require.Len(t, approvals, 1)
+	assert.Equal(t, ApprovalStatusPending, approvals[0].Status)
+	assert.Equal(t, ID("u1"), approvals[0].RequestedBy)
+	assert.Equal(t, "tech", approvals[0].RequestedRole)
+
+	// a second change while the request is open updates it
+	approvals = requestApproval(approvals, violations, "b", 7500, tech, now.Add(time.Minute))
+	require.Len(t, approvals, 1)
+	assert.Equal(t, 7500, approvals[0].Total)
+	assert.Equal(t, "b", approvals[0].Fingerprint)
+	assert.Equal(t, now, approvals[0].RequestedOn)
+
+	decided, err := decideApproval(approvals, ApprovalStatusApproved, manager, "repeat customer", now)
+	require.NoError(t, err)
+	assert.Equal(t, ApprovalStatusPending, approvals[0].Status, "the given approvals are left alone")
+	assert.Equal(t, ApprovalStatusApproved, decided[0].Status)
+	assert.Equal(t, ID("u2"), decided[0].DecidedBy)
+	assert.Equal(t, "manager", decided[0].DecidedRole)
+	assert.Equal(t, &now, decided[0].DecidedOn)
+	assert.Equal(t, "repeat customer", decided[0].Note)
+
+	_, err = decideApproval(decided, ApprovalStatusRejected, manager, "", now)
+	assert.ErrorIs(t, err, NoApprovalRequestError, "a request is only decided once")
+
+	// a new request after a decision keeps the history
+	approvals = requestApproval(decided, violations, "c", 7000, tech, now)
+	require.Len(t, approvals, 2)
+	assert.Equal(t, ApprovalStatusPending, approvals[1].Status)
+}
+
+func TestApprovalCoversPricing(t *testing.T) {
+	ctx := context.Background()
+	tech := Actor{Id: "u1", Role: "tech"}
+	manager := Actor{Id: "u2", Role: "manager"}
+	m := newMemStore()
+	m.policy = &QuotePolicy{MinMargin: 50, ApproverRoles: []Role{"manager"}}
+	m.quotes["q"] = &Quote{Id: "q", Status: QuoteStatusDraft, LineItemIds: []ID{"1"}, SubTotal: 10000, Total: 10000, AdjustmentIds: []ID{}}
+	m.lineItems["1"] = &LineItem{Id: "1", QuoteId: "q", Quantity: 100, UnitPrice: 10000, UnitCost: 6000}
+	v := &priceyQuote{store: m}
+
+	q, err := v.RequestApproval(ctx, "q", tech)
+	require.NoError(t, err)
+	require.Len(t, q.Approvals, 1)
+	q, err = v.Approve(ctx, "q", manager, "")
+	require.NoError(t, err)
+	assert.NoError(t, enforceQuotePolicy(ctx, m, q))
+
+	q, err = v.RequestApproval(ctx, "q", tech)
+	require.NoError(t, err)
+	assert.Len(t, q.Approvals, 1, "an approved quote needs no new request")
+
+	// the cost goes up at the same total, lowering the margin further
+	_, err = (&priceyLineItem{store: m}).SetUnitCost(ctx, "1", 7000)
+	require.NoError(t, err)
+	var approvalErr *ApprovalRequiredError
+	assert.ErrorAs(t, enforceQuotePolicy(ctx, m, q), &approvalErr)
+
+	// once back within policy an open request does not hold the quote back
+	q, err = v.RequestApproval(ctx, "q", tech)
+	require.NoError(t, err)
+	require.Len(t, q.Approvals, 2)
+	m.policy.MinMargin = 20
+	assert.NoError(t, enforceQuotePolicy(ctx, m, q))
+
+	q, err = v.RequestApproval(ctx, "q", tech)
+	require.NoError(t, err)
+	assert.Len(t, q.Approvals, 2, "nothing to approve within policy")
+}
+
+func TestApprovalHeldToDiscountRole(t *testing.T) {
+	tech := Actor{Id: "u1", Role: "tech"}
+	manager := Actor{Id: "u2", Role: "manager"}
+
+	testCases := []struct {
+		name   string
+		policy *QuotePolicy
+	}{
+		{name: "any role limit", policy: &QuotePolicy{MaxDiscount: map[Role]int{"tech": 10, AnyRole: 5}, ApproverRoles: []Role{"manager"}}},
+		{name: "no any role limit", policy: &QuotePolicy{MaxDiscount: map[Role]int{"tech": 10}, ApproverRoles: []Role{"manager"}}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := newMemStore()
+			m.policy = tc.policy
+			m.quotes["q"] = &Quote{Id: "q", Code: "Q-1", Status: QuoteStatusDraft, LineItemIds: []ID{"1"}, SubTotal: 10000, Total: 10000, AdjustmentIds: []ID{}}
+			m.lineItems["1"] = &LineItem{Id: "1", QuoteId: "q", Quantity: 100, UnitPrice: 10000, UnitCost: 6000}
+			v := &priceyQuote{store: m}
+
+			// a tech gives 20% off, which is flagged for approval
+			_, err := (&priceyAdjustment{store: m}).New(WithActor(context.Background(), tech), "q", "Discount", -2000, AdjustmentTypeFlat)
+			require.NoError(t, err)
+			assert.Equal(t, "tech", m.quotes["q"].DiscountRole)
+			require.Len(t, m.quotes["q"].Approvals, 1)
+
+			// sending without an actor is still held to the tech's limit
+			_, err = v.Transition(context.Background(), "q", QuoteStatusSent, "")
+			var approvalErr *ApprovalRequiredError
+			require.ErrorAs(t, err, &approvalErr)
+			assert.Equal(t, "tech", approvalErr.Violations[0].Role)
+
+			q, err := v.RequestApproval(context.Background(), "q", tech)
+			require.NoError(t, err)
+			assert.Len(t, q.Approvals, 1, "the flagged request is brought up to date")
+			_, err = v.Approve(context.Background(), "q", manager, "")
+			require.NoError(t, err)
+
+			_, err = v.Transition(WithActor(context.Background(), manager), "q", QuoteStatusSent, "u2")
+			require.NoError(t, err)
+			q, err = v.Accept(context.Background(), "q", Signature{Name: "Cust"})
+			require.NoError(t, err)
+			assert.Equal(t, QuoteStatusAccepted, q.Status)
+		})
+	}
+}
+
+func TestCanApprove(t *testing.T) {
+	policy := &QuotePolicy{ApproverRoles: []Role{"manager"}}
+	assert.True(t, canApprove(policy, "manager"))
+	assert.False(t, canApprove(policy, "tech"))
+	assert.False(t, canApprove(policy, ""))
+	assert.False(t, canApprove(nil, "manager"))
+}
+
+func TestActorFromContext(t *testing.T) {
+	assert.Equal(t, Actor{}, actorFromContext(context.Background()))
+	ctx := WithActor(context.Background(), Actor{Id: "u1", Role: "tech"})
+	assert.Equal(t, Actor{Id: "u1", Role: "tech"}, actorFromContext(ctx))
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"errors"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -84,6 +86,15 @@ func mustUnmarshal[T any](b []byte, dst *T) error {
 	return json.Unmarshal(b, dst)
 }
 
+// containsPattern is an ILIKE pattern matching search anywhere in a value. The
+// wildcards % and _ and the escape character \ are escaped so they match
+// themselves.
+func containsPattern(search string) string {
+	return "%" + likeEscaper.Replace(search) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 // pgxRowToPricebook scans a pgx row into a Pricebook.
 func pgxRowToPricebook(row pgx.CollectableRow) (*Pricebook, error) {
 	var pb Pricebook
@@ -170,7 +181,7 @@ func pgxRowToCustomValueConfig(row pgx.CollectableRow) (*CustomValueConfig, erro
 	var descJSON []byte
 	err := row.Scan(
 		&c.Id, &c.OrgId, &c.GroupId, &c.Name, &c.Description,
-		&descJSON, &c.Created, &c.Updated,
+		&descJSON, &c.PriceFormulaKey, &c.Created, &c.Updated,
 	)
 	if err != nil {
 		return nil, err
@@ -184,7 +195,7 @@ func pgxRowToCustomValueConfig(row pgx.CollectableRow) (*CustomValueConfig, erro
 
 func pgxRowToQuote(row pgx.CollectableRow) (*Quote, error) {
 	var q Quote
-	var lineItemIdsJSON, adjustmentIdsJSON []byte
+	var lineItemIdsJSON, adjustmentIdsJSON, statusHistoryJSON, unlockHistoryJSON, optionsJSON, acceptanceJSON, paymentScheduleJSON, approvalsJSON []byte
 	err := row.Scan(
 		&q.Id, &q.Code, &q.OrderNumber, &q.LogoId,
 		&q.PrimaryBackgroundColor, &q.PrimaryTextColor,
@@ -193,20 +204,44 @@ func pgxRowToQuote(row pgx.CollectableRow) (*Quote, error) {
 		&q.SenderId, &q.BillToId, &q.ShipToId,
 		&lineItemIdsJSON, &q.SubTotal, &adjustmentIdsJSON, &q.Total,
 		&q.BalanceDue, &q.BalancePercentDue, &q.BalanceDueOn,
-		&q.PayUrl, &q.Sent, &q.SentOn, &q.Sold, &q.SoldOn,
+		&q.PayUrl, &q.SentOn, &q.SoldOn,
 		&q.Created, &q.Updated, &q.Hidden, &q.Locked,
+		&q.Status, &statusHistoryJSON, &unlockHistoryJSON, &q.CreatedBy, &q.CustomerId,
+		&optionsJSON, &q.ChosenOptionId, &acceptanceJSON, &paymentScheduleJSON,
+		&q.ChangeOrderOf, &q.ChangeOrderNumber, &approvalsJSON, &q.ExpiryLocked, &q.DiscountRole,
 	)
 	if err != nil {
 		return nil, err
 	}
 	_ = mustUnmarshal(lineItemIdsJSON, &q.LineItemIds)
 	_ = mustUnmarshal(adjustmentIdsJSON, &q.AdjustmentIds)
+	_ = mustUnmarshal(statusHistoryJSON, &q.StatusHistory)
+	if q.StatusHistory == nil {
+		q.StatusHistory = []QuoteStatusChange{}
+	}
+	_ = mustUnmarshal(unlockHistoryJSON, &q.UnlockHistory)
+	if q.UnlockHistory == nil {
+		q.UnlockHistory = []QuoteUnlock{}
+	}
 	if q.LineItemIds == nil {
 		q.LineItemIds = []ID{}
 	}
 	if q.AdjustmentIds == nil {
 		q.AdjustmentIds = []ID{}
 	}
+	_ = mustUnmarshal(optionsJSON, &q.Options)
+	if q.Options == nil {
+		q.Options = []QuoteOption{}
+	}
+	_ = mustUnmarshal(acceptanceJSON, &q.Acceptance)
+	_ = mustUnmarshal(paymentScheduleJSON, &q.PaymentSchedule)
+	if q.PaymentSchedule == nil {
+		q.PaymentSchedule = []PaymentMilestone{}
+	}
+	_ = mustUnmarshal(approvalsJSON, &q.Approvals)
+	if q.Approvals == nil {
+		q.Approvals = []QuoteApproval{}
+	}
 	return &q, nil
 }
 
@@ -218,7 +253,9 @@ func pgxRowToLineItem(row pgx.CollectableRow) (*LineItem, error) {
 		&li.Description, &li.Quantity, &li.QuantitySuffix, &li.QuantityPrefix,
 		&li.UnitPrice, &li.UnitPriceSuffix, &li.UnitPricePrefix,
 		&li.Amount, &li.AmountSuffix, &li.AmountPrefix,
-		&li.Open, &li.Created, &li.Updated,
+		&li.Open, &li.Name, &li.SourceItemId, &li.SourcePriceId, &li.HideFromCustomer,
+		&li.Optional, &li.AlternateGroup, &li.Selected, &li.OptionId, &li.RemovesLineItemId, &li.UnitCost,
+		&li.Created, &li.Updated,
 	)
 	if err != nil {
 		return nil, err
@@ -233,7 +270,7 @@ func pgxRowToLineItem(row pgx.CollectableRow) (*LineItem, error) {
 func pgxRowToAdjustment(row pgx.CollectableRow) (*Adjustment, error) {
 	var a Adjustment
 	err := row.Scan(
-		&a.Id, &a.QuoteId, &a.Description, &a.Type, &a.Amount,
+		&a.Id, &a.QuoteId, &a.Description, &a.Type, &a.Amount, &a.OptionId,
 		&a.Created, &a.Updated,
 	)
 	if err != nil {
@@ -246,9 +283,10 @@ func pgxRowToContact(row pgx.CollectableRow) (*Contact, error) {
 	var c Contact
 	var phonesJSON, emailsJSON, websitesJSON []byte
 	err := row.Scan(
-		&c.Id, &c.Name, &c.CompanyName,
+		&c.Id, &c.OrgId, &c.GroupId, &c.Name, &c.CompanyName,
 		&phonesJSON, &emailsJSON, &websitesJSON,
 		&c.Street, &c.City, &c.State, &c.Zip,
+		&c.Created, &c.Updated, &c.Hidden,
 	)
 	if err != nil {
 		return nil, err
@@ -256,6 +294,15 @@ func pgxRowToContact(row pgx.CollectableRow) (*Contact, error) {
 	_ = mustUnmarshal(phonesJSON, &c.Phones)
 	_ = mustUnmarshal(emailsJSON, &c.Emails)
 	_ = mustUnmarshal(websitesJSON, &c.Websites)
+	if c.Phones == nil {
+		c.Phones = []string{}
+	}
+	if c.Emails == nil {
+		c.Emails = []string{}
+	}
+	if c.Websites == nil {
+		c.Websites = []string{}
+	}
 	return &c, nil
 }
 
@@ -813,7 +860,7 @@ func (p *Postgres) SearchItemsInPricebook(ctx context.Context, pricebookId ID, s
 	}
 	rows, err := p.db.Query(ctx,
 		`SELECT `+itemCols+` FROM items WHERE org_id=$1 AND group_id=$2 AND pricebook_id=$3 AND (name ILIKE $4 OR description ILIKE $4 OR code ILIKE $4 OR sku ILIKE $4)`,
-		orgId, groupId, pricebookId, "%"+search+"%")
+		orgId, groupId, pricebookId, containsPattern(search))
 	if err != nil {
 		return nil, err
 	}
@@ -1251,7 +1298,7 @@ func (p *Postgres) SearchTags(ctx context.Context, pricebookId ID, search string
 	}
 	rows, err := p.db.Query(ctx,
 		`SELECT `+tagCols+` FROM tags WHERE org_id=$1 AND group_id=$2 AND pricebook_id=$3 AND (name ILIKE $4 OR description ILIKE $4)`,
-		orgId, groupId, pricebookId, "%"+search+"%")
+		orgId, groupId, pricebookId, containsPattern(search))
 	if err != nil {
 		return nil, err
 	}
@@ -1290,7 +1337,7 @@ func (p *Postgres) RecoverPricebookTags(ctx context.Context, pricebookId ID) err
 // CUSTOM VALUE CONFIG
 // ─────────────────────────────────────────────
 
-const cvcCols = `id, org_id, group_id, name, description, descriptors, created, updated`
+const cvcCols = `id, org_id, group_id, name, description, descriptors, price_formula_key, created, updated`
 
 func (p *Postgres) CreateCustomValueConfig(ctx context.Context, name string, description string) (*CustomValueConfig, error) {
 	orgId, groupId, err := p.ext(ctx)
@@ -1425,6 +1472,22 @@ func (p *Postgres) DeleteCustomValueConfigDescriptor(ctx context.Context, id ID,
 	return c, nil
 }
 
+func (p *Postgres) UpdateCustomValueConfigPriceFormula(ctx context.Context, id ID, key *ID) (*CustomValueConfig, error) {
+	c, err := p.GetCustomValueConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE custom_value_configs SET price_formula_key=$1, updated=$2 WHERE id=$3`,
+		key, now, id)
+	if err != nil {
+		return nil, err
+	}
+	c.PriceFormulaKey = key
+	c.Updated = now
+	return c, nil
+}
+
 func (p *Postgres) DeleteCustomValueConfig(ctx context.Context, id ID) error {
 	if _, err := p.GetCustomValueConfig(ctx, id); err != nil {
 		return err
@@ -1514,9 +1577,9 @@ func (p *Postgres) getImage(ctx context.Context, id ID) (*Image, error) {
 // QUOTE
 // ─────────────────────────────────────────────
 
-const quoteCols = `id, code, order_number, logo_id, primary_background_color, primary_text_color, issue_date, expiration_date, payment_terms, notes, sender_id, bill_to_id, ship_to_id, line_item_ids, sub_total, adjustment_ids, total, balance_due, balance_percent_due, balance_due_on, pay_url, sent, sent_on, sold, sold_on, created, updated, hidden, locked`
+const quoteCols = `id, code, order_number, logo_id, primary_background_color, primary_text_color, issue_date, expiration_date, payment_terms, notes, sender_id, bill_to_id, ship_to_id, line_item_ids, sub_total, adjustment_ids, total, balance_due, balance_percent_due, balance_due_on, pay_url, sent_on, sold_on, created, updated, hidden, locked, status, status_history, unlock_history, created_by, customer_id, options, chosen_option_id, acceptance, payment_schedule, change_order_of, change_order_number, approvals, expiry_locked, discount_role`
 
-func (p *Postgres) CreateQuote(ctx context.Context) (*Quote, error) {
+func (p *Postgres) CreateQuote(ctx context.Context, createdBy ID) (*Quote, error) {
 	orgId, groupId, err := p.ext(ctx)
 	if err != nil {
 		return nil, err
@@ -1524,20 +1587,25 @@ func (p *Postgres) CreateQuote(ctx context.Context) (*Quote, error) {
 	now := time.Now()
 	id := newID()
 	_, err = p.db.Exec(ctx, `
-		INSERT INTO quotes (id, org_id, group_id, code, order_number, logo_id, primary_background_color, primary_text_color, issue_date, expiration_date, payment_terms, notes, sender_id, bill_to_id, ship_to_id, line_item_ids, sub_total, adjustment_ids, total, balance_due, balance_percent_due, balance_due_on, pay_url, sent, sent_on, sold, sold_on, created, updated, hidden, locked)
-		VALUES ($1,$2,$3,'','','','','',NULL,NULL,'','','','','',$4,0,$4,0,0,0,NULL,'',FALSE,NULL,FALSE,NULL,$5,$6,FALSE,FALSE)`,
-		id, orgId, groupId, mustMarshal([]ID{}), now, now,
+		INSERT INTO quotes (id, org_id, group_id, code, order_number, logo_id, primary_background_color, primary_text_color, issue_date, expiration_date, payment_terms, notes, sender_id, bill_to_id, ship_to_id, line_item_ids, sub_total, adjustment_ids, total, balance_due, balance_percent_due, balance_due_on, pay_url, sent_on, sold_on, created, updated, hidden, locked, created_by)
+		VALUES ($1,$2,$3,'','','','','',NULL,NULL,'','','','','',$4,0,$4,0,0,0,NULL,'',NULL,NULL,$5,$6,FALSE,FALSE,$7)`,
+		id, orgId, groupId, mustMarshal([]ID{}), now, now, createdBy,
 	)
 	if err != nil {
 		return nil, err
 	}
 	return &Quote{
-		Id: id, LineItemIds: []ID{}, AdjustmentIds: []ID{},
-		Created: now, Updated: now,
+		Id: id, LineItemIds: []ID{}, AdjustmentIds: []ID{}, Options: []QuoteOption{}, PaymentSchedule: []PaymentMilestone{}, Approvals: []QuoteApproval{},
+		Status: QuoteStatusDraft, StatusHistory: []QuoteStatusChange{}, UnlockHistory: []QuoteUnlock{},
+		Created: now, Updated: now, CreatedBy: createdBy,
 	}, nil
 }
 
-func (p *Postgres) CreateDuplicateQuote(ctx context.Context, quoteId ID) (*Quote, error) {
+// CreateDuplicateQuote copies the quote's details, options, payment schedule
+// and discount role into a new draft quote. Line items and adjustments are not
+// copied, and the code, order number, dates, pay url and chosen option are
+// cleared since they belong to the original.
+func (p *Postgres) CreateDuplicateQuote(ctx context.Context, quoteId ID, createdBy ID) (*Quote, error) {
 	original, err := p.getQuote(ctx, quoteId)
 	if err != nil {
 		return nil, err
@@ -1549,49 +1617,22 @@ func (p *Postgres) CreateDuplicateQuote(ctx context.Context, quoteId ID) (*Quote
 	now := time.Now()
 	id := newID()
 	_, err = p.db.Exec(ctx, `
-		INSERT INTO quotes (id, org_id, group_id, code, order_number, logo_id, primary_background_color, primary_text_color, issue_date, expiration_date, payment_terms, notes, sender_id, bill_to_id, ship_to_id, line_item_ids, sub_total, adjustment_ids, total, balance_due, balance_percent_due, balance_due_on, pay_url, sent, sent_on, sold, sold_on, created, updated, hidden, locked)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,FALSE,NULL,FALSE,NULL,$24,$25,FALSE,FALSE)`,
+		INSERT INTO quotes (id, org_id, group_id, code, order_number, logo_id, primary_background_color, primary_text_color, issue_date, expiration_date, payment_terms, notes, sender_id, bill_to_id, ship_to_id, line_item_ids, sub_total, adjustment_ids, total, balance_due, balance_percent_due, balance_due_on, pay_url, sent_on, sold_on, created, updated, hidden, locked, created_by, customer_id, options, payment_schedule, discount_role)
+		VALUES ($1,$2,$3,'','',$4,$5,$6,NULL,NULL,$7,$8,$9,$10,$11,$12,0,$12,0,$13,$14,NULL,'',NULL,NULL,$15,$16,FALSE,FALSE,$17,$18,$19,$20,$21)`,
 		id, orgId, groupId,
-		original.Code, original.OrderNumber, original.LogoId,
+		original.LogoId,
 		original.PrimaryBackgroundColor, original.PrimaryTextColor,
-		original.IssueDate, original.ExpirationDate,
 		original.PaymentTerms, original.Notes,
 		original.SenderId, original.BillToId, original.ShipToId,
-		mustMarshal([]ID{}), // reset line items
-		original.SubTotal,
-		mustMarshal([]ID{}), // reset adjustments
-		original.Total, original.BalanceDue, original.BalancePercentDue,
-		original.BalanceDueOn, original.PayUrl,
-		now, now,
+		mustMarshal([]ID{}), // reset line items and adjustments
+		original.BalanceDue, original.BalancePercentDue,
+		now, now, createdBy, original.CustomerId, mustMarshal(original.Options), mustMarshal(original.PaymentSchedule),
+		original.DiscountRole, // the copied adjustments keep their discount's role
 	)
 	if err != nil {
 		return nil, err
 	}
-	return &Quote{
-		Id:                     id,
-		Code:                   original.Code,
-		OrderNumber:            original.OrderNumber,
-		LogoId:                 original.LogoId,
-		PrimaryBackgroundColor: original.PrimaryBackgroundColor,
-		PrimaryTextColor:       original.PrimaryTextColor,
-		IssueDate:              original.IssueDate,
-		ExpirationDate:         original.ExpirationDate,
-		PaymentTerms:           original.PaymentTerms,
-		Notes:                  original.Notes,
-		SenderId:               original.SenderId,
-		BillToId:               original.BillToId,
-		ShipToId:               original.ShipToId,
-		LineItemIds:            []ID{},
-		SubTotal:               original.SubTotal,
-		AdjustmentIds:          []ID{},
-		Total:                  original.Total,
-		BalanceDue:             original.BalanceDue,
-		BalancePercentDue:      original.BalancePercentDue,
-		BalanceDueOn:           original.BalanceDueOn,
-		PayUrl:                 original.PayUrl,
-		Created:                now,
-		Updated:                now,
-	}, nil
+	return p.getQuote(ctx, id)
 }
 
 func (p *Postgres) GetQuote(ctx context.Context, id ID) (*Quote, error) {
@@ -1620,8 +1661,131 @@ func (p *Postgres) getQuote(ctx context.Context, id ID) (*Quote, error) {
 	return q, nil
 }
 
+// quoteSortColumns maps each QuoteSort to the column it orders by.
+var quoteSortColumns = map[QuoteSort]string{
+	QuoteSortCreated: "created",
+	QuoteSortUpdated: "updated",
+	QuoteSortCode:    "code",
+	QuoteSortTotal:   "total",
+	QuoteSortSentOn:  "sent_on",
+	QuoteSortSoldOn:  "sold_on",
+}
+
+func (p *Postgres) ListQuotes(ctx context.Context, filter QuoteFilter) (*QuotePage, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort := filter.Sort
+	if sort == "" {
+		sort = QuoteSortCreated
+	}
+	sortCol, ok := quoteSortColumns[sort]
+	if !ok {
+		return nil, InvalidQuoteSortError
+	}
+
+	// conditions are built from hard-coded strings, values are always passed as
+	// arguments
+	args := []any{orgId, groupId}
+	where := `org_id=$1 AND group_id=$2`
+	cond := func(sql string, val any) {
+		args = append(args, val)
+		where += ` AND ` + strings.ReplaceAll(sql, "?", "$"+strconv.Itoa(len(args)))
+	}
+	if !filter.IncludeDeleted {
+		where += ` AND hidden=FALSE`
+	}
+	if len(filter.Statuses) > 0 {
+		cond(`status = ANY(?)`, filter.Statuses)
+	}
+	if filter.SentFrom != nil {
+		cond(`sent_on >= ?`, *filter.SentFrom)
+	}
+	if filter.SentTo != nil {
+		cond(`sent_on < ?`, *filter.SentTo)
+	}
+	if filter.SoldFrom != nil {
+		cond(`sold_on >= ?`, *filter.SoldFrom)
+	}
+	if filter.SoldTo != nil {
+		cond(`sold_on < ?`, *filter.SoldTo)
+	}
+	if filter.BillToId != nil {
+		cond(`bill_to_id = ?`, *filter.BillToId)
+	}
+	if filter.Search != "" {
+		cond(`(code ILIKE ? OR order_number ILIKE ?)`, containsPattern(filter.Search))
+	}
+	if filter.MinTotal != nil {
+		cond(`total >= ?`, *filter.MinTotal)
+	}
+	if filter.MaxTotal != nil {
+		cond(`total <= ?`, *filter.MaxTotal)
+	}
+	if filter.CreatedBy != nil {
+		cond(`created_by = ?`, *filter.CreatedBy)
+	}
+	if filter.CustomerId != nil {
+		cond(`customer_id = ?`, *filter.CustomerId)
+	}
+	if filter.ChangeOrderOf != nil {
+		cond(`change_order_of = ?`, *filter.ChangeOrderOf)
+	}
+
+	page := &QuotePage{}
+	err = p.db.QueryRow(ctx, `SELECT COUNT(*) FROM quotes WHERE `+where, args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	direction := ` ASC NULLS LAST`
+	if filter.Descending {
+		direction = ` DESC NULLS LAST`
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultQuoteLimit
+	}
+	limit = min(limit, MaxQuoteLimit)
+	offset := max(filter.Offset, 0)
+	args = append(args, limit, offset)
+	rows, err := p.db.Query(ctx,
+		`SELECT `+quoteCols+` FROM quotes WHERE `+where+
+			` ORDER BY `+sortCol+direction+`, id`+direction+
+			` LIMIT $`+strconv.Itoa(len(args)-1)+` OFFSET $`+strconv.Itoa(len(args)),
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	page.Quotes, err = pgx.CollectRows(rows, pgxRowToQuote)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// getUnlockedQuote loads a quote that is about to be changed, returning a
+// *QuoteLockedError when it is locked.
+func (p *Postgres) getUnlockedQuote(ctx context.Context, id ID) (*Quote, error) {
+	q, err := p.getQuote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if q.Locked {
+		return nil, &QuoteLockedError{QuoteId: id}
+	}
+	return q, nil
+}
+
+// checkQuoteUnlocked returns a *QuoteLockedError when the quote is locked.
+func (p *Postgres) checkQuoteUnlocked(ctx context.Context, quoteId ID) error {
+	_, err := p.getUnlockedQuote(ctx, quoteId)
+	return err
+}
+
 func (p *Postgres) updateQuoteField(ctx context.Context, id ID, col string, val any) (*Quote, error) {
-	if _, err := p.getQuote(ctx, id); err != nil {
+	if _, err := p.getUnlockedQuote(ctx, id); err != nil {
 		return nil, err
 	}
 	now := time.Now()
@@ -1648,6 +1812,17 @@ func (p *Postgres) UpdateQuoteIssueDate(ctx context.Context, id ID, issueDate *t
 func (p *Postgres) UpdateQuoteExpirationDate(ctx context.Context, id ID, expirationDate *time.Time) (*Quote, error) {
 	return p.updateQuoteField(ctx, id, "expiration_date", expirationDate)
 }
+func (p *Postgres) UpdateQuoteColors(ctx context.Context, id ID, backgroundColor, textColor string) (*Quote, error) {
+	if _, err := p.getUnlockedQuote(ctx, id); err != nil {
+		return nil, err
+	}
+	_, err := p.db.Exec(ctx, `UPDATE quotes SET primary_background_color=$1, primary_text_color=$2, updated=$3 WHERE id=$4`,
+		backgroundColor, textColor, time.Now(), id)
+	if err != nil {
+		return nil, err
+	}
+	return p.getQuote(ctx, id)
+}
 func (p *Postgres) UpdateQuotePaymentTerms(ctx context.Context, id ID, paymentTerms string) (*Quote, error) {
 	return p.updateQuoteField(ctx, id, "payment_terms", paymentTerms)
 }
@@ -1663,6 +1838,86 @@ func (p *Postgres) UpdateQuoteBillToId(ctx context.Context, id ID, contactId ID)
 func (p *Postgres) UpdateQuoteShipToId(ctx context.Context, id ID, contactId ID) (*Quote, error) {
 	return p.updateQuoteField(ctx, id, "ship_to_id", contactId)
 }
+func (p *Postgres) UpdateQuoteCustomerId(ctx context.Context, id ID, customerId ID) (*Quote, error) {
+	return p.updateQuoteField(ctx, id, "customer_id", customerId)
+}
+func (p *Postgres) UpdateQuoteOptions(ctx context.Context, id ID, options []QuoteOption) (*Quote, error) {
+	if options == nil {
+		options = []QuoteOption{}
+	}
+	return p.updateQuoteField(ctx, id, "options", mustMarshal(options))
+}
+func (p *Postgres) UpdateQuoteChosenOptionId(ctx context.Context, id ID, optionId ID) (*Quote, error) {
+	return p.updateQuoteField(ctx, id, "chosen_option_id", optionId)
+}
+
+// UpdateQuoteChangeOrder links the quote to the original it changes.
+func (p *Postgres) UpdateQuoteChangeOrder(ctx context.Context, id ID, originalId ID, number int) (*Quote, error) {
+	if _, err := p.getUnlockedQuote(ctx, id); err != nil {
+		return nil, err
+	}
+	_, err := p.db.Exec(ctx, `UPDATE quotes SET change_order_of=$1, change_order_number=$2, updated=$3 WHERE id=$4`,
+		originalId, number, time.Now(), id)
+	if err != nil {
+		return nil, err
+	}
+	return p.getQuote(ctx, id)
+}
+
+// AllocateChangeOrderNumber counts up the original quote's change orders. The
+// update holds the original's row lock, so concurrent change orders never
+// share a number, and it carries on from the highest number already used by
+// change orders made before the counter existed. The original is usually
+// locked, but the counter is bookkeeping rather than content, so the lock is
+// not checked.
+func (p *Postgres) AllocateChangeOrderNumber(ctx context.Context, originalId ID) (int, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var number int
+	err = p.db.QueryRow(ctx, `
+		UPDATE quotes q SET change_order_counter = GREATEST(q.change_order_counter,
+			(SELECT COALESCE(MAX(co.change_order_number), 0) FROM quotes co WHERE co.change_order_of = q.id)) + 1
+		WHERE q.id=$1 AND q.org_id=$2 AND q.group_id=$3
+		RETURNING q.change_order_counter`,
+		originalId, orgId, groupId).Scan(&number)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return number, err
+}
+
+func (p *Postgres) UpdateQuoteAcceptance(ctx context.Context, id ID, acceptance *QuoteAcceptance) (*Quote, error) {
+	return p.updateQuoteField(ctx, id, "acceptance", mustMarshal(acceptance))
+}
+
+// UpdateQuotePaymentSchedule replaces the quote's payment schedule, giving new
+// milestones an id.
+func (p *Postgres) UpdateQuotePaymentSchedule(ctx context.Context, id ID, schedule []PaymentMilestone) (*Quote, error) {
+	schedule = append([]PaymentMilestone{}, schedule...)
+	for i := range schedule {
+		if schedule[i].Id == "" {
+			schedule[i].Id = newID()
+		}
+	}
+	return p.updateQuoteField(ctx, id, "payment_schedule", mustMarshal(schedule))
+}
+
+// UpdateQuoteApprovals replaces the quote's approval requests, giving new ones
+// an id.
+func (p *Postgres) UpdateQuoteApprovals(ctx context.Context, id ID, approvals []QuoteApproval) (*Quote, error) {
+	approvals = append([]QuoteApproval{}, approvals...)
+	for i := range approvals {
+		if approvals[i].Id == "" {
+			approvals[i].Id = newID()
+		}
+	}
+	return p.updateQuoteField(ctx, id, "approvals", mustMarshal(approvals))
+}
+func (p *Postgres) UpdateQuoteDiscountRole(ctx context.Context, id ID, role Role) (*Quote, error) {
+	return p.updateQuoteField(ctx, id, "discount_role", role)
+}
 func (p *Postgres) UpdateQuoteSubTotal(ctx context.Context, id ID, subTotal int) (*Quote, error) {
 	return p.updateQuoteField(ctx, id, "sub_total", subTotal)
 }
@@ -1681,25 +1936,90 @@ func (p *Postgres) UpdateQuoteBalanceDueOn(ctx context.Context, id ID, balanceDu
 func (p *Postgres) UpdateQuotePayUrl(ctx context.Context, id ID, payUrl string) (*Quote, error) {
 	return p.updateQuoteField(ctx, id, "pay_url", payUrl)
 }
-func (p *Postgres) UpdateQuoteSent(ctx context.Context, id ID, sent bool) (*Quote, error) {
-	return p.updateQuoteField(ctx, id, "sent", sent)
-}
 func (p *Postgres) UpdateQuoteSentOn(ctx context.Context, id ID, sentOn *time.Time) (*Quote, error) {
 	return p.updateQuoteField(ctx, id, "sent_on", sentOn)
 }
-func (p *Postgres) UpdateQuoteSold(ctx context.Context, id ID, sold bool) (*Quote, error) {
-	return p.updateQuoteField(ctx, id, "sold", sold)
-}
 func (p *Postgres) UpdateQuoteSoldOn(ctx context.Context, id ID, soldOn *time.Time) (*Quote, error) {
 	return p.updateQuoteField(ctx, id, "sold_on", soldOn)
 }
 
+func (p *Postgres) UpdateQuoteStatus(ctx context.Context, id ID, status QuoteStatus, change QuoteStatusChange) (*Quote, error) {
+	if _, err := p.getQuote(ctx, id); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err := p.db.Exec(ctx, `UPDATE quotes SET status=$1, status_history=status_history || $2::jsonb, updated=$3 WHERE id=$4`,
+		status, mustMarshal([]QuoteStatusChange{change}), now, id)
+	if err != nil {
+		return nil, err
+	}
+	return p.getQuote(ctx, id)
+}
+
+// ListOverdueQuotes returns the group's sent and viewed quotes whose expiration
+// date has passed, oldest expiration first.
+func (p *Postgres) ListOverdueQuotes(ctx context.Context, now time.Time, limit int) ([]*Quote, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx,
+		`SELECT `+quoteCols+` FROM quotes
+		WHERE org_id=$1 AND group_id=$2 AND hidden=FALSE AND status IN ($3, $4) AND expiration_date < $5
+		ORDER BY expiration_date, id LIMIT $6`,
+		orgId, groupId, QuoteStatusSent, QuoteStatusViewed, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgxRowToQuote)
+}
+
+// ExpireQuote expires the quote with a single conditional update so that
+// sweeps running at the same time on several instances expire it only once.
+// The quote is marked as locked by the sweep only when it was not locked
+// already.
+func (p *Postgres) ExpireQuote(ctx context.Context, id ID, change QuoteStatusChange, lock bool) (*Quote, error) {
+	if _, err := p.getQuote(ctx, id); err != nil {
+		return nil, err
+	}
+	tag, err := p.db.Exec(ctx, `
+		UPDATE quotes SET status=$1, status_history=status_history || $2::jsonb, locked=locked OR $3, expiry_locked=$3 AND NOT locked, updated=$4
+		WHERE id=$5 AND hidden=FALSE AND status=$6 AND expiration_date < $7`,
+		QuoteStatusExpired, mustMarshal([]QuoteStatusChange{change}), lock, time.Now(), id, change.From, change.On)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, InvalidQuoteStatusError
+	}
+	return p.getQuote(ctx, id)
+}
+
 func (p *Postgres) LockQuote(ctx context.Context, id ID) (*Quote, error) {
-	return p.updateQuoteField(ctx, id, "locked", true)
+	if _, err := p.getQuote(ctx, id); err != nil {
+		return nil, err
+	}
+	_, err := p.db.Exec(ctx, `UPDATE quotes SET locked=TRUE, expiry_locked=FALSE, updated=$1 WHERE id=$2`, time.Now(), id)
+	if err != nil {
+		return nil, err
+	}
+	return p.getQuote(ctx, id)
+}
+
+func (p *Postgres) UnlockQuote(ctx context.Context, id ID, unlock QuoteUnlock) (*Quote, error) {
+	if _, err := p.getQuote(ctx, id); err != nil {
+		return nil, err
+	}
+	_, err := p.db.Exec(ctx, `UPDATE quotes SET locked=FALSE, expiry_locked=FALSE, unlock_history=unlock_history || $1::jsonb, updated=$2 WHERE id=$3`,
+		mustMarshal([]QuoteUnlock{unlock}), time.Now(), id)
+	if err != nil {
+		return nil, err
+	}
+	return p.getQuote(ctx, id)
 }
 
 func (p *Postgres) DeleteQuote(ctx context.Context, id ID) (*Quote, error) {
-	q, err := p.getQuote(ctx, id)
+	q, err := p.getUnlockedQuote(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -1714,7 +2034,7 @@ func (p *Postgres) DeleteQuote(ctx context.Context, id ID) (*Quote, error) {
 }
 
 func (p *Postgres) QuoteAddLineItem(ctx context.Context, id ID, lineItemId ID) (*Quote, error) {
-	q, err := p.getQuote(ctx, id)
+	q, err := p.getUnlockedQuote(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -1733,7 +2053,7 @@ func (p *Postgres) QuoteAddLineItem(ctx context.Context, id ID, lineItemId ID) (
 }
 
 func (p *Postgres) QuoteRemoveLineItem(ctx context.Context, id ID, lineItemId ID) (*Quote, error) {
-	q, err := p.getQuote(ctx, id)
+	q, err := p.getUnlockedQuote(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -1754,7 +2074,7 @@ func (p *Postgres) QuoteRemoveLineItem(ctx context.Context, id ID, lineItemId ID
 }
 
 func (p *Postgres) QuoteAddAdjustment(ctx context.Context, id ID, adjustmentId ID) (*Quote, error) {
-	q, err := p.getQuote(ctx, id)
+	q, err := p.getUnlockedQuote(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -1772,8 +2092,24 @@ func (p *Postgres) QuoteAddAdjustment(ctx context.Context, id ID, adjustmentId I
 	return q, nil
 }
 
+// QuoteAddOption appends a new option to the end of the quote's options.
+func (p *Postgres) QuoteAddOption(ctx context.Context, id ID, name, description string) (*QuoteOption, error) {
+	q, err := p.getUnlockedQuote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	option := QuoteOption{Id: newID(), Name: name, Description: description}
+	q.Options = append(q.Options, option)
+	_, err = p.db.Exec(ctx, `UPDATE quotes SET options=$1, updated=$2 WHERE id=$3`,
+		mustMarshal(q.Options), time.Now(), id)
+	if err != nil {
+		return nil, err
+	}
+	return &option, nil
+}
+
 func (p *Postgres) QuoteRemoveAdjustment(ctx context.Context, id ID, adjustmentId ID) (*Quote, error) {
-	q, err := p.getQuote(ctx, id)
+	q, err := p.getUnlockedQuote(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -1794,286 +2130,1551 @@ func (p *Postgres) QuoteRemoveAdjustment(ctx context.Context, id ID, adjustmentI
 }
 
 // ─────────────────────────────────────────────
-// LINE ITEM
+// QUOTE REVISION
 // ─────────────────────────────────────────────
 
-const lineItemCols = `id, quote_id, parent_id, sub_item_ids, image_id, description, quantity, quantity_suffix, quantity_prefix, unit_price, unit_price_suffix, unit_price_prefix, amount, amount_suffix, amount_prefix, open, created, updated`
+const quoteRevisionCols = `id, org_id, group_id, quote_id, number, snapshot, created`
 
-func (p *Postgres) createLineItemRaw(ctx context.Context, quoteId ID, parentId *ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
-	orgId, groupId, err := p.ext(ctx)
+func (p *Postgres) scanQuoteRevision(ctx context.Context, row pgx.CollectableRow) (*QuoteRevision, error) {
+	var r QuoteRevision
+	var orgId, groupId string
+	var snapshotJSON []byte
+	var id, quoteId ID
+	var number int
+	var created time.Time
+	err := row.Scan(&id, &orgId, &groupId, &quoteId, &number, &snapshotJSON, &created)
 	if err != nil {
 		return nil, err
 	}
-	now := time.Now()
-	id := newID()
-	_, err = p.db.Exec(ctx, `
-		INSERT INTO line_items (id, org_id, group_id, quote_id, parent_id, sub_item_ids, image_id, description, quantity, quantity_suffix, quantity_prefix, unit_price, unit_price_suffix, unit_price_prefix, amount, amount_suffix, amount_prefix, open, created, updated)
-		VALUES ($1,$2,$3,$4,$5,'[]',NULL,$6,$7,'','',$8,'','', $9,'','',FALSE,$10,$11)`,
-		id, orgId, groupId, quoteId, parentId, description, quantity, unitPrice, amount, now, now,
-	)
-	if err != nil {
+	if err := p.authCheck(ctx, orgId, groupId); err != nil {
 		return nil, err
 	}
-	return &LineItem{
-		Id:          id,
-		QuoteId:     quoteId,
-		ParentId:    parentId,
-		SubItemIds:  []ID{},
-		Description: description,
-		Quantity:    quantity,
-		UnitPrice:   unitPrice,
-		Amount:      amount,
-		Created:     now,
-		Updated:     now,
-	}, nil
-}
-
-func (p *Postgres) CreateLineItem(ctx context.Context, quoteId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
-	return p.createLineItemRaw(ctx, quoteId, nil, description, quantity, unitPrice, amount)
-}
-
-func (p *Postgres) CreateSubLineItem(ctx context.Context, quoteId, parentId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
-	return p.createLineItemRaw(ctx, quoteId, &parentId, description, quantity, unitPrice, amount)
+	_ = mustUnmarshal(snapshotJSON, &r)
+	r.Id = id
+	r.QuoteId = quoteId
+	r.Number = number
+	r.Created = created
+	return &r, nil
 }
 
-func (p *Postgres) CreateDuplicateLineItem(ctx context.Context, id ID) (*LineItem, error) {
-	orig, err := p.GetLineItem(ctx, id)
+func (p *Postgres) CreateQuoteRevision(ctx context.Context, revision QuoteRevision) (*QuoteRevision, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.getQuote(ctx, revision.QuoteId); err != nil {
+		return nil, err
+	}
+	revision.Id = newID()
+	revision.Created = time.Now()
+	// the unique (quote_id, number) constraint rejects a concurrent revision
+	// that picked the same number
+	err = p.db.QueryRow(ctx, `
+		INSERT INTO quote_revisions (id, org_id, group_id, quote_id, number, snapshot, created)
+		SELECT $1, $2, $3, $4, COALESCE(MAX(number), 0) + 1, $5, $6 FROM quote_revisions WHERE quote_id=$4
+		RETURNING number`,
+		revision.Id, orgId, groupId, revision.QuoteId, mustMarshal(revision), revision.Created,
+	).Scan(&revision.Number)
 	if err != nil {
 		return nil, err
 	}
-	return p.createLineItemRaw(ctx, orig.QuoteId, orig.ParentId, orig.Description, orig.Quantity, orig.UnitPrice, orig.Amount)
+	return &revision, nil
 }
 
-func (p *Postgres) GetLineItem(ctx context.Context, id ID) (*LineItem, error) {
-	rows, err := p.db.Query(ctx, `SELECT `+lineItemCols+` FROM line_items WHERE id=$1`, id)
+func (p *Postgres) GetQuoteRevision(ctx context.Context, id ID) (*QuoteRevision, error) {
+	rows, err := p.db.Query(ctx, `SELECT `+quoteRevisionCols+` FROM quote_revisions WHERE id=$1`, id)
 	if err != nil {
 		return nil, err
 	}
-	li, err := pgx.CollectOneRow(rows, pgxRowToLineItem)
+	return pgx.CollectOneRow(rows, func(row pgx.CollectableRow) (*QuoteRevision, error) {
+		return p.scanQuoteRevision(ctx, row)
+	})
+}
+
+func (p *Postgres) GetQuoteRevisions(ctx context.Context, quoteId ID) ([]*QuoteRevision, error) {
+	orgId, groupId, err := p.ext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	var orgId, groupId string
-	err = p.db.QueryRow(ctx, `SELECT org_id, group_id FROM line_items WHERE id=$1`, id).Scan(&orgId, &groupId)
+	rows, err := p.db.Query(ctx, `SELECT `+quoteRevisionCols+` FROM quote_revisions WHERE org_id=$1 AND group_id=$2 AND quote_id=$3 ORDER BY number`,
+		orgId, groupId, quoteId)
 	if err != nil {
 		return nil, err
 	}
-	if err := p.authCheck(ctx, orgId, groupId); err != nil {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*QuoteRevision, error) {
+		return p.scanQuoteRevision(ctx, row)
+	})
+}
+
+// ─────────────────────────────────────────────
+// QUOTE TEMPLATE
+// ─────────────────────────────────────────────
+
+const quoteTemplateCols = `id, org_id, group_id, name, description, template, created, updated, hidden`
+
+func pgxRowToQuoteTemplate(row pgx.CollectableRow) (*QuoteTemplate, error) {
+	var t QuoteTemplate
+	var templateJSON []byte
+	var id, orgId, groupId ID
+	var name, description string
+	var created, updated time.Time
+	var hidden bool
+	err := row.Scan(&id, &orgId, &groupId, &name, &description, &templateJSON, &created, &updated, &hidden)
+	if err != nil {
 		return nil, err
 	}
-	return li, nil
+	_ = mustUnmarshal(templateJSON, &t)
+	t.Id = id
+	t.OrgId = orgId
+	t.GroupId = groupId
+	t.Name = name
+	t.Description = description
+	t.Created = created
+	t.Updated = updated
+	t.Hidden = hidden
+	return &t, nil
 }
 
-func (p *Postgres) MoveLineItem(ctx context.Context, id ID, parentId *ID, index *int) (*LineItem, error) {
-	li, err := p.GetLineItem(ctx, id)
+func (p *Postgres) CreateQuoteTemplate(ctx context.Context, template QuoteTemplate) (*QuoteTemplate, error) {
+	orgId, groupId, err := p.ext(ctx)
 	if err != nil {
 		return nil, err
 	}
 	now := time.Now()
-	_, err = p.db.Exec(ctx, `UPDATE line_items SET parent_id=$1, updated=$2 WHERE id=$3`,
-		parentId, now, id)
+	template.Id = newID()
+	template.OrgId = orgId
+	template.GroupId = groupId
+	template.Created = now
+	template.Updated = now
+	template.Hidden = false
+	_, err = p.db.Exec(ctx, `
+		INSERT INTO quote_templates (id, org_id, group_id, name, description, template, created, updated, hidden)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,FALSE)`,
+		template.Id, orgId, groupId, template.Name, template.Description, mustMarshal(template), now, now,
+	)
 	if err != nil {
 		return nil, err
 	}
-	li.ParentId = parentId
-	li.Updated = now
-	return li, nil
+	return &template, nil
 }
 
-func (p *Postgres) UpdateLineItemImage(ctx context.Context, id ID, imageId *ID) (*LineItem, error) {
-	li, err := p.GetLineItem(ctx, id)
+func (p *Postgres) GetQuoteTemplate(ctx context.Context, id ID) (*QuoteTemplate, error) {
+	rows, err := p.db.Query(ctx, `SELECT `+quoteTemplateCols+` FROM quote_templates WHERE id=$1`, id)
 	if err != nil {
 		return nil, err
 	}
-	now := time.Now()
-	_, err = p.db.Exec(ctx, `UPDATE line_items SET image_id=$1, updated=$2 WHERE id=$3`,
-		imageId, now, id)
+	t, err := pgx.CollectOneRow(rows, pgxRowToQuoteTemplate)
 	if err != nil {
 		return nil, err
 	}
-	li.ImageId = imageId
-	li.Updated = now
-	return li, nil
-}
-
-func (p *Postgres) UpdateLineItemDescription(ctx context.Context, id ID, description string) (*LineItem, error) {
+	if err := p.authCheck(ctx, t.OrgId, t.GroupId); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (p *Postgres) GetQuoteTemplates(ctx context.Context) ([]*QuoteTemplate, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx,
+		`SELECT `+quoteTemplateCols+` FROM quote_templates WHERE org_id=$1 AND group_id=$2 AND hidden=FALSE ORDER BY name`,
+		orgId, groupId)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgxRowToQuoteTemplate)
+}
+
+func (p *Postgres) SearchQuoteTemplates(ctx context.Context, search string) ([]*QuoteTemplate, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx,
+		`SELECT `+quoteTemplateCols+` FROM quote_templates WHERE org_id=$1 AND group_id=$2 AND hidden=FALSE AND (name ILIKE $3 OR description ILIKE $3) ORDER BY name`,
+		orgId, groupId, containsPattern(search))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgxRowToQuoteTemplate)
+}
+
+func (p *Postgres) UpdateQuoteTemplateInfo(ctx context.Context, id ID, name, description string) (*QuoteTemplate, error) {
+	t, err := p.GetQuoteTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	t.Name = name
+	t.Description = description
+	t.Updated = time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE quote_templates SET name=$1, description=$2, template=$3, updated=$4 WHERE id=$5`,
+		name, description, mustMarshal(t), t.Updated, id)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (p *Postgres) DeleteQuoteTemplate(ctx context.Context, id ID) error {
+	if _, err := p.GetQuoteTemplate(ctx, id); err != nil {
+		return err
+	}
+	_, err := p.db.Exec(ctx, `UPDATE quote_templates SET hidden=TRUE, updated=$1 WHERE id=$2`, time.Now(), id)
+	return err
+}
+
+// ─────────────────────────────────────────────
+// QUOTE NUMBER SEQUENCE
+// ─────────────────────────────────────────────
+
+const quoteNumberSequenceCols = `org_id, group_id, pattern, assign_on, reset_yearly, counter, year, updated`
+
+func pgxRowToQuoteNumberSequence(row pgx.CollectableRow) (*QuoteNumberSequence, error) {
+	var seq QuoteNumberSequence
+	err := row.Scan(&seq.OrgId, &seq.GroupId, &seq.Pattern, &seq.AssignOn, &seq.ResetYearly, &seq.Counter, &seq.Year, &seq.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &seq, nil
+}
+
+// SetQuoteNumberSequence creates or reconfigures the group's sequence. The
+// counter carries on from where it was.
+func (p *Postgres) SetQuoteNumberSequence(ctx context.Context, seq QuoteNumberSequence) (*QuoteNumberSequence, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx, `
+		INSERT INTO quote_number_sequences (org_id, group_id, pattern, assign_on, reset_yearly, counter, year, updated)
+		VALUES ($1,$2,$3,$4,$5,0,0,$6)
+		ON CONFLICT (org_id, group_id) DO UPDATE SET pattern=$3, assign_on=$4, reset_yearly=$5, updated=$6
+		RETURNING `+quoteNumberSequenceCols,
+		orgId, groupId, seq.Pattern, seq.AssignOn, seq.ResetYearly, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectOneRow(rows, pgxRowToQuoteNumberSequence)
+}
+
+func (p *Postgres) GetQuoteNumberSequence(ctx context.Context) (*QuoteNumberSequence, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx,
+		`SELECT `+quoteNumberSequenceCols+` FROM quote_number_sequences WHERE org_id=$1 AND group_id=$2`,
+		orgId, groupId)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := pgx.CollectOneRow(rows, pgxRowToQuoteNumberSequence)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return seq, err
+}
+
+const quotePolicyCols = `org_id, group_id, min_margin, max_discount, approver_roles, updated`
+
+func pgxRowToQuotePolicy(row pgx.CollectableRow) (*QuotePolicy, error) {
+	var policy QuotePolicy
+	var maxDiscountJSON, approverRolesJSON []byte
+	err := row.Scan(&policy.OrgId, &policy.GroupId, &policy.MinMargin, &maxDiscountJSON, &approverRolesJSON, &policy.Updated)
+	if err != nil {
+		return nil, err
+	}
+	_ = mustUnmarshal(maxDiscountJSON, &policy.MaxDiscount)
+	if policy.MaxDiscount == nil {
+		policy.MaxDiscount = map[Role]int{}
+	}
+	_ = mustUnmarshal(approverRolesJSON, &policy.ApproverRoles)
+	if policy.ApproverRoles == nil {
+		policy.ApproverRoles = []Role{}
+	}
+	return &policy, nil
+}
+
+// SetQuotePolicy creates or replaces the group's quote policy.
+func (p *Postgres) SetQuotePolicy(ctx context.Context, policy QuotePolicy) (*QuotePolicy, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy.MaxDiscount == nil {
+		policy.MaxDiscount = map[Role]int{}
+	}
+	if policy.ApproverRoles == nil {
+		policy.ApproverRoles = []Role{}
+	}
+	rows, err := p.db.Query(ctx, `
+		INSERT INTO quote_policies (org_id, group_id, min_margin, max_discount, approver_roles, updated)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		ON CONFLICT (org_id, group_id) DO UPDATE SET min_margin=$3, max_discount=$4, approver_roles=$5, updated=$6
+		RETURNING `+quotePolicyCols,
+		orgId, groupId, policy.MinMargin, mustMarshal(policy.MaxDiscount), mustMarshal(policy.ApproverRoles), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectOneRow(rows, pgxRowToQuotePolicy)
+}
+
+func (p *Postgres) GetQuotePolicy(ctx context.Context) (*QuotePolicy, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx,
+		`SELECT `+quotePolicyCols+` FROM quote_policies WHERE org_id=$1 AND group_id=$2`,
+		orgId, groupId)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := pgx.CollectOneRow(rows, pgxRowToQuotePolicy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return policy, err
+}
+
+// AllocateQuoteNumber hands out the group's next number. The update holds the
+// sequence's row lock, so concurrent allocations never share a number. See
+// advanceQuoteNumberSequence for the counter rules.
+func (p *Postgres) AllocateQuoteNumber(ctx context.Context, year int) (*QuoteNumberSequence, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx, `
+		UPDATE quote_number_sequences
+		SET counter = CASE WHEN reset_yearly AND year <> $3 THEN 1 ELSE counter + 1 END, year=$3, updated=$4
+		WHERE org_id=$1 AND group_id=$2
+		RETURNING `+quoteNumberSequenceCols,
+		orgId, groupId, year, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	seq, err := pgx.CollectOneRow(rows, pgxRowToQuoteNumberSequence)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return seq, err
+}
+
+// ─────────────────────────────────────────────
+// LINE ITEM
+// ─────────────────────────────────────────────
+
+const lineItemCols = `id, quote_id, parent_id, sub_item_ids, image_id, description, quantity, quantity_suffix, quantity_prefix, unit_price, unit_price_suffix, unit_price_prefix, amount, amount_suffix, amount_prefix, open, name, source_item_id, source_price_id, hide_from_customer, optional, alternate_group, selected, option_id, removes_line_item_id, unit_cost, created, updated`
+
+func (p *Postgres) createLineItemRaw(ctx context.Context, quoteId ID, parentId *ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkQuoteUnlocked(ctx, quoteId); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	id := newID()
+	_, err = p.db.Exec(ctx, `
+		INSERT INTO line_items (id, org_id, group_id, quote_id, parent_id, sub_item_ids, image_id, description, quantity, quantity_suffix, quantity_prefix, unit_price, unit_price_suffix, unit_price_prefix, amount, amount_suffix, amount_prefix, open, created, updated)
+		VALUES ($1,$2,$3,$4,$5,'[]',NULL,$6,$7,'','',$8,'','', $9,'','',FALSE,$10,$11)`,
+		id, orgId, groupId, quoteId, parentId, description, quantity, unitPrice, amount, now, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LineItem{
+		Id:          id,
+		QuoteId:     quoteId,
+		ParentId:    parentId,
+		SubItemIds:  []ID{},
+		Description: description,
+		Quantity:    quantity,
+		UnitPrice:   unitPrice,
+		Amount:      amount,
+		Created:     now,
+		Updated:     now,
+	}, nil
+}
+
+func (p *Postgres) CreateLineItem(ctx context.Context, quoteId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
+	return p.createLineItemRaw(ctx, quoteId, nil, description, quantity, unitPrice, amount)
+}
+
+func (p *Postgres) CreateSubLineItem(ctx context.Context, quoteId, parentId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error) {
+	return p.createLineItemRaw(ctx, quoteId, &parentId, description, quantity, unitPrice, amount)
+}
+
+// CreateDuplicateLineItem copies every field of the line item into a new line
+// item on quoteId under parentId. The copy starts without sub items.
+func (p *Postgres) CreateDuplicateLineItem(ctx context.Context, id ID, quoteId ID, parentId *ID) (*LineItem, error) {
+	if _, err := p.GetLineItem(ctx, id); err != nil {
+		return nil, err
+	}
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkQuoteUnlocked(ctx, quoteId); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	newId := newID()
+	_, err = p.db.Exec(ctx, `
+		INSERT INTO line_items (id, org_id, group_id, quote_id, parent_id, sub_item_ids, image_id, description, quantity, quantity_suffix, quantity_prefix, unit_price, unit_price_suffix, unit_price_prefix, amount, amount_suffix, amount_prefix, open, name, source_item_id, source_price_id, hide_from_customer, optional, alternate_group, selected, option_id, removes_line_item_id, unit_cost, created, updated)
+		SELECT $1, $2, $3, $4, $5, '[]', image_id, description, quantity, quantity_suffix, quantity_prefix, unit_price, unit_price_suffix, unit_price_prefix, amount, amount_suffix, amount_prefix, open, name, source_item_id, source_price_id, hide_from_customer, optional, alternate_group, selected, option_id, removes_line_item_id, unit_cost, $6, $7
+		FROM line_items WHERE id=$8`,
+		newId, orgId, groupId, quoteId, parentId, now, now, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetLineItem(ctx, newId)
+}
+
+func (p *Postgres) GetLineItem(ctx context.Context, id ID) (*LineItem, error) {
+	rows, err := p.db.Query(ctx, `SELECT `+lineItemCols+` FROM line_items WHERE id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	li, err := pgx.CollectOneRow(rows, pgxRowToLineItem)
+	if err != nil {
+		return nil, err
+	}
+	var orgId, groupId string
+	err = p.db.QueryRow(ctx, `SELECT org_id, group_id FROM line_items WHERE id=$1`, id).Scan(&orgId, &groupId)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authCheck(ctx, orgId, groupId); err != nil {
+		return nil, err
+	}
+	return li, nil
+}
+
+// getUnlockedLineItem loads a line item that is about to be changed, returning
+// a *QuoteLockedError when its quote is locked.
+func (p *Postgres) getUnlockedLineItem(ctx context.Context, id ID) (*LineItem, error) {
 	li, err := p.GetLineItem(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if err := p.checkQuoteUnlocked(ctx, li.QuoteId); err != nil {
+		return nil, err
+	}
+	return li, nil
+}
+
+// MoveLineItem moves the line item under parentId (or to the top level when
+// nil) at index among its new siblings, updating the quote's LineItemIds and
+// the old and new parents' SubItemIds in a single transaction.
+func (p *Postgres) MoveLineItem(ctx context.Context, id ID, parentId *ID, index *int) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// lock the quote so concurrent moves on it are applied one at a time
+	rows, err := tx.Query(ctx, `SELECT `+quoteCols+` FROM quotes WHERE id=$1 FOR UPDATE`, li.QuoteId)
+	if err != nil {
+		return nil, err
+	}
+	q, err := pgx.CollectOneRow(rows, pgxRowToQuote)
+	if err != nil {
+		return nil, err
+	}
+	rows, err = tx.Query(ctx, `SELECT `+lineItemCols+` FROM line_items WHERE quote_id=$1`, li.QuoteId)
+	if err != nil {
+		return nil, err
+	}
+	all, err := pgx.CollectRows(rows, pgxRowToLineItem)
+	if err != nil {
+		return nil, err
+	}
+	lineItems := map[ID]*LineItem{}
+	for _, l := range all {
+		if slices.Contains(q.LineItemIds, l.Id) {
+			lineItems[l.Id] = l
+		}
+	}
+
+	move, err := planLineItemMove(q, lineItems, id, parentId, index)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = tx.Exec(ctx, `UPDATE line_items SET parent_id=$1, updated=$2 WHERE id=$3`, parentId, now, id)
+	if err != nil {
+		return nil, err
+	}
+	for subParentId, subItemIds := range move.SubItemIds {
+		_, err = tx.Exec(ctx, `UPDATE line_items SET sub_item_ids=$1, updated=$2 WHERE id=$3`,
+			mustMarshal(subItemIds), now, subParentId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.Exec(ctx, `UPDATE quotes SET line_item_ids=$1, updated=$2 WHERE id=$3`,
+		mustMarshal(move.LineItemIds), now, li.QuoteId)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	li.ParentId = parentId
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemImage(ctx context.Context, id ID, imageId *ID) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET image_id=$1, updated=$2 WHERE id=$3`,
+		imageId, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.ImageId = imageId
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemDescription(ctx context.Context, id ID, description string) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET description=$1, updated=$2 WHERE id=$3`,
+		description, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.Description = description
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemQuantity(ctx context.Context, id ID, quantity int, prefix, suffix string) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET quantity=$1, quantity_prefix=$2, quantity_suffix=$3, updated=$4 WHERE id=$5`,
+		quantity, prefix, suffix, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.Quantity = quantity
+	li.QuantityPrefix = prefix
+	li.QuantitySuffix = suffix
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemUnitPrice(ctx context.Context, id ID, unitPrice int, prefix, suffix string) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET unit_price=$1, unit_price_prefix=$2, unit_price_suffix=$3, updated=$4 WHERE id=$5`,
+		unitPrice, prefix, suffix, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.UnitPrice = unitPrice
+	li.UnitPricePrefix = prefix
+	li.UnitPriceSuffix = suffix
+	li.Updated = now
+	return li, nil
+}
+
+// UpdateLineItemUnitCost changes what the line item costs. Cost is internal
+// to the business, so it can be corrected after the quote is locked.
+func (p *Postgres) UpdateLineItemUnitCost(ctx context.Context, id ID, unitCost int) (*LineItem, error) {
+	li, err := p.GetLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET unit_cost=$1, updated=$2 WHERE id=$3`,
+		unitCost, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.UnitCost = unitCost
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemAmount(ctx context.Context, id ID, amount *int, prefix, suffix string) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET amount=$1, amount_prefix=$2, amount_suffix=$3, updated=$4 WHERE id=$5`,
+		amount, prefix, suffix, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.Amount = amount
+	li.AmountPrefix = prefix
+	li.AmountSuffix = suffix
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemOpen(ctx context.Context, id ID, open bool) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET open=$1, updated=$2 WHERE id=$3`, open, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.Open = open
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemName(ctx context.Context, id ID, name string) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET name=$1, updated=$2 WHERE id=$3`, name, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.Name = name
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemSource(ctx context.Context, id ID, itemId, priceId *ID) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET source_item_id=$1, source_price_id=$2, updated=$3 WHERE id=$4`,
+		itemId, priceId, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.SourceItemId = itemId
+	li.SourcePriceId = priceId
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemHideFromCustomer(ctx context.Context, id ID, hideFromCustomer bool) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET hide_from_customer=$1, updated=$2 WHERE id=$3`,
+		hideFromCustomer, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.HideFromCustomer = hideFromCustomer
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemOptional(ctx context.Context, id ID, optional bool) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET optional=$1, updated=$2 WHERE id=$3`,
+		optional, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.Optional = optional
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemAlternateGroup(ctx context.Context, id ID, alternateGroup string) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET alternate_group=$1, updated=$2 WHERE id=$3`,
+		alternateGroup, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.AlternateGroup = alternateGroup
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemSelected(ctx context.Context, id ID, selected bool) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET selected=$1, updated=$2 WHERE id=$3`,
+		selected, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.Selected = selected
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemOptionId(ctx context.Context, id ID, optionId ID) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET option_id=$1, updated=$2 WHERE id=$3`,
+		optionId, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.OptionId = optionId
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) UpdateLineItemRemovesLineItemId(ctx context.Context, id ID, removesLineItemId ID) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET removes_line_item_id=$1, updated=$2 WHERE id=$3`,
+		removesLineItemId, now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.RemovesLineItemId = removesLineItemId
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) LineItemAddSubItem(ctx context.Context, id ID, subItemId ID) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(li.SubItemIds, subItemId) {
+		return li, nil
+	}
+	li.SubItemIds = append(li.SubItemIds, subItemId)
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET sub_item_ids=$1, updated=$2 WHERE id=$3`,
+		mustMarshal(li.SubItemIds), now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) LineItemRemoveSubItem(ctx context.Context, id ID, subItemId ID) (*LineItem, error) {
+	li, err := p.getUnlockedLineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range li.SubItemIds {
+		if s == subItemId {
+			li.SubItemIds = slices.Delete(li.SubItemIds, i, i+1)
+			break
+		}
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE line_items SET sub_item_ids=$1, updated=$2 WHERE id=$3`,
+		mustMarshal(li.SubItemIds), now, id)
+	if err != nil {
+		return nil, err
+	}
+	li.Updated = now
+	return li, nil
+}
+
+func (p *Postgres) DeleteLineItem(ctx context.Context, id ID) error {
+	if _, err := p.getUnlockedLineItem(ctx, id); err != nil {
+		return err
+	}
+	_, err := p.db.Exec(ctx, `DELETE FROM line_items WHERE id=$1`, id)
+	return err
+}
+
+// ─────────────────────────────────────────────
+// ADJUSTMENT
+// ─────────────────────────────────────────────
+
+const adjCols = `id, quote_id, description, type, amount, option_id, created, updated`
+
+func (p *Postgres) CreateAdjustment(ctx context.Context, quoteId ID, description string, amount int, adjustmentType AdjustmentType) (*Adjustment, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkQuoteUnlocked(ctx, quoteId); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	id := newID()
+	_, err = p.db.Exec(ctx, `
+		INSERT INTO adjustments (id, org_id, group_id, quote_id, description, type, amount, created, updated)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		id, orgId, groupId, quoteId, description, adjustmentType, amount, now, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Adjustment{
+		Id: id, QuoteId: quoteId, Description: description,
+		Type: adjustmentType, Amount: amount,
+		Created: now, Updated: now,
+	}, nil
+}
+
+func (p *Postgres) GetAdjustment(ctx context.Context, id ID) (*Adjustment, error) {
+	rows, err := p.db.Query(ctx, `SELECT `+adjCols+` FROM adjustments WHERE id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	a, err := pgx.CollectOneRow(rows, pgxRowToAdjustment)
+	if err != nil {
+		return nil, err
+	}
+	var orgId, groupId string
+	err = p.db.QueryRow(ctx, `SELECT org_id, group_id FROM adjustments WHERE id=$1`, id).Scan(&orgId, &groupId)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authCheck(ctx, orgId, groupId); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// getUnlockedAdjustment loads an adjustment that is about to be changed,
+// returning a *QuoteLockedError when its quote is locked.
+func (p *Postgres) getUnlockedAdjustment(ctx context.Context, id ID) (*Adjustment, error) {
+	a, err := p.GetAdjustment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkQuoteUnlocked(ctx, a.QuoteId); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (p *Postgres) UpdateAdjustment(ctx context.Context, id ID, description string, amount int, adjustmentType AdjustmentType) (*Adjustment, error) {
+	a, err := p.getUnlockedAdjustment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE adjustments SET description=$1, amount=$2, type=$3, updated=$4 WHERE id=$5`,
+		description, amount, adjustmentType, now, id)
+	if err != nil {
+		return nil, err
+	}
+	a.Description = description
+	a.Amount = amount
+	a.Type = adjustmentType
+	a.Updated = now
+	return a, nil
+}
+
+func (p *Postgres) UpdateAdjustmentOptionId(ctx context.Context, id ID, optionId ID) (*Adjustment, error) {
+	a, err := p.getUnlockedAdjustment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE adjustments SET option_id=$1, updated=$2 WHERE id=$3`,
+		optionId, now, id)
+	if err != nil {
+		return nil, err
+	}
+	a.OptionId = optionId
+	a.Updated = now
+	return a, nil
+}
+
+func (p *Postgres) RemoveAdjustment(ctx context.Context, id ID) error {
+	if _, err := p.getUnlockedAdjustment(ctx, id); err != nil {
+		return err
+	}
+	_, err := p.db.Exec(ctx, `DELETE FROM adjustments WHERE id=$1`, id)
+	return err
+}
+
+// ─────────────────────────────────────────────
+// CONTACT
+// ─────────────────────────────────────────────
+
+const contactCols = `id, org_id, group_id, name, company_name, phones, emails, websites, street, city, state, zip, created, updated, hidden`
+
+func (p *Postgres) CreateContact(ctx context.Context, contact Contact) (*Contact, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	contact.Id = newID()
+	contact.OrgId = orgId
+	contact.GroupId = groupId
+	contact.Created = now
+	contact.Updated = now
+	contact.Hidden = false
+	if contact.Phones == nil {
+		contact.Phones = []string{}
+	}
+	if contact.Emails == nil {
+		contact.Emails = []string{}
+	}
+	if contact.Websites == nil {
+		contact.Websites = []string{}
+	}
+	_, err = p.db.Exec(ctx, `
+		INSERT INTO contacts (id, org_id, group_id, name, company_name, phones, emails, websites, street, city, state, zip, created, updated, hidden)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,FALSE)`,
+		contact.Id, orgId, groupId, contact.Name, contact.CompanyName,
+		mustMarshal(contact.Phones), mustMarshal(contact.Emails), mustMarshal(contact.Websites),
+		contact.Street, contact.City, contact.State, contact.Zip, now, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+func (p *Postgres) CreateDuplicateContact(ctx context.Context, id ID) (*Contact, error) {
+	if _, err := p.GetContact(ctx, id); err != nil {
+		return nil, err
+	}
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	newId := newID()
+	_, err = p.db.Exec(ctx, `
+		INSERT INTO contacts (id, org_id, group_id, name, company_name, phones, emails, websites, street, city, state, zip, created, updated, hidden)
+		SELECT $1, $2, $3, name, company_name, phones, emails, websites, street, city, state, zip, $5, $5, FALSE
+		FROM contacts WHERE id=$4`,
+		newId, orgId, groupId, id, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetContact(ctx, newId)
+}
+
+func (p *Postgres) GetContact(ctx context.Context, id ID) (*Contact, error) {
+	rows, err := p.db.Query(ctx, `SELECT `+contactCols+` FROM contacts WHERE id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	c, err := pgx.CollectOneRow(rows, pgxRowToContact)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authCheck(ctx, c.OrgId, c.GroupId); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *Postgres) GetContacts(ctx context.Context) ([]*Contact, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx,
+		`SELECT `+contactCols+` FROM contacts WHERE org_id=$1 AND group_id=$2 AND hidden=FALSE ORDER BY name, company_name`,
+		orgId, groupId)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgxRowToContact)
+}
+
+// SearchContacts matches search against the contact's name, company, emails
+// and phones. Phones are also compared on their digits alone, so "555-0100"
+// finds "(555) 0100".
+func (p *Postgres) SearchContacts(ctx context.Context, search string) ([]*Contact, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx, `
+		SELECT `+contactCols+` FROM contacts
+		WHERE org_id=$1 AND group_id=$2 AND hidden=FALSE AND (
+			name ILIKE $3 OR company_name ILIKE $3 OR emails::text ILIKE $3 OR phones::text ILIKE $3
+			OR ($4 <> '' AND regexp_replace(phones::text, '[^0-9]', '', 'g') LIKE '%' || $4 || '%')
+		)
+		ORDER BY name, company_name`,
+		orgId, groupId, containsPattern(search), phoneDigits(search))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgxRowToContact)
+}
+
+func (p *Postgres) UpdateContact(ctx context.Context, contact Contact) (*Contact, error) {
+	existing, err := p.GetContact(ctx, contact.Id)
+	if err != nil {
+		return nil, err
+	}
+	if contact.Phones == nil {
+		contact.Phones = []string{}
+	}
+	if contact.Emails == nil {
+		contact.Emails = []string{}
+	}
+	if contact.Websites == nil {
+		contact.Websites = []string{}
+	}
 	now := time.Now()
-	_, err = p.db.Exec(ctx, `UPDATE line_items SET description=$1, updated=$2 WHERE id=$3`,
-		description, now, id)
+	_, err = p.db.Exec(ctx, `
+		UPDATE contacts SET name=$1, company_name=$2, phones=$3, emails=$4, websites=$5, street=$6, city=$7, state=$8, zip=$9, updated=$10
+		WHERE id=$11`,
+		contact.Name, contact.CompanyName,
+		mustMarshal(contact.Phones), mustMarshal(contact.Emails), mustMarshal(contact.Websites),
+		contact.Street, contact.City, contact.State, contact.Zip, now, contact.Id,
+	)
 	if err != nil {
 		return nil, err
 	}
-	li.Description = description
-	li.Updated = now
-	return li, nil
+	contact.OrgId = existing.OrgId
+	contact.GroupId = existing.GroupId
+	contact.Created = existing.Created
+	contact.Updated = now
+	contact.Hidden = existing.Hidden
+	return &contact, nil
 }
 
-func (p *Postgres) UpdateLineItemQuantity(ctx context.Context, id ID, quantity int, prefix, suffix string) (*LineItem, error) {
-	li, err := p.GetLineItem(ctx, id)
+// DeleteContact hides the contact from listings and searches. Quotes that
+// already use it keep showing it.
+func (p *Postgres) DeleteContact(ctx context.Context, id ID) error {
+	if _, err := p.GetContact(ctx, id); err != nil {
+		return err
+	}
+	_, err := p.db.Exec(ctx, `UPDATE contacts SET hidden=TRUE, updated=$1 WHERE id=$2`, time.Now(), id)
+	return err
+}
+
+// ─────────────────────────────────────────────
+// CUSTOMER
+// ─────────────────────────────────────────────
+
+const customerCols = `id, org_id, group_id, name, contact_ids, address_ids, tags, notes, created, updated, hidden`
+
+func pgxRowToCustomer(row pgx.CollectableRow) (*Customer, error) {
+	var c Customer
+	var contactIdsJSON, addressIdsJSON, tagsJSON []byte
+	err := row.Scan(
+		&c.Id, &c.OrgId, &c.GroupId, &c.Name,
+		&contactIdsJSON, &addressIdsJSON, &tagsJSON, &c.Notes,
+		&c.Created, &c.Updated, &c.Hidden,
+	)
+	if err != nil {
+		return nil, err
+	}
+	_ = mustUnmarshal(contactIdsJSON, &c.ContactIds)
+	_ = mustUnmarshal(addressIdsJSON, &c.AddressIds)
+	_ = mustUnmarshal(tagsJSON, &c.Tags)
+	if c.ContactIds == nil {
+		c.ContactIds = []ID{}
+	}
+	if c.AddressIds == nil {
+		c.AddressIds = []ID{}
+	}
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	return &c, nil
+}
+
+func (p *Postgres) CreateCustomer(ctx context.Context, customer Customer) (*Customer, error) {
+	orgId, groupId, err := p.ext(ctx)
 	if err != nil {
 		return nil, err
 	}
 	now := time.Now()
-	_, err = p.db.Exec(ctx, `UPDATE line_items SET quantity=$1, quantity_prefix=$2, quantity_suffix=$3, updated=$4 WHERE id=$5`,
-		quantity, prefix, suffix, now, id)
+	customer.Id = newID()
+	customer.OrgId = orgId
+	customer.GroupId = groupId
+	customer.Created = now
+	customer.Updated = now
+	customer.Hidden = false
+	if customer.ContactIds == nil {
+		customer.ContactIds = []ID{}
+	}
+	if customer.AddressIds == nil {
+		customer.AddressIds = []ID{}
+	}
+	if customer.Tags == nil {
+		customer.Tags = []string{}
+	}
+	_, err = p.db.Exec(ctx, `
+		INSERT INTO customers (id, org_id, group_id, name, contact_ids, address_ids, tags, notes, created, updated, hidden)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,FALSE)`,
+		customer.Id, orgId, groupId, customer.Name,
+		mustMarshal(customer.ContactIds), mustMarshal(customer.AddressIds), mustMarshal(customer.Tags),
+		customer.Notes, now, now,
+	)
 	if err != nil {
 		return nil, err
 	}
-	li.Quantity = quantity
-	li.QuantityPrefix = prefix
-	li.QuantitySuffix = suffix
-	li.Updated = now
-	return li, nil
+	return &customer, nil
 }
 
-func (p *Postgres) UpdateLineItemUnitPrice(ctx context.Context, id ID, unitPrice int, prefix, suffix string) (*LineItem, error) {
-	li, err := p.GetLineItem(ctx, id)
+func (p *Postgres) GetCustomer(ctx context.Context, id ID) (*Customer, error) {
+	rows, err := p.db.Query(ctx, `SELECT `+customerCols+` FROM customers WHERE id=$1`, id)
 	if err != nil {
 		return nil, err
 	}
-	now := time.Now()
-	_, err = p.db.Exec(ctx, `UPDATE line_items SET unit_price=$1, unit_price_prefix=$2, unit_price_suffix=$3, updated=$4 WHERE id=$5`,
-		unitPrice, prefix, suffix, now, id)
+	c, err := pgx.CollectOneRow(rows, pgxRowToCustomer)
 	if err != nil {
 		return nil, err
 	}
-	li.UnitPrice = unitPrice
-	li.UnitPricePrefix = prefix
-	li.UnitPriceSuffix = suffix
-	li.Updated = now
-	return li, nil
+	if err := p.authCheck(ctx, c.OrgId, c.GroupId); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-func (p *Postgres) UpdateLineItemAmount(ctx context.Context, id ID, amount *int, prefix, suffix string) (*LineItem, error) {
-	li, err := p.GetLineItem(ctx, id)
+func (p *Postgres) GetCustomers(ctx context.Context) ([]*Customer, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx,
+		`SELECT `+customerCols+` FROM customers WHERE org_id=$1 AND group_id=$2 AND hidden=FALSE ORDER BY name`,
+		orgId, groupId)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgxRowToCustomer)
+}
+
+// SearchCustomers matches search against the customer's name, notes and tags,
+// and against the contacts and addresses attached to the customer.
+func (p *Postgres) SearchCustomers(ctx context.Context, search string) ([]*Customer, error) {
+	orgId, groupId, err := p.ext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	rows, err := p.db.Query(ctx, `
+		SELECT `+customerCols+` FROM customers c
+		WHERE org_id=$1 AND group_id=$2 AND hidden=FALSE AND (
+			name ILIKE $3 OR notes ILIKE $3 OR tags::text ILIKE $3
+			OR EXISTS (
+				SELECT 1 FROM contacts ct
+				WHERE ct.org_id=$1 AND ct.group_id=$2
+				AND (c.contact_ids ? ct.id OR c.address_ids ? ct.id)
+				AND (ct.name ILIKE $3 OR ct.company_name ILIKE $3 OR ct.emails::text ILIKE $3 OR ct.phones::text ILIKE $3 OR ct.street ILIKE $3)
+			)
+		)
+		ORDER BY name`,
+		orgId, groupId, containsPattern(search))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgxRowToCustomer)
+}
+
+func (p *Postgres) UpdateCustomerInfo(ctx context.Context, id ID, name string, tags []string, notes string) (*Customer, error) {
+	c, err := p.GetCustomer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []string{}
+	}
 	now := time.Now()
-	_, err = p.db.Exec(ctx, `UPDATE line_items SET amount=$1, amount_prefix=$2, amount_suffix=$3, updated=$4 WHERE id=$5`,
-		amount, prefix, suffix, now, id)
+	_, err = p.db.Exec(ctx, `UPDATE customers SET name=$1, tags=$2, notes=$3, updated=$4 WHERE id=$5`,
+		name, mustMarshal(tags), notes, now, id)
 	if err != nil {
 		return nil, err
 	}
-	li.Amount = amount
-	li.AmountPrefix = prefix
-	li.AmountSuffix = suffix
-	li.Updated = now
-	return li, nil
+	c.Name = name
+	c.Tags = tags
+	c.Notes = notes
+	c.Updated = now
+	return c, nil
 }
 
-func (p *Postgres) UpdateLineItemOpen(ctx context.Context, id ID, open bool) (*LineItem, error) {
-	li, err := p.GetLineItem(ctx, id)
+// updateCustomerIds rewrites one of the customer's id lists (contact_ids or
+// address_ids). col is a hard-coded string in all callers.
+func (p *Postgres) updateCustomerIds(ctx context.Context, id ID, col string, change func(c *Customer) []ID) (*Customer, error) {
+	c, err := p.GetCustomer(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	ids := change(c)
 	now := time.Now()
-	_, err = p.db.Exec(ctx, `UPDATE line_items SET open=$1, updated=$2 WHERE id=$3`, open, now, id)
+	_, err = p.db.Exec(ctx, `UPDATE customers SET `+col+`=$1, updated=$2 WHERE id=$3`, mustMarshal(ids), now, id)
 	if err != nil {
 		return nil, err
 	}
-	li.Open = open
-	li.Updated = now
-	return li, nil
+	return p.GetCustomer(ctx, id)
 }
 
-func (p *Postgres) DeleteLineItem(ctx context.Context, id ID) error {
-	if _, err := p.GetLineItem(ctx, id); err != nil {
+func (p *Postgres) CustomerAddContact(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	if _, err := p.GetContact(ctx, contactId); err != nil {
+		return nil, err
+	}
+	return p.updateCustomerIds(ctx, id, "contact_ids", func(c *Customer) []ID {
+		if slices.Contains(c.ContactIds, contactId) {
+			return c.ContactIds
+		}
+		return append(c.ContactIds, contactId)
+	})
+}
+
+func (p *Postgres) CustomerRemoveContact(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	return p.updateCustomerIds(ctx, id, "contact_ids", func(c *Customer) []ID {
+		return slices.DeleteFunc(c.ContactIds, func(i ID) bool { return i == contactId })
+	})
+}
+
+func (p *Postgres) CustomerAddAddress(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	if _, err := p.GetContact(ctx, contactId); err != nil {
+		return nil, err
+	}
+	return p.updateCustomerIds(ctx, id, "address_ids", func(c *Customer) []ID {
+		if slices.Contains(c.AddressIds, contactId) {
+			return c.AddressIds
+		}
+		return append(c.AddressIds, contactId)
+	})
+}
+
+func (p *Postgres) CustomerRemoveAddress(ctx context.Context, id ID, contactId ID) (*Customer, error) {
+	return p.updateCustomerIds(ctx, id, "address_ids", func(c *Customer) []ID {
+		return slices.DeleteFunc(c.AddressIds, func(i ID) bool { return i == contactId })
+	})
+}
+
+func (p *Postgres) DeleteCustomer(ctx context.Context, id ID) error {
+	if _, err := p.GetCustomer(ctx, id); err != nil {
 		return err
 	}
-	_, err := p.db.Exec(ctx, `DELETE FROM line_items WHERE id=$1`, id)
+	_, err := p.db.Exec(ctx, `UPDATE customers SET hidden=TRUE, updated=$1 WHERE id=$2`, time.Now(), id)
 	return err
 }
 
 // ─────────────────────────────────────────────
-// ADJUSTMENT
+// PAYMENT
 // ─────────────────────────────────────────────
 
-const adjCols = `id, quote_id, description, type, amount, created, updated`
+const paymentCols = `id, org_id, group_id, quote_id, amount, refund, method, reference, received_on, milestone_id, created, updated, hidden`
 
-func (p *Postgres) CreateAdjustment(ctx context.Context, quoteId ID, description string, amount int, adjustmentType AdjustmentType) (*Adjustment, error) {
+func pgxRowToPayment(row pgx.CollectableRow) (*Payment, error) {
+	var pm Payment
+	err := row.Scan(
+		&pm.Id, &pm.OrgId, &pm.GroupId, &pm.QuoteId, &pm.Amount, &pm.Refund,
+		&pm.Method, &pm.Reference, &pm.ReceivedOn, &pm.MilestoneId,
+		&pm.Created, &pm.Updated, &pm.Hidden,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &pm, nil
+}
+
+// CreatePayment records a payment against a quote. Payments are recorded
+// whether or not the quote is locked, as they usually come after it is
+// accepted.
+func (p *Postgres) CreatePayment(ctx context.Context, payment Payment) (*Payment, error) {
 	orgId, groupId, err := p.ext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := p.getQuote(ctx, payment.QuoteId); err != nil {
+		return nil, err
+	}
 	now := time.Now()
-	id := newID()
+	payment.Id = newID()
+	payment.OrgId = orgId
+	payment.GroupId = groupId
+	payment.Created = now
+	payment.Updated = now
+	payment.Hidden = false
 	_, err = p.db.Exec(ctx, `
-		INSERT INTO adjustments (id, org_id, group_id, quote_id, description, type, amount, created, updated)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
-		id, orgId, groupId, quoteId, description, adjustmentType, amount, now, now,
+		INSERT INTO payments (id, org_id, group_id, quote_id, amount, refund, method, reference, received_on, milestone_id, created, updated, hidden)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,FALSE)`,
+		payment.Id, orgId, groupId, payment.QuoteId, payment.Amount, payment.Refund,
+		payment.Method, payment.Reference, payment.ReceivedOn, payment.MilestoneId, now, now,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return &Adjustment{
-		Id: id, QuoteId: quoteId, Description: description,
-		Type: adjustmentType, Amount: amount,
-		Created: now, Updated: now,
-	}, nil
+	return &payment, nil
 }
 
-func (p *Postgres) GetAdjustment(ctx context.Context, id ID) (*Adjustment, error) {
-	rows, err := p.db.Query(ctx, `SELECT `+adjCols+` FROM adjustments WHERE id=$1`, id)
+func (p *Postgres) GetPayment(ctx context.Context, id ID) (*Payment, error) {
+	rows, err := p.db.Query(ctx, `SELECT `+paymentCols+` FROM payments WHERE id=$1`, id)
 	if err != nil {
 		return nil, err
 	}
-	a, err := pgx.CollectOneRow(rows, pgxRowToAdjustment)
+	pm, err := pgx.CollectOneRow(rows, pgxRowToPayment)
 	if err != nil {
 		return nil, err
 	}
-	var orgId, groupId string
-	err = p.db.QueryRow(ctx, `SELECT org_id, group_id FROM adjustments WHERE id=$1`, id).Scan(&orgId, &groupId)
+	if err := p.authCheck(ctx, pm.OrgId, pm.GroupId); err != nil {
+		return nil, err
+	}
+	return pm, nil
+}
+
+// GetQuotePayments returns the quote's payments and refunds in the order they
+// were received.
+func (p *Postgres) GetQuotePayments(ctx context.Context, quoteId ID) ([]*Payment, error) {
+	orgId, groupId, err := p.ext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if err := p.authCheck(ctx, orgId, groupId); err != nil {
+	rows, err := p.db.Query(ctx,
+		`SELECT `+paymentCols+` FROM payments WHERE org_id=$1 AND group_id=$2 AND quote_id=$3 AND hidden=FALSE ORDER BY received_on, created`,
+		orgId, groupId, quoteId)
+	if err != nil {
 		return nil, err
 	}
-	return a, nil
+	return pgx.CollectRows(rows, pgxRowToPayment)
 }
 
-func (p *Postgres) UpdateAdjustment(ctx context.Context, id ID, description string, amount int, adjustmentType AdjustmentType) (*Adjustment, error) {
-	a, err := p.GetAdjustment(ctx, id)
+func (p *Postgres) DeletePayment(ctx context.Context, id ID) error {
+	if _, err := p.GetPayment(ctx, id); err != nil {
+		return err
+	}
+	_, err := p.db.Exec(ctx, `UPDATE payments SET hidden=TRUE, updated=$1 WHERE id=$2`, time.Now(), id)
+	return err
+}
+
+// ─────────────────────────────────────────────
+// INVOICE
+// ─────────────────────────────────────────────
+
+const invoiceCols = `id, org_id, group_id, invoice, created, updated, hidden`
+
+func pgxRowToInvoice(row pgx.CollectableRow) (*Invoice, error) {
+	var inv Invoice
+	var invoiceJSON []byte
+	var id, orgId, groupId ID
+	var created, updated time.Time
+	var hidden bool
+	err := row.Scan(&id, &orgId, &groupId, &invoiceJSON, &created, &updated, &hidden)
+	if err != nil {
+		return nil, err
+	}
+	_ = mustUnmarshal(invoiceJSON, &inv)
+	inv.Id = id
+	inv.OrgId = orgId
+	inv.GroupId = groupId
+	inv.Created = created
+	inv.Updated = updated
+	inv.Hidden = hidden
+	return &inv, nil
+}
+
+// CreateInvoice stores the invoice as it is. Invoices are created from locked
+// quotes, so the quote is only checked to exist.
+func (p *Postgres) CreateInvoice(ctx context.Context, invoice Invoice) (*Invoice, error) {
+	orgId, groupId, err := p.ext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := p.getQuote(ctx, invoice.QuoteId); err != nil {
+		return nil, err
+	}
 	now := time.Now()
-	_, err = p.db.Exec(ctx, `UPDATE adjustments SET description=$1, amount=$2, type=$3, updated=$4 WHERE id=$5`,
-		description, amount, adjustmentType, now, id)
+	invoice.Id = newID()
+	invoice.OrgId = orgId
+	invoice.GroupId = groupId
+	invoice.Created = now
+	invoice.Updated = now
+	invoice.Hidden = false
+	_, err = p.db.Exec(ctx, `
+		INSERT INTO invoices (id, org_id, group_id, quote_id, number, invoice, created, updated, hidden)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,FALSE)`,
+		invoice.Id, orgId, groupId, invoice.QuoteId, invoice.Number, mustMarshal(invoice), now, now,
+	)
 	if err != nil {
 		return nil, err
 	}
-	a.Description = description
-	a.Amount = amount
-	a.Type = adjustmentType
-	a.Updated = now
-	return a, nil
+	return &invoice, nil
 }
 
-func (p *Postgres) RemoveAdjustment(ctx context.Context, id ID) error {
-	if _, err := p.GetAdjustment(ctx, id); err != nil {
+func (p *Postgres) GetInvoice(ctx context.Context, id ID) (*Invoice, error) {
+	rows, err := p.db.Query(ctx, `SELECT `+invoiceCols+` FROM invoices WHERE id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := pgx.CollectOneRow(rows, pgxRowToInvoice)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authCheck(ctx, inv.OrgId, inv.GroupId); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// GetQuoteInvoices returns the invoices made from the quote, oldest first.
+// Deleted invoices are left out.
+func (p *Postgres) GetQuoteInvoices(ctx context.Context, quoteId ID) ([]*Invoice, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx,
+		`SELECT `+invoiceCols+` FROM invoices WHERE org_id=$1 AND group_id=$2 AND quote_id=$3 AND hidden=FALSE ORDER BY created`,
+		orgId, groupId, quoteId)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgxRowToInvoice)
+}
+
+// updateInvoice applies change to the stored invoice.
+func (p *Postgres) updateInvoice(ctx context.Context, id ID, change func(inv *Invoice)) (*Invoice, error) {
+	inv, err := p.GetInvoice(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	change(inv)
+	inv.Updated = time.Now()
+	_, err = p.db.Exec(ctx, `UPDATE invoices SET invoice=$1, updated=$2 WHERE id=$3`, mustMarshal(inv), inv.Updated, id)
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (p *Postgres) UpdateInvoiceDueDate(ctx context.Context, id ID, dueDate *time.Time) (*Invoice, error) {
+	return p.updateInvoice(ctx, id, func(inv *Invoice) {
+		inv.DueDate = dueDate
+	})
+}
+
+func (p *Postgres) UpdateInvoicePaymentTerms(ctx context.Context, id ID, paymentTerms string) (*Invoice, error) {
+	return p.updateInvoice(ctx, id, func(inv *Invoice) {
+		inv.PaymentTerms = paymentTerms
+	})
+}
+
+func (p *Postgres) DeleteInvoice(ctx context.Context, id ID) error {
+	if _, err := p.GetInvoice(ctx, id); err != nil {
 		return err
 	}
-	_, err := p.db.Exec(ctx, `DELETE FROM adjustments WHERE id=$1`, id)
+	_, err := p.db.Exec(ctx, `UPDATE invoices SET hidden=TRUE, updated=$1 WHERE id=$2`, time.Now(), id)
 	return err
 }
 
 // ─────────────────────────────────────────────
-// CONTACT
+// INVOICE NUMBER SEQUENCE
 // ─────────────────────────────────────────────
 
-func (p *Postgres) GetContact(ctx context.Context, id ID) (*Contact, error) {
+const invoiceNumberSequenceCols = `org_id, group_id, pattern, reset_yearly, counter, year, updated`
+
+func pgxRowToInvoiceNumberSequence(row pgx.CollectableRow) (*InvoiceNumberSequence, error) {
+	var seq InvoiceNumberSequence
+	err := row.Scan(&seq.OrgId, &seq.GroupId, &seq.Pattern, &seq.ResetYearly, &seq.Counter, &seq.Year, &seq.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &seq, nil
+}
+
+// SetInvoiceNumberSequence creates or reconfigures the group's invoice
+// sequence. The counter carries on from where it was.
+func (p *Postgres) SetInvoiceNumberSequence(ctx context.Context, seq InvoiceNumberSequence) (*InvoiceNumberSequence, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx, `
+		INSERT INTO invoice_number_sequences (org_id, group_id, pattern, reset_yearly, counter, year, updated)
+		VALUES ($1,$2,$3,$4,0,0,$5)
+		ON CONFLICT (org_id, group_id) DO UPDATE SET pattern=$3, reset_yearly=$4, updated=$5
+		RETURNING `+invoiceNumberSequenceCols,
+		orgId, groupId, seq.Pattern, seq.ResetYearly, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectOneRow(rows, pgxRowToInvoiceNumberSequence)
+}
+
+func (p *Postgres) GetInvoiceNumberSequence(ctx context.Context) (*InvoiceNumberSequence, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
 	rows, err := p.db.Query(ctx,
-		`SELECT id, name, company_name, phones, emails, websites, street, city, state, zip FROM contacts WHERE id=$1`, id)
+		`SELECT `+invoiceNumberSequenceCols+` FROM invoice_number_sequences WHERE org_id=$1 AND group_id=$2`,
+		orgId, groupId)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := pgx.CollectOneRow(rows, pgxRowToInvoiceNumberSequence)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return seq, err
+}
+
+// AllocateInvoiceNumber hands out the group's next invoice number, starting a
+// sequence with DefaultInvoiceNumberPattern when the group has none. The
+// upsert holds the sequence's row lock, so concurrent allocations never share
+// a number.
+func (p *Postgres) AllocateInvoiceNumber(ctx context.Context, year int) (*InvoiceNumberSequence, error) {
+	orgId, groupId, err := p.ext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(ctx, `
+		INSERT INTO invoice_number_sequences AS s (org_id, group_id, pattern, reset_yearly, counter, year, updated)
+		VALUES ($1,$2,$3,FALSE,1,$4,$5)
+		ON CONFLICT (org_id, group_id) DO UPDATE
+		SET counter = CASE WHEN s.reset_yearly AND s.year <> $4 THEN 1 ELSE s.counter + 1 END, year=$4, updated=$5
+		RETURNING `+invoiceNumberSequenceCols,
+		orgId, groupId, DefaultInvoiceNumberPattern, year, time.Now())
 	if err != nil {
 		return nil, err
 	}
-	return pgx.CollectOneRow(rows, pgxRowToContact)
+	return pgx.CollectOneRow(rows, pgxRowToInvoiceNumberSequence)
 }
 
 // ─────────────────────────────────────────────
@@ -0,0 +1,29 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainsPattern(t *testing.T) {
+	testCases := []struct {
+		search   string
+		expected string
+	}{
+		{search: "", expected: "%%"},
+		{search: "EST-42", expected: "%EST-42%"},
+		{search: "50%", expected: `%50\%%`},
+		{search: "a_b", expected: `%a\_b%`},
+		{search: `c:\jobs`, expected: `%c:\\jobs%`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.search, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, containsPattern(tc.search))
+		})
+	}
+}
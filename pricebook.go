@@ -95,6 +95,10 @@ type CustomValueType = string
 const (
 	CustomValueTypeNumber CustomValueType = "number"
 	CustomValueTypeString CustomValueType = "string"
+	// CustomValueTypeFormula values are computed from other custom values, the
+	// item's cost and its default price. The descriptor's DefaultValue holds the
+	// formula expression, see formula.go for the supported syntax.
+	CustomValueTypeFormula CustomValueType = "formula"
 )
 
 type CustomValueConfig struct {
@@ -104,8 +108,10 @@ type CustomValueConfig struct {
 	Name        string                  `json:"name" firestore:"name"`
 	Description string                  `json:"description" firestore:"description"`
 	Descriptors []CustomValueDescriptor `json:"descriptors" firestore:"descriptors"`
-	Created     time.Time               `json:"created" firestore:"created"`
-	Updated     time.Time               `json:"updated" firestore:"updated"`
+	// PriceFormulaKey key of the formula descriptor that drives the default price of items using this config, if any
+	PriceFormulaKey *ID       `json:"priceFormulaKey" firestore:"priceFormulaKey"`
+	Created         time.Time `json:"created" firestore:"created"`
+	Updated         time.Time `json:"updated" firestore:"updated"`
 }
 
 type CustomValueDescriptor struct {
@@ -115,6 +121,26 @@ type CustomValueDescriptor struct {
 	ValueType    CustomValueType `json:"valueType" firestore:"valueType"`
 }
 
+// EffectiveCustomValues is the result of resolving an item's custom values
+// against the CustomValueConfig inherited from its category tree or pricebook.
+type EffectiveCustomValues struct {
+	// ConfigId identifier of the config that applies to the item, nil if none was found
+	ConfigId *ID `json:"configId" firestore:"configId"`
+	// Values one entry per descriptor in the resolved config, in descriptor order
+	Values []EffectiveCustomValue `json:"values" firestore:"values"`
+	// Orphaned custom values on the item whose keys are not in the resolved config
+	Orphaned []CustomValue `json:"orphaned" firestore:"orphaned"`
+}
+
+// EffectiveCustomValue is a descriptor paired with the value that applies to an item.
+type EffectiveCustomValue struct {
+	Descriptor CustomValueDescriptor `json:"descriptor" firestore:"descriptor"`
+	// Value the item's own value, or the descriptor's DefaultValue when the item has none
+	Value string `json:"value" firestore:"value"`
+	// IsDefault flag indicating the value came from the descriptor's DefaultValue
+	IsDefault bool `json:"isDefault" firestore:"isDefault"`
+}
+
 type SimpleItem struct {
 	Id          ID     `json:"id" firestore:"id"`
 	OrgId       ID     `json:"orgId" firestore:"orgId"`
@@ -0,0 +1,208 @@
+package pricey
+
+import (
+	"context"
+)
+
+// QuoteTotals holds the amounts calculated for a quote from its line items and
+// adjustments. All amounts are in pennies.
+type QuoteTotals struct {
+	// LineItemAmounts amount of every line item on the quote, keyed by line item id
+	LineItemAmounts map[ID]int `json:"lineItemAmounts" firestore:"lineItemAmounts"`
+	// SubTotal sum of the amounts of the top level line items in the priced option
+	SubTotal int `json:"subTotal" firestore:"subTotal"`
+	// AdjustmentAmounts amount each adjustment adds to the subtotal, keyed by adjustment id
+	AdjustmentAmounts map[ID]int `json:"adjustmentAmounts" firestore:"adjustmentAmounts"`
+	// Total subtotal plus every adjustment (discounts, fees and taxes)
+	Total int `json:"total" firestore:"total"`
+	// BalanceDue percentage of the total when BalancePercentDue is set, otherwise the fixed BalanceDue
+	BalanceDue int `json:"balanceDue" firestore:"balanceDue"`
+	// Excluded optional and alternate line items left out of the totals because they are not selected
+	Excluded map[ID]bool `json:"excluded" firestore:"excluded"`
+}
+
+// calculateQuoteTotals prices a quote. A line item's amount is its Amount when
+// overridden, otherwise UnitPrice * Quantity, and when it has no quantity the
+// sum of its sub line items. Only line items listed on the quote are counted,
+// and excluded line items (see excludedLineItems) are priced but left out of
+// their parent's amount and the subtotal. A quote with options is priced as its
+// active option (see activeQuoteOptionId).
+func calculateQuoteTotals(quote *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) *QuoteTotals {
+	return calculateOptionTotals(quote, lineItems, adjustments, activeQuoteOptionId(quote))
+}
+
+// calculateOptionTotals prices the quote as optionId: the shared top level line
+// items and adjustments along with those placed in optionId. Every line item is
+// still given an amount.
+func calculateOptionTotals(quote *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment, optionId ID) *QuoteTotals {
+	totals := &QuoteTotals{
+		LineItemAmounts:   map[ID]int{},
+		AdjustmentAmounts: map[ID]int{},
+		Excluded:          excludedLineItems(quote, lineItems),
+	}
+
+	counted := map[ID]bool{}
+	for _, lineItemId := range quote.LineItemIds {
+		if lineItems[lineItemId] != nil && !totals.Excluded[lineItemId] {
+			counted[lineItemId] = true
+		}
+	}
+	inProgress := map[ID]bool{}
+	for _, lineItemId := range quote.LineItemIds {
+		l := lineItems[lineItemId]
+		if l == nil {
+			continue
+		}
+		amount := lineItemAmount(lineItems, counted, totals.LineItemAmounts, inProgress, l)
+		if l.ParentId == nil && counted[lineItemId] && inQuoteOption(quote, l.OptionId, optionId) {
+			totals.SubTotal += amount
+		}
+	}
+
+	totals.Total = totals.SubTotal
+	for _, adjustmentId := range quote.AdjustmentIds {
+		a := adjustments[adjustmentId]
+		if a != nil && inQuoteOption(quote, a.OptionId, optionId) {
+			amount := adjustmentAmount(a, totals.SubTotal)
+			totals.AdjustmentAmounts[adjustmentId] = amount
+			totals.Total += amount
+		}
+	}
+
+	if quote.BalancePercentDue != 0 {
+		totals.BalanceDue = totals.Total * quote.BalancePercentDue / 100
+	} else {
+		totals.BalanceDue = quote.BalanceDue
+	}
+
+	return totals
+}
+
+func lineItemAmount(lineItems map[ID]*LineItem, counted map[ID]bool, amounts map[ID]int, inProgress map[ID]bool, l *LineItem) int {
+	if amount, ok := amounts[l.Id]; ok {
+		return amount
+	}
+	if inProgress[l.Id] {
+		return 0
+	}
+	inProgress[l.Id] = true
+	defer delete(inProgress, l.Id)
+
+	amount := 0
+	if l.Amount != nil {
+		amount = *l.Amount
+	} else if l.Quantity > 0 {
+		amount = l.UnitPrice * l.Quantity / 100
+	} else {
+		for _, subItemId := range l.SubItemIds {
+			sub := lineItems[subItemId]
+			if counted[subItemId] && sub.ParentId != nil && *sub.ParentId == l.Id {
+				amount += lineItemAmount(lineItems, counted, amounts, inProgress, sub)
+			}
+		}
+	}
+	amounts[l.Id] = amount
+	return amount
+}
+
+// excludedLineItems returns the line items on the quote that the customer has
+// not chosen. An optional line item is excluded until it is selected. Of the
+// line items sharing an alternate group only one is kept: the selected one, or
+// when none is selected the first in quote order. A group holding an optional
+// line item is optional as a whole and keeps nothing until one is selected.
+func excludedLineItems(quote *Quote, lineItems map[ID]*LineItem) map[ID]bool {
+	excluded := map[ID]bool{}
+	groups := map[string][]*LineItem{}
+	var groupOrder []string
+	for _, lineItemId := range quote.LineItemIds {
+		l := lineItems[lineItemId]
+		if l == nil {
+			continue
+		}
+		if l.AlternateGroup == "" {
+			if l.Optional && !l.Selected {
+				excluded[l.Id] = true
+			}
+			continue
+		}
+		if groups[l.AlternateGroup] == nil {
+			groupOrder = append(groupOrder, l.AlternateGroup)
+		}
+		groups[l.AlternateGroup] = append(groups[l.AlternateGroup], l)
+	}
+	for _, group := range groupOrder {
+		alternates := groups[group]
+		var kept *LineItem
+		optional := false
+		for _, l := range alternates {
+			optional = optional || l.Optional
+			if l.Selected && kept == nil {
+				kept = l
+			}
+		}
+		if kept == nil && !optional {
+			kept = alternates[0]
+		}
+		for _, l := range alternates {
+			if l != kept {
+				excluded[l.Id] = true
+			}
+		}
+	}
+	return excluded
+}
+
+// loadQuoteLineItemsAndAdjustments fetches every line item and adjustment listed
+// on the quote.
+func loadQuoteLineItemsAndAdjustments(ctx context.Context, store Store, quote *Quote) (map[ID]*LineItem, map[ID]*Adjustment, error) {
+	lineItems := map[ID]*LineItem{}
+	for _, lineItemId := range quote.LineItemIds {
+		lineItem, err := store.GetLineItem(ctx, lineItemId)
+		if err != nil {
+			return nil, nil, err
+		}
+		lineItems[lineItemId] = lineItem
+	}
+	adjustments := map[ID]*Adjustment{}
+	for _, adjustmentId := range quote.AdjustmentIds {
+		adjustment, err := store.GetAdjustment(ctx, adjustmentId)
+		if err != nil {
+			return nil, nil, err
+		}
+		adjustments[adjustmentId] = adjustment
+	}
+	return lineItems, adjustments, nil
+}
+
+// recalculateQuote prices the quote and persists its subtotal, total and
+// balance due when they have changed.
+func recalculateQuote(ctx context.Context, store Store, quoteId ID) (*Quote, error) {
+	quote, err := store.GetQuote(ctx, quoteId)
+	if err != nil {
+		return nil, err
+	}
+	lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, store, quote)
+	if err != nil {
+		return nil, err
+	}
+	totals := calculateQuoteTotals(quote, lineItems, adjustments)
+	if quote.SubTotal != totals.SubTotal {
+		quote, err = store.UpdateQuoteSubTotal(ctx, quoteId, totals.SubTotal)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if quote.Total != totals.Total {
+		quote, err = store.UpdateQuoteTotal(ctx, quoteId, totals.Total)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if quote.BalanceDue != totals.BalanceDue {
+		quote, err = store.UpdateQuoteBalanceDue(ctx, quoteId, totals.BalanceDue)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return quote, nil
+}
@@ -0,0 +1,129 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateQuoteTotals(t *testing.T) {
+	i := func(num int) *int {
+		return &num
+	}
+	s := func(str string) *string {
+		return &str
+	}
+	lineItems := map[ID]*LineItem{
+		"1":  {Id: "1", Quantity: 200, UnitPrice: 1050},
+		"2":  {Id: "2", SubItemIds: []ID{"3", "4"}},
+		"3":  {Id: "3", ParentId: s("2"), Quantity: 100, UnitPrice: 5000},
+		"4":  {Id: "4", ParentId: s("2"), Amount: i(2500)},
+		"5":  {Id: "5", ParentId: s("missing"), Quantity: 100, UnitPrice: 9999},
+		"6":  {Id: "6", Optional: true, Quantity: 100, UnitPrice: 700},
+		"7":  {Id: "7", Optional: true, Selected: true, Quantity: 100, UnitPrice: 800},
+		"8":  {Id: "8", AlternateGroup: "roof", Quantity: 100, UnitPrice: 1000},
+		"9":  {Id: "9", AlternateGroup: "roof", Quantity: 100, UnitPrice: 2000},
+		"10": {Id: "10", AlternateGroup: "roof", Selected: true, Quantity: 100, UnitPrice: 3000},
+		"11": {Id: "11", SubItemIds: []ID{"12", "13"}},
+		"12": {Id: "12", ParentId: s("11"), Quantity: 100, UnitPrice: 400},
+		"13": {Id: "13", ParentId: s("11"), Optional: true, Quantity: 100, UnitPrice: 600},
+		"14": {Id: "14", AlternateGroup: "trim", Optional: true, Quantity: 100, UnitPrice: 100},
+		"15": {Id: "15", AlternateGroup: "trim", Quantity: 100, UnitPrice: 200},
+	}
+	adjustments := map[ID]*Adjustment{
+		"1": {Id: "1", Type: AdjustmentTypeFlat, Amount: -1000},
+		"2": {Id: "2", Type: AdjustmentTypePercent, Amount: 10},
+	}
+	testCases := []struct {
+		name     string
+		quote    *Quote
+		expected *QuoteTotals
+	}{
+		{
+			name:  "empty",
+			quote: &Quote{},
+			expected: &QuoteTotals{
+				LineItemAmounts:   map[ID]int{},
+				AdjustmentAmounts: map[ID]int{},
+				Excluded:          map[ID]bool{},
+			},
+		},
+		{
+			name:  "line items and adjustments",
+			quote: &Quote{LineItemIds: []ID{"1", "2", "3", "4", "5"}, AdjustmentIds: []ID{"1", "2"}, BalancePercentDue: 50},
+			expected: &QuoteTotals{
+				LineItemAmounts:   map[ID]int{"1": 2100, "2": 7500, "3": 5000, "4": 2500, "5": 9999},
+				SubTotal:          9600,
+				AdjustmentAmounts: map[ID]int{"1": -1000, "2": 960},
+				Total:             9560,
+				BalanceDue:        4780,
+				Excluded:          map[ID]bool{},
+			},
+		},
+		{
+			name:  "sub line items not on the quote are ignored",
+			quote: &Quote{LineItemIds: []ID{"2", "3"}, BalanceDue: 100},
+			expected: &QuoteTotals{
+				LineItemAmounts:   map[ID]int{"2": 5000, "3": 5000},
+				SubTotal:          5000,
+				AdjustmentAmounts: map[ID]int{},
+				Total:             5000,
+				BalanceDue:        100,
+				Excluded:          map[ID]bool{},
+			},
+		},
+		{
+			name:  "unselected optional line items are excluded",
+			quote: &Quote{LineItemIds: []ID{"1", "6", "7", "11", "12", "13"}},
+			expected: &QuoteTotals{
+				LineItemAmounts:   map[ID]int{"1": 2100, "6": 700, "7": 800, "11": 400, "12": 400, "13": 600},
+				SubTotal:          3300,
+				AdjustmentAmounts: map[ID]int{},
+				Total:             3300,
+				Excluded:          map[ID]bool{"6": true, "13": true},
+			},
+		},
+		{
+			name:  "selected alternate is kept",
+			quote: &Quote{LineItemIds: []ID{"8", "9", "10"}},
+			expected: &QuoteTotals{
+				LineItemAmounts:   map[ID]int{"8": 1000, "9": 2000, "10": 3000},
+				SubTotal:          3000,
+				AdjustmentAmounts: map[ID]int{},
+				Total:             3000,
+				Excluded:          map[ID]bool{"8": true, "9": true},
+			},
+		},
+		{
+			name:  "first alternate is kept when none is selected",
+			quote: &Quote{LineItemIds: []ID{"9", "8"}},
+			expected: &QuoteTotals{
+				LineItemAmounts:   map[ID]int{"8": 1000, "9": 2000},
+				SubTotal:          2000,
+				AdjustmentAmounts: map[ID]int{},
+				Total:             2000,
+				Excluded:          map[ID]bool{"8": true},
+			},
+		},
+		{
+			name:  "optional alternate group keeps nothing until selected",
+			quote: &Quote{LineItemIds: []ID{"1", "14", "15"}},
+			expected: &QuoteTotals{
+				LineItemAmounts:   map[ID]int{"1": 2100, "14": 100, "15": 200},
+				SubTotal:          2100,
+				AdjustmentAmounts: map[ID]int{},
+				Total:             2100,
+				Excluded:          map[ID]bool{"14": true, "15": true},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, calculateQuoteTotals(tc.quote, lineItems, adjustments))
+		})
+	}
+}
@@ -38,14 +38,36 @@ type priceyPrint struct {
 	store            Store
 	pdfClient        *gotenberg.Client
 	standardTemplate *template.Template
+	invoiceTemplate  *template.Template
+	// changeOrderTemplate prints a change order against its original quote
+	changeOrderTemplate *template.Template
 }
 
 //go:embed templates/standard.html
 var standardTemplate string
 
+//go:embed templates/invoice.html
+var invoiceTemplate string
+
+//go:embed templates/changeorder.html
+var changeOrderTemplate string
+
 //go:embed templates/footer.html
 var footerTemplate string
 
+//go:embed templates/common.html
+var commonTemplate string
+
+// parsePage parses a page template along with the styles and partials in
+// common.html.
+func parsePage(name, page string, funcs template.FuncMap) (*template.Template, error) {
+	t, err := template.New(name).Funcs(funcs).Parse(page)
+	if err != nil {
+		return nil, err
+	}
+	return t.Parse(commonTemplate)
+}
+
 func newPrinter(store Store, pdfClient *gotenberg.Client) *priceyPrint {
 	funcs := template.FuncMap{
 		"pennies":          pennies,
@@ -54,15 +76,27 @@ func newPrinter(store Store, pdfClient *gotenberg.Client) *priceyPrint {
 		"adjustmentAmount": adjustmentAmount,
 		"qrcode":           qrcode,
 	}
-	standardTemplate, err := template.New("standard").Funcs(funcs).Parse(standardTemplate)
+	standardTemplate, err := parsePage("standard", standardTemplate, funcs)
 	if err != nil {
 		panic("failed to parse standard template: " + err.Error())
 	}
 
+	invoiceTemplate, err := parsePage("invoice", invoiceTemplate, funcs)
+	if err != nil {
+		panic("failed to parse invoice template: " + err.Error())
+	}
+
+	changeOrderTemplate, err := parsePage("changeorder", changeOrderTemplate, funcs)
+	if err != nil {
+		panic("failed to parse change order template: " + err.Error())
+	}
+
 	return &priceyPrint{
-		store:            store,
-		pdfClient:        pdfClient,
-		standardTemplate: standardTemplate,
+		store:               store,
+		pdfClient:           pdfClient,
+		standardTemplate:    standardTemplate,
+		invoiceTemplate:     invoiceTemplate,
+		changeOrderTemplate: changeOrderTemplate,
 	}
 }
 
@@ -70,6 +104,9 @@ func pennies(v int) string {
 	if v == 0 {
 		return ""
 	}
+	if v < 0 {
+		return "-" + pennies(-v)
+	}
 	d := fmt.Sprintf("%d", v/100)
 	ld := len(d)
 	sb := strings.Builder{}
@@ -151,76 +188,113 @@ func (v *priceyPrint) GetPrintableQuote(ctx context.Context, id ID) (*PrintableQ
 		if err != nil {
 			return err
 		}
-		images := map[ID]*Image{}
-		contacts := map[ID]*Contact{}
-		lineItems := map[ID]*LineItem{}
-		adjustments := map[ID]*Adjustment{}
-		if quote.LogoId != "" {
-			imageUrl, err := v.store.GetImageUrl(ctx, quote.LogoId)
-			if err != nil {
-				return err
-			}
-			if imageUrl != "" {
-				images[quote.LogoId] = &Image{
-					Id:  quote.LogoId,
-					Url: imageUrl,
-				}
-			}
+		contacts, err := loadQuoteContacts(ctx, v.store, quote)
+		if err != nil {
+			return err
 		}
-		if quote.SenderId != "" {
-			c, err := v.store.GetContact(ctx, quote.SenderId)
-			if err != nil {
-				return err
-			}
-			contacts[quote.SenderId] = c
+		lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, quote)
+		if err != nil {
+			return err
 		}
-		if quote.BillToId != "" {
-			c, err := v.store.GetContact(ctx, quote.BillToId)
-			if err != nil {
-				return err
-			}
-			contacts[quote.BillToId] = c
+		images, err := v.loadImages(ctx, quote, lineItems)
+		if err != nil {
+			return err
 		}
-		if quote.ShipToId != "" {
-			c, err := v.store.GetContact(ctx, quote.ShipToId)
-			if err != nil {
-				return err
-			}
-			contacts[quote.ShipToId] = c
+		payments, err := v.store.GetQuotePayments(ctx, id)
+		if err != nil {
+			return err
+		}
+		changeOrdersTotal, err := acceptedChangeOrdersTotal(ctx, v.store, quote)
+		if err != nil {
+			return err
 		}
 
-		for _, lineItemId := range quote.LineItemIds {
-			lineItem, err := v.store.GetLineItem(ctx, lineItemId)
-			if err != nil {
-				return err
-			}
-			lineItems[lineItemId] = lineItem
-			if lineItem.ImageId != nil && images[*lineItem.ImageId] == nil {
-				imgUrl, err := v.store.GetImageUrl(ctx, *lineItem.ImageId)
-				if err != nil {
-					return err
-				}
-				if imgUrl != "" {
-					images[*lineItem.ImageId] = &Image{Id: *lineItem.ImageId, Url: imgUrl}
-				}
-			}
+		fullQuote = v.getPrintableQuote(quote, images, contacts, lineItems, adjustments, payments)
+		if changeOrdersTotal != 0 {
+			v.printableBalance(fullQuote, quote, fullQuote.Total+changeOrdersTotal, payments)
 		}
 
-		for _, adjustmentId := range quote.AdjustmentIds {
-			adjustment, err := v.store.GetAdjustment(ctx, adjustmentId)
-			if err != nil {
-				return err
-			}
-			adjustments[adjustmentId] = adjustment
+		return nil
+	})
+}
+
+// GetPrintableRevision builds a printable quote from a revision, showing the
+// quote exactly as it was when the revision was made.
+func (v *priceyPrint) GetPrintableRevision(ctx context.Context, revisionId ID) (*PrintableQuote, error) {
+	fullQuote := &PrintableQuote{}
+	return fullQuote, v.store.Transaction(ctx, func(ctx context.Context) error {
+		revision, err := v.store.GetQuoteRevision(ctx, revisionId)
+		if err != nil {
+			return err
+		}
+		lineItems := map[ID]*LineItem{}
+		for _, li := range revision.LineItems {
+			lineItems[li.Id] = li
+		}
+		adjustments := map[ID]*Adjustment{}
+		for _, a := range revision.Adjustments {
+			adjustments[a.Id] = a
+		}
+		contacts := map[ID]*Contact{}
+		for _, c := range revision.Contacts {
+			contacts[c.Id] = c
+		}
+		images, err := v.loadImages(ctx, &revision.Quote, lineItems)
+		if err != nil {
+			return err
 		}
 
-		fullQuote = v.getPrintableQuote(quote, images, contacts, lineItems, adjustments)
+		fullQuote = v.getPrintableQuote(&revision.Quote, images, contacts, lineItems, adjustments, nil)
+		fullQuote.Revision = revision.Number
 
 		return nil
 	})
 }
 
-func (v *priceyPrint) getPrintableQuote(quote *Quote, images map[ID]*Image, contacts map[ID]*Contact, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) *PrintableQuote {
+// loadQuoteContacts fetches the quote's sender, bill to and ship to contacts.
+func loadQuoteContacts(ctx context.Context, store Store, quote *Quote) (map[ID]*Contact, error) {
+	contacts := map[ID]*Contact{}
+	for _, contactId := range []ID{quote.SenderId, quote.BillToId, quote.ShipToId} {
+		if contactId == "" || contacts[contactId] != nil {
+			continue
+		}
+		c, err := store.GetContact(ctx, contactId)
+		if err != nil {
+			return nil, err
+		}
+		contacts[contactId] = c
+	}
+	return contacts, nil
+}
+
+// loadImages looks up the urls of the quote's logo and line item images.
+func (v *priceyPrint) loadImages(ctx context.Context, quote *Quote, lineItems map[ID]*LineItem) (map[ID]*Image, error) {
+	images := map[ID]*Image{}
+	imageIds := []ID{}
+	if quote.LogoId != "" {
+		imageIds = append(imageIds, quote.LogoId)
+	}
+	for _, lineItemId := range quote.LineItemIds {
+		if lineItem := lineItems[lineItemId]; lineItem != nil && lineItem.ImageId != nil {
+			imageIds = append(imageIds, *lineItem.ImageId)
+		}
+	}
+	for _, imageId := range imageIds {
+		if images[imageId] != nil {
+			continue
+		}
+		imageUrl, err := v.store.GetImageUrl(ctx, imageId)
+		if err != nil {
+			return nil, err
+		}
+		if imageUrl != "" {
+			images[imageId] = &Image{Id: imageId, Url: imageUrl}
+		}
+	}
+	return images, nil
+}
+
+func (v *priceyPrint) getPrintableQuote(quote *Quote, images map[ID]*Image, contacts map[ID]*Contact, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment, payments []*Payment) *PrintableQuote {
 	q := &PrintableQuote{Id: quote.Id}
 	q.Code = quote.Code
 	q.OrderNumber = quote.OrderNumber
@@ -232,14 +306,14 @@ func (v *priceyPrint) getPrintableQuote(quote *Quote, images map[ID]*Image, cont
 	q.Notes = quote.Notes
 	q.BalanceDueOn = quote.BalanceDueOn
 	q.PayUrl = quote.PayUrl
-	q.Sent = quote.Sent
 	q.SentOn = quote.SentOn
-	q.Sold = quote.Sold
 	q.SoldOn = quote.SoldOn
 	q.Created = quote.Created
 	q.Updated = quote.Updated
 	q.Hidden = quote.Hidden
 	q.Locked = quote.Locked
+	q.Status = quoteStatus(quote)
+	q.Acceptance = quote.Acceptance
 
 	if quote.LogoId != "" {
 		q.Logo = images[quote.LogoId]
@@ -279,28 +353,102 @@ func (v *priceyPrint) getPrintableQuote(quote *Quote, images map[ID]*Image, cont
 		}
 	}
 
+	totals := calculateQuoteTotals(quote, lineItems, adjustments)
+	for _, item := range items {
+		item.fl.Amount = totals.LineItemAmounts[item.l.Id]
+		item.fl.Excluded = totals.Excluded[item.l.Id]
+	}
+	q.SubTotal = totals.SubTotal
+
+	// top level line items are split into the shared section and one section
+	// per option, each numbered from 1
+	sections := map[ID][]*PrintableLineItem{}
+	for _, fl := range q.LineItems {
+		optionId := lookup[fl.Id].l.OptionId
+		if quoteOption(quote, optionId) == nil {
+			optionId = ""
+		}
+		sections[optionId] = append(sections[optionId], fl)
+	}
+	q.LineItems = v.printableSection(lookup, sections[""])
+	for _, option := range quote.Options {
+		optionTotals := calculateOptionTotals(quote, lineItems, adjustments, option.Id)
+		q.Options = append(q.Options, &PrintableOption{
+			Id:          option.Id,
+			Name:        option.Name,
+			Description: option.Description,
+			LineItems:   v.printableSection(lookup, sections[option.Id]),
+			SubTotal:    optionTotals.SubTotal,
+			Adjustments: v.optionAdjustments(quote, adjustments, option.Id),
+			Total:       optionTotals.Total,
+			BalanceDue:  optionTotals.BalanceDue,
+			Chosen:      option.Id == quote.ChosenOptionId,
+		})
+	}
+
+	q.Adjustments = v.optionAdjustments(quote, adjustments, activeQuoteOptionId(quote))
+	q.Total = totals.Total
+	q.BalanceDue = totals.BalanceDue
+
+	v.printableBalance(q, quote, totals.Total, payments)
+	return q
+}
+
+// printableBalance applies the payments against total to the printable quote
+// and its payment schedule.
+func (v *priceyPrint) printableBalance(q *PrintableQuote, quote *Quote, total int, payments []*Payment) {
+	balance := quoteBalance(quote.Id, total, quote.PaymentSchedule, payments)
+	q.Payments = payments
+	q.AmountPaid = balance.Paid
+	q.RemainingBalance = balance.Outstanding
+	q.Overpaid = balance.Overpaid
+
+	q.PaymentSchedule = nil
+	due := firstDueMilestone(balance)
+	for i, mb := range balance.Milestones {
+		m := quote.PaymentSchedule[i]
+		pm := &PrintableMilestone{
+			Id:          m.Id,
+			Description: m.Description,
+			Amount:      mb.Amount,
+			Paid:        mb.Paid,
+			DueOn:       m.DueOn,
+			Trigger:     m.Trigger,
+			PayUrl:      m.PayUrl,
+			Due:         i == due,
+		}
+		if m.Type == AdjustmentTypePercent {
+			pm.Percent = m.Amount
+		}
+		if pm.PayUrl == "" {
+			pm.PayUrl = quote.PayUrl
+		}
+		q.PaymentSchedule = append(q.PaymentSchedule, pm)
+	}
+}
+
+// printableSection drops hidden line items and numbers the rest. Hidden line
+// items still count towards their parent and the subtotal, so they are only
+// dropped once the amounts are known.
+func (v *priceyPrint) printableSection(lookup map[ID]*item, items []*PrintableLineItem) []*PrintableLineItem {
+	items = v.withoutHidden(lookup, items, map[ID]bool{})
 	visited := map[ID]bool{}
-	for i, item := range q.LineItems {
-		q.SubTotal += v.findAmount(lookup, item, map[ID]bool{})
+	for i, item := range items {
 		v.calculateDepthAndNumber("", 0, i, item, visited)
 	}
+	return items
+}
 
-	q.Total = q.SubTotal
+// optionAdjustments returns the adjustments that apply to optionId in quote
+// order.
+func (v *priceyPrint) optionAdjustments(quote *Quote, adjustments map[ID]*Adjustment, optionId ID) []*Adjustment {
+	var applied []*Adjustment
 	for _, adjustmentId := range quote.AdjustmentIds {
-		a := adjustments[adjustmentId]
-		if a != nil {
-			q.Adjustments = append(q.Adjustments, a)
-			q.Total += adjustmentAmount(a, q.SubTotal)
+		if a := adjustments[adjustmentId]; a != nil && inQuoteOption(quote, a.OptionId, optionId) {
+			applied = append(applied, a)
 		}
 	}
-
-	if quote.BalanceDue != 0 {
-		q.BalanceDue = quote.BalanceDue
-	} else if quote.BalancePercentDue != 0 {
-		q.BalanceDue = q.Total * quote.BalancePercentDue / 100
-	}
-
-	return q
+	return applied
 }
 
 func (v *priceyPrint) getPrintableLineItem(lineItems map[ID]*LineItem, images map[ID]*Image, id ID) (*LineItem, *PrintableLineItem) {
@@ -315,6 +463,7 @@ func (v *priceyPrint) getPrintableLineItem(lineItems map[ID]*LineItem, images ma
 			Id:              l.Id,
 			Depth:           0,
 			Image:           i,
+			Name:            l.Name,
 			Description:     l.Description,
 			QuantityPrefix:  l.QuantityPrefix,
 			Quantity:        l.Quantity,
@@ -325,6 +474,10 @@ func (v *priceyPrint) getPrintableLineItem(lineItems map[ID]*LineItem, images ma
 			AmountPrefix:    l.AmountPrefix,
 			Amount:          0,
 			AmountSuffix:    l.AmountSuffix,
+			Optional:        l.Optional,
+			AlternateGroup:  l.AlternateGroup,
+			Selected:        l.Selected,
+			Removed:         l.RemovesLineItemId != "",
 			Created:         l.Created,
 			Updated:         l.Updated,
 		}
@@ -339,21 +492,20 @@ func (v *priceyPrint) getPrintableLineItem(lineItems map[ID]*LineItem, images ma
 	return l, fl
 }
 
-func (v *priceyPrint) findAmount(lookup map[ID]*item, item *PrintableLineItem, visited map[ID]bool) int {
-	visited[item.Id] = true
-	i := lookup[item.Id]
-	if i == nil {
-		return 0
-	}
-	if i.fl.AmountOverridden {
-		return item.Amount
-	}
-	for _, subItem := range item.SubItems {
-		if !visited[subItem.Id] {
-			item.Amount += v.findAmount(lookup, subItem, visited)
+func (v *priceyPrint) withoutHidden(lookup map[ID]*item, items []*PrintableLineItem, visited map[ID]bool) []*PrintableLineItem {
+	var visible []*PrintableLineItem
+	for _, item := range items {
+		if visited[item.Id] {
+			continue
+		}
+		visited[item.Id] = true
+		if i := lookup[item.Id]; i != nil && i.l.HideFromCustomer {
+			continue
 		}
+		item.SubItems = v.withoutHidden(lookup, item.SubItems, visited)
+		visible = append(visible, item)
 	}
-	return item.Amount
+	return visible
 }
 
 func (v *priceyPrint) calculateDepthAndNumber(parentNumber string, depth, index int, item *PrintableLineItem, visited map[ID]bool) {
@@ -395,6 +547,33 @@ func (v *priceyPrint) Standard(ctx context.Context, id ID, w io.Writer) error {
 	return nil
 }
 
+func (v *priceyPrint) StandardRevision(ctx context.Context, revisionId ID, w io.Writer) error {
+	q, err := v.GetPrintableRevision(ctx, revisionId)
+	if err != nil {
+		return err
+	}
+	buf := bytes.Buffer{}
+	err = v.standardTemplate.Execute(&buf, q)
+	if err != nil {
+		return err
+	}
+	resp, err := print(v.pdfClient, &buf)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	_, err = io.Copy(w, resp)
+	return err
+}
+
+func (v *priceyPrint) StandardRevisionHTML(ctx context.Context, revisionId ID, w io.Writer) error {
+	q, err := v.GetPrintableRevision(ctx, revisionId)
+	if err != nil {
+		return err
+	}
+	return v.standardTemplate.Execute(w, q)
+}
+
 func (v *priceyPrint) StandardHTML(ctx context.Context, id ID, w io.Writer) error {
 	q, err := v.GetPrintableQuote(ctx, id)
 	if err != nil {
@@ -402,3 +581,160 @@ func (v *priceyPrint) StandardHTML(ctx context.Context, id ID, w io.Writer) erro
 	}
 	return v.standardTemplate.Execute(w, q)
 }
+
+// GetPrintableInvoice builds a printable invoice from the invoice's own copy of
+// the quote.
+func (v *priceyPrint) GetPrintableInvoice(ctx context.Context, id ID) (*PrintableInvoice, error) {
+	printable := &PrintableInvoice{}
+	return printable, v.store.Transaction(ctx, func(ctx context.Context) error {
+		inv, err := v.store.GetInvoice(ctx, id)
+		if err != nil {
+			return err
+		}
+		quote, lineItems, _, _ := invoiceQuote(inv)
+		images, err := v.loadImages(ctx, quote, lineItems)
+		if err != nil {
+			return err
+		}
+
+		printable = v.getPrintableInvoice(inv, images)
+
+		return nil
+	})
+}
+
+func (v *priceyPrint) getPrintableInvoice(inv *Invoice, images map[ID]*Image) *PrintableInvoice {
+	quote, lineItems, adjustments, contacts := invoiceQuote(inv)
+	q := v.getPrintableQuote(quote, images, contacts, lineItems, adjustments, nil)
+	return &PrintableInvoice{
+		Id:                     inv.Id,
+		Number:                 inv.Number,
+		QuoteCode:              inv.QuoteCode,
+		OrderNumber:            inv.OrderNumber,
+		Logo:                   q.Logo,
+		PrimaryBackgroundColor: inv.PrimaryBackgroundColor,
+		PrimaryTextColor:       inv.PrimaryTextColor,
+		IssueDate:              inv.IssueDate,
+		DueDate:                inv.DueDate,
+		PaymentTerms:           inv.PaymentTerms,
+		Notes:                  inv.Notes,
+		Sender:                 q.Sender,
+		BillTo:                 q.BillTo,
+		ShipTo:                 q.ShipTo,
+		LineItems:              q.LineItems,
+		SubTotal:               q.SubTotal,
+		Adjustments:            q.Adjustments,
+		Total:                  q.Total,
+		PayUrl:                 inv.PayUrl,
+	}
+}
+
+func (v *priceyPrint) Invoice(ctx context.Context, id ID, w io.Writer) error {
+	inv, err := v.GetPrintableInvoice(ctx, id)
+	if err != nil {
+		return err
+	}
+	buf := bytes.Buffer{}
+	err = v.invoiceTemplate.Execute(&buf, inv)
+	if err != nil {
+		return err
+	}
+	resp, err := print(v.pdfClient, &buf)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	_, err = io.Copy(w, resp)
+	return err
+}
+
+func (v *priceyPrint) InvoiceHTML(ctx context.Context, id ID, w io.Writer) error {
+	inv, err := v.GetPrintableInvoice(ctx, id)
+	if err != nil {
+		return err
+	}
+	return v.invoiceTemplate.Execute(w, inv)
+}
+
+// GetPrintableChangeOrder builds a printable change order. Along with its own
+// line items it carries the original quote's code and the contract total
+// before and after the change, counting only the change orders accepted
+// before it.
+func (v *priceyPrint) GetPrintableChangeOrder(ctx context.Context, id ID) (*PrintableQuote, error) {
+	printable := &PrintableQuote{}
+	return printable, v.store.Transaction(ctx, func(ctx context.Context) error {
+		co, err := v.store.GetQuote(ctx, id)
+		if err != nil {
+			return err
+		}
+		if co.ChangeOrderOf == "" {
+			return NotChangeOrderError
+		}
+		original, err := v.store.GetQuote(ctx, co.ChangeOrderOf)
+		if err != nil {
+			return err
+		}
+		changeOrders, err := listChangeOrders(ctx, v.store, original.Id, false)
+		if err != nil {
+			return err
+		}
+		contacts, err := loadQuoteContacts(ctx, v.store, co)
+		if err != nil {
+			return err
+		}
+		lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, v.store, co)
+		if err != nil {
+			return err
+		}
+		images, err := v.loadImages(ctx, co, lineItems)
+		if err != nil {
+			return err
+		}
+
+		printable = v.getPrintableChangeOrder(co, original, changeOrders, images, contacts, lineItems, adjustments)
+
+		return nil
+	})
+}
+
+func (v *priceyPrint) getPrintableChangeOrder(co, original *Quote, changeOrders []*Quote, images map[ID]*Image, contacts map[ID]*Contact, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) *PrintableQuote {
+	earlier := []*Quote{}
+	for _, other := range changeOrders {
+		if other.ChangeOrderNumber < co.ChangeOrderNumber {
+			earlier = append(earlier, other)
+		}
+	}
+	q := v.getPrintableQuote(co, images, contacts, lineItems, adjustments, nil)
+	q.OriginalCode = original.Code
+	q.ChangeOrderNumber = co.ChangeOrderNumber
+	q.PreviousContractTotal = contractTotal(original, earlier).Total
+	q.ContractTotal = q.PreviousContractTotal + q.Total
+	return q
+}
+
+func (v *priceyPrint) ChangeOrder(ctx context.Context, id ID, w io.Writer) error {
+	co, err := v.GetPrintableChangeOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	buf := bytes.Buffer{}
+	err = v.changeOrderTemplate.Execute(&buf, co)
+	if err != nil {
+		return err
+	}
+	resp, err := print(v.pdfClient, &buf)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	_, err = io.Copy(w, resp)
+	return err
+}
+
+func (v *priceyPrint) ChangeOrderHTML(ctx context.Context, id ID, w io.Writer) error {
+	co, err := v.GetPrintableChangeOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	return v.changeOrderTemplate.Execute(w, co)
+}
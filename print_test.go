@@ -2,6 +2,7 @@ package pricey
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -11,6 +12,8 @@ import (
 	"time"
 
 	gotenberg "github.com/starwalkn/gotenberg-go-client/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestQuoteToPrintableQuote(t *testing.T) {
@@ -290,7 +293,7 @@ func TestQuoteToPrintableQuote(t *testing.T) {
 		return
 	}
 	p := newPrinter(nil, client)
-	qActual := p.getPrintableQuote(quote, images, contacts, lineItems, adjustments)
+	qActual := p.getPrintableQuote(quote, images, contacts, lineItems, adjustments, nil)
 
 	os.MkdirAll("tmp", os.ModePerm)
 	pdfExpected, err := os.Create("tmp/test_expected.pdf")
@@ -366,6 +369,297 @@ func TestQuoteToPrintableQuote(t *testing.T) {
 	}
 }
 
+func TestPrintableQuoteHidesLineItems(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	quote := &Quote{Id: "1", LineItemIds: []ID{"1", "2", "3", "4"}}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Name: "Water Heater", SubItemIds: []ID{"2", "3"}},
+		"2": {Id: "2", ParentId: s("1"), Quantity: 100, UnitPrice: 50000},
+		"3": {Id: "3", ParentId: s("1"), Quantity: 200, UnitPrice: 1000, HideFromCustomer: true},
+		"4": {Id: "4", Quantity: 100, UnitPrice: 700, HideFromCustomer: true},
+	}
+
+	q := (&priceyPrint{}).getPrintableQuote(quote, map[ID]*Image{}, map[ID]*Contact{}, lineItems, map[ID]*Adjustment{}, nil)
+	assert.Equal(t, 52700, q.SubTotal)
+	require.Len(t, q.LineItems, 1)
+	assert.Equal(t, "Water Heater", q.LineItems[0].Name)
+	assert.Equal(t, 52000, q.LineItems[0].Amount)
+	require.Len(t, q.LineItems[0].SubItems, 1)
+	assert.Equal(t, ID("2"), q.LineItems[0].SubItems[0].Id)
+	assert.Equal(t, "1.1", q.LineItems[0].SubItems[0].Number)
+}
+
+func TestPrintableQuoteOmitsCost(t *testing.T) {
+	quote := &Quote{Id: "1", LineItemIds: []ID{"1"}}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Name: "Labor", Quantity: 100, UnitPrice: 15000, UnitCost: 4321},
+	}
+
+	q := (&priceyPrint{}).getPrintableQuote(quote, map[ID]*Image{}, map[ID]*Contact{}, lineItems, map[ID]*Adjustment{}, nil)
+	buf := bytes.Buffer{}
+	require.NoError(t, newPrinter(nil, nil).standardTemplate.Execute(&buf, q))
+	assert.Contains(t, buf.String(), "150.00")
+	assert.NotContains(t, buf.String(), "43.21")
+
+	buf.Reset()
+	require.NoError(t, json.NewEncoder(&buf).Encode(q))
+	assert.NotContains(t, buf.String(), "4321", "printable quotes are customer facing")
+}
+
+func TestPrintableQuoteExcludesUnselectedOptions(t *testing.T) {
+	quote := &Quote{Id: "1", LineItemIds: []ID{"1", "2", "3", "4"}}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Name: "Water Heater", Quantity: 100, UnitPrice: 50000},
+		"2": {Id: "2", Name: "Expansion Tank", Optional: true, Quantity: 100, UnitPrice: 9000},
+		"3": {Id: "3", Name: "Standard Warranty", AlternateGroup: "warranty", Quantity: 100, UnitPrice: 0},
+		"4": {Id: "4", Name: "Extended Warranty", AlternateGroup: "warranty", Selected: true, Quantity: 100, UnitPrice: 15000},
+	}
+
+	q := (&priceyPrint{}).getPrintableQuote(quote, map[ID]*Image{}, map[ID]*Contact{}, lineItems, map[ID]*Adjustment{}, nil)
+	assert.Equal(t, 65000, q.SubTotal)
+	assert.Equal(t, 65000, q.Total)
+	require.Len(t, q.LineItems, 4)
+	assert.True(t, q.LineItems[1].Optional)
+	assert.True(t, q.LineItems[1].Excluded)
+	assert.Equal(t, 9000, q.LineItems[1].Amount)
+	assert.True(t, q.LineItems[2].Excluded)
+	assert.False(t, q.LineItems[3].Excluded)
+	assert.Equal(t, "warranty", q.LineItems[3].AlternateGroup)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, newPrinter(nil, nil).standardTemplate.Execute(&buf, q))
+	assert.Contains(t, buf.String(), "Optional")
+	assert.Contains(t, buf.String(), `textAlignRight light">90.00`)
+}
+
+func TestPrintableQuoteOptions(t *testing.T) {
+	quote := &Quote{
+		Id:            "1",
+		LineItemIds:   []ID{"1", "2", "3", "4"},
+		AdjustmentIds: []ID{"1"},
+		Options:       []QuoteOption{{Id: "good", Name: "Good"}, {Id: "best", Name: "Best"}},
+	}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Name: "Haul Away", Quantity: 100, UnitPrice: 15000},
+		"2": {Id: "2", Name: "Standard Furnace", OptionId: "good", Quantity: 100, UnitPrice: 300000},
+		"3": {Id: "3", Name: "Variable Speed Furnace", OptionId: "best", Quantity: 100, UnitPrice: 550000},
+		"4": {Id: "4", Name: "Smart Thermostat", OptionId: "best", Quantity: 100, UnitPrice: 35000},
+	}
+	adjustments := map[ID]*Adjustment{
+		"1": {Id: "1", Description: "Rebate", OptionId: "best", Type: AdjustmentTypeFlat, Amount: -50000},
+	}
+
+	q := (&priceyPrint{}).getPrintableQuote(quote, map[ID]*Image{}, map[ID]*Contact{}, lineItems, adjustments, nil)
+	require.Len(t, q.LineItems, 1)
+	assert.Equal(t, "Haul Away", q.LineItems[0].Name)
+	require.Len(t, q.Options, 2)
+	assert.Equal(t, "Good", q.Options[0].Name)
+	require.Len(t, q.Options[0].LineItems, 1)
+	assert.Equal(t, "1", q.Options[0].LineItems[0].Number)
+	assert.Empty(t, q.Options[0].Adjustments)
+	assert.Equal(t, 315000, q.Options[0].Total)
+	require.Len(t, q.Options[1].LineItems, 2)
+	assert.Equal(t, "2", q.Options[1].LineItems[1].Number)
+	require.Len(t, q.Options[1].Adjustments, 1)
+	assert.Equal(t, 600000, q.Options[1].SubTotal)
+	assert.Equal(t, 550000, q.Options[1].Total)
+	// priced as the first option until one is chosen
+	assert.Equal(t, 315000, q.Total)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, newPrinter(nil, nil).standardTemplate.Execute(&buf, q))
+	assert.Contains(t, buf.String(), "Variable Speed Furnace")
+	assert.Contains(t, buf.String(), "$5,500.00")
+}
+
+func TestPrintableQuoteSignature(t *testing.T) {
+	signedAt := time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC)
+	quote := &Quote{Id: "1", Acceptance: &QuoteAcceptance{
+		Signature:   Signature{Name: "Jane Doe", Title: "Owner", IP: "203.0.113.7", SignedAt: signedAt},
+		ContentHash: "abc123",
+	}}
+
+	q := (&priceyPrint{}).getPrintableQuote(quote, map[ID]*Image{}, map[ID]*Contact{}, map[ID]*LineItem{}, map[ID]*Adjustment{}, nil)
+	require.NotNil(t, q.Acceptance)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, newPrinter(nil, nil).standardTemplate.Execute(&buf, q))
+	assert.Contains(t, buf.String(), "Jane Doe")
+	assert.Contains(t, buf.String(), "May 1, 2024 2:30 PM UTC")
+	assert.Contains(t, buf.String(), "abc123")
+}
+
+func TestPrintablePaymentSchedule(t *testing.T) {
+	dueOn := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	quote := &Quote{
+		Id:          "1",
+		LineItemIds: []ID{"1"},
+		PayUrl:      "https://pay.example.com/q/1",
+		PaymentSchedule: []PaymentMilestone{
+			{Id: "1", Description: "Deposit", Type: AdjustmentTypePercent, Amount: 30, DueOn: &dueOn},
+			{Id: "2", Description: "Rough-in", Type: AdjustmentTypePercent, Amount: 40, Trigger: "rough-in inspection passed"},
+			{Id: "3", Description: "Completion", Type: AdjustmentTypeFlat, Amount: 300000, PayUrl: "https://pay.example.com/q/1/final"},
+		},
+	}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Quantity: 100, UnitPrice: 1000000},
+	}
+
+	q := (&priceyPrint{}).getPrintableQuote(quote, map[ID]*Image{}, map[ID]*Contact{}, lineItems, map[ID]*Adjustment{}, nil)
+	require.Len(t, q.PaymentSchedule, 3)
+	assert.Equal(t, 300000, q.PaymentSchedule[0].Amount)
+	assert.Equal(t, 30, q.PaymentSchedule[0].Percent)
+	assert.True(t, q.PaymentSchedule[0].Due)
+	assert.Equal(t, "https://pay.example.com/q/1", q.PaymentSchedule[0].PayUrl)
+	assert.Equal(t, 400000, q.PaymentSchedule[1].Amount)
+	assert.False(t, q.PaymentSchedule[1].Due)
+	assert.Equal(t, 0, q.PaymentSchedule[2].Percent)
+	assert.Equal(t, "https://pay.example.com/q/1/final", q.PaymentSchedule[2].PayUrl)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, newPrinter(nil, nil).standardTemplate.Execute(&buf, q))
+	assert.Contains(t, buf.String(), "Payment Schedule")
+	assert.Contains(t, buf.String(), "June 1, 2024")
+	assert.Contains(t, buf.String(), "rough-in inspection passed")
+	assert.Contains(t, buf.String(), "Pay Deposit")
+	assert.NotContains(t, buf.String(), "Pay Here")
+}
+
+func TestPrintablePayments(t *testing.T) {
+	receivedOn := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	quote := &Quote{
+		Id:          "1",
+		LineItemIds: []ID{"1"},
+		PayUrl:      "https://pay.example.com/q/1",
+		PaymentSchedule: []PaymentMilestone{
+			{Id: "1", Description: "Deposit", Type: AdjustmentTypePercent, Amount: 50},
+			{Id: "2", Description: "Completion", Type: AdjustmentTypePercent, Amount: 50},
+		},
+	}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Quantity: 100, UnitPrice: 100000},
+	}
+	payments := []*Payment{
+		{Id: "1", Amount: 60000, Method: PaymentMethodCheck, Reference: "1042", ReceivedOn: receivedOn},
+		{Id: "2", Amount: 5000, Refund: true, Method: PaymentMethodCheck, ReceivedOn: receivedOn},
+	}
+
+	q := (&priceyPrint{}).getPrintableQuote(quote, map[ID]*Image{}, map[ID]*Contact{}, lineItems, map[ID]*Adjustment{}, payments)
+	assert.Equal(t, 55000, q.AmountPaid)
+	assert.Equal(t, 45000, q.RemainingBalance)
+	assert.Equal(t, 0, q.Overpaid)
+	require.Len(t, q.PaymentSchedule, 2)
+	assert.Equal(t, 50000, q.PaymentSchedule[0].Paid)
+	assert.False(t, q.PaymentSchedule[0].Due)
+	assert.Equal(t, 5000, q.PaymentSchedule[1].Paid)
+	assert.True(t, q.PaymentSchedule[1].Due)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, newPrinter(nil, nil).standardTemplate.Execute(&buf, q))
+	assert.Contains(t, buf.String(), "Payment 6/3/24 (check 1042)")
+	assert.Contains(t, buf.String(), "-$600.00")
+	assert.Contains(t, buf.String(), "Refund 6/3/24 (check)")
+	assert.Contains(t, buf.String(), "Remaining Balance</td><td class=\"pxl\">$450.00")
+	assert.Contains(t, buf.String(), "Pay Completion")
+	assert.NotContains(t, buf.String(), "Pay Deposit")
+
+	payments = append(payments, &Payment{Id: "3", Amount: 50000, ReceivedOn: receivedOn})
+	q = (&priceyPrint{}).getPrintableQuote(quote, map[ID]*Image{}, map[ID]*Contact{}, lineItems, map[ID]*Adjustment{}, payments)
+	assert.Equal(t, 0, q.RemainingBalance)
+	assert.Equal(t, 5000, q.Overpaid)
+	buf.Reset()
+	require.NoError(t, newPrinter(nil, nil).standardTemplate.Execute(&buf, q))
+	assert.Contains(t, buf.String(), "Credit Due</td><td class=\"pxl\">$50.00")
+	assert.NotContains(t, buf.String(), "Pay Completion")
+}
+
+func TestPrintableInvoice(t *testing.T) {
+	dueDate := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	inv := &Invoice{
+		Id:           "1",
+		Number:       "INV-00003",
+		QuoteCode:    "EST-7",
+		IssueDate:    time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		DueDate:      &dueDate,
+		PaymentTerms: "Net 30",
+		BillToId:     "c1",
+		PayUrl:       "https://pay.example.com/inv/1",
+		LineItems: []*LineItem{
+			{Id: "1", Description: "Labor", Quantity: 100, UnitPrice: 30000, SubItemIds: []ID{}},
+			{Id: "2", Description: "Hidden", Quantity: 100, UnitPrice: 1000, HideFromCustomer: true, SubItemIds: []ID{}},
+		},
+		Adjustments: []*Adjustment{{Id: "tax", Description: "Tax", Type: AdjustmentTypePercent, Amount: 10}},
+		Contacts:    []*Contact{{Id: "c1", Name: "Dana"}},
+	}
+
+	q := (&priceyPrint{}).getPrintableInvoice(inv, map[ID]*Image{})
+	assert.Equal(t, "INV-00003", q.Number)
+	assert.Equal(t, "Dana", q.BillTo.Name)
+	require.Len(t, q.LineItems, 1)
+	assert.Equal(t, 31000, q.SubTotal)
+	assert.Equal(t, 34100, q.Total)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, newPrinter(nil, nil).invoiceTemplate.Execute(&buf, q))
+	assert.Contains(t, buf.String(), "Invoice # </td><td class=\"\">INV-00003")
+	assert.Contains(t, buf.String(), "Estimate # </td><td class=\"\">EST-7")
+	assert.Contains(t, buf.String(), "July 1, 2024")
+	assert.Contains(t, buf.String(), "Amount Due</td><td class=\"pxl\">$341.00")
+	assert.Contains(t, buf.String(), "Pay Here")
+	assert.NotContains(t, buf.String(), "Hidden")
+}
+
+func TestPrintableChangeOrder(t *testing.T) {
+	amount := -30000
+	original := &Quote{Id: "q", Code: "EST-7", Total: 200000, Status: QuoteStatusConverted}
+	changeOrders := []*Quote{
+		{Id: "co1", ChangeOrderOf: "q", ChangeOrderNumber: 1, Total: 50000, Status: QuoteStatusAccepted},
+		{Id: "co3", ChangeOrderOf: "q", ChangeOrderNumber: 3, Total: 70000, Status: QuoteStatusAccepted},
+	}
+	co := &Quote{
+		Id:                "co2",
+		Code:              "EST-7-CO2",
+		ChangeOrderOf:     "q",
+		ChangeOrderNumber: 2,
+		Status:            QuoteStatusSent,
+		LineItemIds:       []ID{"1", "2"},
+		AdjustmentIds:     []ID{},
+	}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", Description: "Extra outlet", Quantity: 200, UnitPrice: 7500, SubItemIds: []ID{}},
+		"2": {Id: "2", Description: "Ceiling fan", Amount: &amount, RemovesLineItemId: "x", SubItemIds: []ID{}},
+	}
+
+	q := (&priceyPrint{}).getPrintableChangeOrder(co, original, changeOrders, map[ID]*Image{}, map[ID]*Contact{}, lineItems, map[ID]*Adjustment{})
+	assert.Equal(t, "EST-7", q.OriginalCode)
+	assert.Equal(t, 2, q.ChangeOrderNumber)
+	assert.Equal(t, -15000, q.Total)
+	assert.Equal(t, 250000, q.PreviousContractTotal, "only earlier accepted change orders count")
+	assert.Equal(t, 235000, q.ContractTotal)
+	require.Len(t, q.LineItems, 2)
+	assert.False(t, q.LineItems[0].Removed)
+	assert.True(t, q.LineItems[1].Removed)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, newPrinter(nil, nil).changeOrderTemplate.Execute(&buf, q))
+	assert.Contains(t, buf.String(), "Change Order # </td><td class=\"\">2")
+	assert.Contains(t, buf.String(), "Original Estimate # </td><td class=\"\">EST-7")
+	assert.Contains(t, buf.String(), "Removed from the original estimate")
+	assert.Contains(t, buf.String(), "-300.00")
+	assert.Contains(t, buf.String(), "Previous Contract Total</td><td class=\"pxl\">$2,500.00")
+	assert.Contains(t, buf.String(), "New Contract Total</td><td class=\"pxl\">$2,350.00")
+}
+
+func TestPennies(t *testing.T) {
+	assert.Equal(t, "", pennies(0))
+	assert.Equal(t, "1,234.56", pennies(123456))
+	assert.Equal(t, "-1.50", pennies(-150))
+	assert.Equal(t, "-123.00", pennies(-12300))
+	assert.Equal(t, "-1,234.56", pennies(-123456))
+}
+
 func TestPrintableQuotePDF(t *testing.T) {
 	if !isPortInUse(3000) {
 		t.Skipf("Gotenberg port was not in use, likely gotenberg is not running, skipping")
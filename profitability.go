@@ -0,0 +1,118 @@
+package pricey
+
+// Profit compares what something costs the business with what the customer
+// pays for it. Margin is the gross margin as a percentage of the price and
+// Markup the gross margin as a percentage of the cost; each is 0 when there is
+// nothing to divide by.
+type Profit struct {
+	Cost        int     `json:"cost" firestore:"cost"`
+	Price       int     `json:"price" firestore:"price"`
+	GrossMargin int     `json:"grossMargin" firestore:"grossMargin"`
+	Margin      float64 `json:"margin" firestore:"margin"`
+	Markup      float64 `json:"markup" firestore:"markup"`
+}
+
+// LineItemProfit is the profit on one line item of a quote.
+type LineItemProfit struct {
+	LineItemId  ID     `json:"lineItemId" firestore:"lineItemId"`
+	ParentId    *ID    `json:"parentId" firestore:"parentId"`
+	Depth       int    `json:"depth" firestore:"depth"`
+	Name        string `json:"name" firestore:"name"`
+	Description string `json:"description" firestore:"description"`
+	Quantity    int    `json:"quantity" firestore:"quantity"`
+	UnitCost    int    `json:"unitCost" firestore:"unitCost"`
+	UnitPrice   int    `json:"unitPrice" firestore:"unitPrice"`
+	Profit      Profit `json:"profit" firestore:"profit"`
+}
+
+// QuoteProfitability is the internal profitability report for a quote. It is
+// never part of the printable quote.
+type QuoteProfitability struct {
+	QuoteId ID `json:"quoteId" firestore:"quoteId"`
+	// LineItems every line item counted in the total, depth first
+	LineItems []*LineItemProfit `json:"lineItems" firestore:"lineItems"`
+	// Groups the top level line items, each covering the line items beneath it
+	Groups []*LineItemProfit `json:"groups" firestore:"groups"`
+	// SubTotal the groups together, before adjustments
+	SubTotal Profit `json:"subTotal" firestore:"subTotal"`
+	// Adjustments sum of the discounts, fees and taxes
+	Adjustments int `json:"adjustments" firestore:"adjustments"`
+	// Total the whole quote after adjustments
+	Total Profit `json:"total" firestore:"total"`
+}
+
+func newProfit(cost, price int) Profit {
+	p := Profit{Cost: cost, Price: price, GrossMargin: price - cost}
+	if price != 0 {
+		p.Margin = float64(p.GrossMargin) * 100 / float64(price)
+	}
+	if cost != 0 {
+		p.Markup = float64(p.GrossMargin) * 100 / float64(cost)
+	}
+	return p
+}
+
+// quoteProfitability reports the profit on each line item counted in the
+// quote's total (see calculateQuoteTotals), on each top level line item, and on
+// the quote as a whole. A line item's cost is UnitCost * Quantity, or UnitCost
+// alone for a lump sum without a quantity. A line item without a cost of its
+// own, such as a bundle priced from the pricebook, costs what its counted sub
+// line items cost.
+func quoteProfitability(quote *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) *QuoteProfitability {
+	totals := calculateQuoteTotals(quote, lineItems, adjustments)
+	optionId := activeQuoteOptionId(quote)
+	report := &QuoteProfitability{
+		QuoteId:   quote.Id,
+		LineItems: []*LineItemProfit{},
+		Groups:    []*LineItemProfit{},
+	}
+
+	visited := map[ID]bool{}
+	var walk func(l *LineItem, depth int) int
+	walk = func(l *LineItem, depth int) int {
+		visited[l.Id] = true
+		line := &LineItemProfit{
+			LineItemId:  l.Id,
+			ParentId:    l.ParentId,
+			Depth:       depth,
+			Name:        l.Name,
+			Description: l.Description,
+			Quantity:    l.Quantity,
+			UnitCost:    l.UnitCost,
+			UnitPrice:   l.UnitPrice,
+		}
+		report.LineItems = append(report.LineItems, line)
+		subCost := 0
+		for _, subItemId := range lineItemChildren(quote, lineItems, &l.Id) {
+			if visited[subItemId] || totals.Excluded[subItemId] {
+				continue
+			}
+			subCost += walk(lineItems[subItemId], depth+1)
+		}
+		cost := subCost
+		if l.UnitCost != 0 {
+			cost = l.UnitCost
+			if l.Quantity > 0 {
+				cost = l.UnitCost * l.Quantity / 100
+			}
+		}
+		line.Profit = newProfit(cost, totals.LineItemAmounts[l.Id])
+		return cost
+	}
+
+	cost := 0
+	for _, lineItemId := range lineItemChildren(quote, lineItems, nil) {
+		l := lineItems[lineItemId]
+		if visited[lineItemId] || totals.Excluded[lineItemId] || !inQuoteOption(quote, l.OptionId, optionId) {
+			continue
+		}
+		at := len(report.LineItems)
+		cost += walk(l, 0)
+		report.Groups = append(report.Groups, report.LineItems[at])
+	}
+
+	report.SubTotal = newProfit(cost, totals.SubTotal)
+	report.Adjustments = totals.Total - totals.SubTotal
+	report.Total = newProfit(cost, totals.Total)
+	return report
+}
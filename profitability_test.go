@@ -0,0 +1,90 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProfit(t *testing.T) {
+	testCases := []struct {
+		name   string
+		cost   int
+		price  int
+		margin float64
+		markup float64
+	}{
+		{name: "profit", cost: 6000, price: 10000, margin: 40, markup: 66.66666666666667},
+		{name: "loss", cost: 12000, price: 10000, margin: -20, markup: -16.666666666666668},
+		{name: "no cost", cost: 0, price: 10000, margin: 100, markup: 0},
+		{name: "no price", cost: 5000, price: 0, margin: 0, markup: -100},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := newProfit(tc.cost, tc.price)
+			assert.Equal(t, tc.price-tc.cost, p.GrossMargin)
+			assert.InDelta(t, tc.margin, p.Margin, 0.0001)
+			assert.InDelta(t, tc.markup, p.Markup, 0.0001)
+		})
+	}
+}
+
+func TestQuoteProfitability(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	permit := 10000
+	quote := &Quote{
+		Id:            "q",
+		LineItemIds:   []ID{"1", "2", "3", "4", "5", "6"},
+		AdjustmentIds: []ID{"discount"},
+	}
+	lineItems := map[ID]*LineItem{
+		// 2 hours at $150, costing $60 an hour
+		"1": {Id: "1", Name: "Labor", Quantity: 200, UnitPrice: 15000, UnitCost: 6000},
+		// bundle without a cost of its own
+		"2": {Id: "2", Name: "Water heater", Quantity: 100, UnitPrice: 120000, SubItemIds: []ID{"3", "4"}},
+		"3": {Id: "3", ParentId: s("2"), Quantity: 100, UnitPrice: 90000, UnitCost: 60000},
+		"4": {Id: "4", ParentId: s("2"), Quantity: 300, UnitPrice: 2000, UnitCost: 500},
+		// lump sum
+		"5": {Id: "5", Name: "Permit", Amount: &permit, UnitCost: 8000},
+		// not chosen, left out
+		"6": {Id: "6", Optional: true, Quantity: 100, UnitPrice: 50000, UnitCost: 10000},
+	}
+	adjustments := map[ID]*Adjustment{
+		"discount": {Id: "discount", Description: "Discount", Type: AdjustmentTypeFlat, Amount: -10000},
+	}
+
+	report := quoteProfitability(quote, lineItems, adjustments)
+	ids := []ID{}
+	for _, l := range report.LineItems {
+		ids = append(ids, l.LineItemId)
+	}
+	assert.Equal(t, []ID{"1", "2", "3", "4", "5"}, ids)
+	require.Len(t, report.Groups, 3)
+
+	labor := report.Groups[0]
+	assert.Equal(t, Profit{Cost: 12000, Price: 30000, GrossMargin: 18000, Margin: 60, Markup: 150}, labor.Profit)
+
+	heater := report.Groups[1]
+	assert.Equal(t, 61500, heater.Profit.Cost, "a bundle costs what its sub items cost")
+	assert.Equal(t, 120000, heater.Profit.Price)
+	assert.Equal(t, 1, report.LineItems[2].Depth)
+	assert.Equal(t, 1500, report.LineItems[3].Profit.Cost)
+
+	lumpSum := report.Groups[2]
+	assert.Equal(t, 8000, lumpSum.Profit.Cost, "a lump sum costs its unit cost")
+	assert.Equal(t, 2000, lumpSum.Profit.GrossMargin)
+
+	assert.Equal(t, 81500, report.SubTotal.Cost)
+	assert.Equal(t, 160000, report.SubTotal.Price)
+	assert.Equal(t, -10000, report.Adjustments)
+	assert.Equal(t, 81500, report.Total.Cost)
+	assert.Equal(t, 150000, report.Total.Price)
+	assert.Equal(t, 68500, report.Total.GrossMargin)
+}
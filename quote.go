@@ -1,60 +1,395 @@
 package pricey
 
 import (
+	"errors"
 	"time"
 )
 
+var (
+	LineItemNotOnQuoteError       = errors.New("line item does not belong to the quote")
+	InvalidQuoteStatusError       = errors.New("quote cannot move to that status from its current status")
+	UnlockReasonRequiredError     = errors.New("a reason is required to unlock a quote")
+	RevisionQuoteMismatchError    = errors.New("revisions belong to different quotes")
+	LineItemCycleError            = errors.New("line item cannot be moved beneath itself")
+	QuoteNumberPatternError       = errors.New("quote number pattern must contain a {N} counter")
+	QuoteNumberAssignmentError    = errors.New("quote numbers must be assigned on create or send")
+	InvalidQuoteSortError         = errors.New("quotes cannot be sorted by that field")
+	LineItemNotSelectableError    = errors.New("line item is neither optional nor an alternate")
+	AlternateSelectionError       = errors.New("only one line item may be selected from an alternate group")
+	QuoteOptionNotFoundError      = errors.New("option does not belong to the quote")
+	QuoteOptionRequiredError      = errors.New("an option must be chosen before the quote is accepted")
+	LineItemOptionError           = errors.New("only top level line items can be placed in an option")
+	SignatureRequiredError        = errors.New("a signer name is required to accept a quote")
+	InvalidPaymentMilestoneError  = errors.New("payment milestones need a positive amount and at most 100 percent")
+	PaymentScheduleTotalError     = errors.New("payment schedule must add up to the quote total")
+	InvalidPaymentAmountError     = errors.New("payment amount must be positive")
+	PaymentMilestoneNotFoundError = errors.New("milestone is not on the quote's payment schedule")
+	RefundExceedsPaymentsError    = errors.New("a refund cannot be more than has been paid")
+	QuoteNotAcceptedError         = errors.New("only accepted quotes can be invoiced")
+	InvoiceScopeError             = errors.New("a quote is invoiced either by line items or by payment milestones")
+	InvoiceLineItemError          = errors.New("only top level line items counted in the quote's total can be invoiced")
+	AlreadyInvoicedError          = errors.New("line item or milestone has already been invoiced")
+	ChangeOrderNotSoldError       = errors.New("change orders can only be made to accepted quotes")
+	NotChangeOrderError           = errors.New("quote is not a change order")
+	AlreadyRemovedError           = errors.New("line item has already been removed by a change order")
+	ChangeOrderLineItemError      = errors.New("only top level line items counted in the original quote's total can be removed")
+	QuoteExpiredError             = errors.New("quote has expired and must be re-issued before it is accepted")
+	QuoteNotExpiredError          = errors.New("only expired quotes can be re-issued")
+	ReissueExpirationDateError    = errors.New("a re-issued quote must expire in the future")
+	InvalidQuotePolicyError       = errors.New("policy limits must be percentages between 0 and 100")
+	ApproverRoleError             = errors.New("role is not allowed to approve quotes for the group")
+	NoApprovalRequestError        = errors.New("quote has no pending approval request")
+)
+
 type Quote struct {
-	Id                     ID         `json:"id" firestore:"id" firestore:"id"`
-	Code                   string     `json:"code" firestore:"code" firestore:"code"`
-	OrderNumber            string     `json:"orderNumber" firestore:"orderNumber" firestore:"orderNumber"`
-	LogoId                 ID         `json:"logoId" firestore:"logoId" firestore:"logoId"`
-	PrimaryBackgroundColor string     `json:"primaryBackgroundColor" firestore:"primaryBackgroundColor" firestore:"primaryBackgroundColor"`
-	PrimaryTextColor       string     `json:"primaryTextColor" firestore:"primaryTextColor" firestore:"primaryTextColor"`
-	IssueDate              *time.Time `json:"issueDate" firestore:"issueDate" firestore:"issueDate"`
-	ExpirationDate         *time.Time `json:"expirationDate" firestore:"expirationDate" firestore:"expirationDate"`
-	PaymentTerms           string     `json:"paymentTerms" firestore:"paymentTerms" firestore:"paymentTerms"`
-	Notes                  string     `json:"notes" firestore:"notes" firestore:"notes"`
-	SenderId               ID         `json:"senderId" firestore:"senderId" firestore:"senderId"`
-	BillToId               ID         `json:"billToId" firestore:"billToId" firestore:"billToId"`
-	ShipToId               ID         `json:"shipToId" firestore:"shipToId" firestore:"shipToId"`
-	LineItemIds            []ID       `json:"lineItemIds" firestore:"lineItemIds" firestore:"lineItemIds"`
-	SubTotal               int        `json:"subTotal" firestore:"subTotal" firestore:"subTotal"`
-	AdjustmentIds          []ID       `json:"adjustmentsIds" firestore:"adjustmentsIds" firestore:"adjustmentsIds"`
-	Total                  int        `json:"total" firestore:"total" firestore:"total"`
-	BalanceDue             int        `json:"balanceDue" firestore:"balanceDue" firestore:"balanceDue"`
-	BalancePercentDue      int        `json:"balancePercentDue" firestore:"balancePercentDue" firestore:"balancePercentDue"`
-	BalanceDueOn           *time.Time `json:"balanceDueOn" firestore:"balanceDueOn" firestore:"balanceDueOn"`
-	PayUrl                 string     `json:"payUrl" firestore:"payUrl" firestore:"payUrl"`
-	Sent                   bool       `json:"sent" firestore:"sent" firestore:"sent"`
-	SentOn                 *time.Time `json:"sentOn" firestore:"sentOn" firestore:"sentOn"`
-	Sold                   bool       `json:"sold" firestore:"sold" firestore:"sold"`
-	SoldOn                 *time.Time `json:"soldOn" firestore:"soldOn" firestore:"soldOn"`
-	Created                time.Time  `json:"created" firestore:"created" firestore:"created"`
-	Updated                time.Time  `json:"updated" firestore:"updated" firestore:"updated"`
-	Hidden                 bool       `json:"hidden" firestore:"hidden" firestore:"hidden"`
-	Locked                 bool       `json:"locked" firestore:"locked" firestore:"locked"`
+	Id                     ID                  `json:"id" firestore:"id" firestore:"id"`
+	Code                   string              `json:"code" firestore:"code" firestore:"code"`
+	OrderNumber            string              `json:"orderNumber" firestore:"orderNumber" firestore:"orderNumber"`
+	LogoId                 ID                  `json:"logoId" firestore:"logoId" firestore:"logoId"`
+	PrimaryBackgroundColor string              `json:"primaryBackgroundColor" firestore:"primaryBackgroundColor" firestore:"primaryBackgroundColor"`
+	PrimaryTextColor       string              `json:"primaryTextColor" firestore:"primaryTextColor" firestore:"primaryTextColor"`
+	IssueDate              *time.Time          `json:"issueDate" firestore:"issueDate" firestore:"issueDate"`
+	ExpirationDate         *time.Time          `json:"expirationDate" firestore:"expirationDate" firestore:"expirationDate"`
+	PaymentTerms           string              `json:"paymentTerms" firestore:"paymentTerms" firestore:"paymentTerms"`
+	Notes                  string              `json:"notes" firestore:"notes" firestore:"notes"`
+	SenderId               ID                  `json:"senderId" firestore:"senderId" firestore:"senderId"`
+	BillToId               ID                  `json:"billToId" firestore:"billToId" firestore:"billToId"`
+	ShipToId               ID                  `json:"shipToId" firestore:"shipToId" firestore:"shipToId"`
+	LineItemIds            []ID                `json:"lineItemIds" firestore:"lineItemIds" firestore:"lineItemIds"`
+	SubTotal               int                 `json:"subTotal" firestore:"subTotal" firestore:"subTotal"`
+	AdjustmentIds          []ID                `json:"adjustmentsIds" firestore:"adjustmentsIds" firestore:"adjustmentsIds"`
+	Total                  int                 `json:"total" firestore:"total" firestore:"total"`
+	BalanceDue             int                 `json:"balanceDue" firestore:"balanceDue" firestore:"balanceDue"`
+	BalancePercentDue      int                 `json:"balancePercentDue" firestore:"balancePercentDue" firestore:"balancePercentDue"`
+	BalanceDueOn           *time.Time          `json:"balanceDueOn" firestore:"balanceDueOn" firestore:"balanceDueOn"`
+	PayUrl                 string              `json:"payUrl" firestore:"payUrl" firestore:"payUrl"`
+	SentOn                 *time.Time          `json:"sentOn" firestore:"sentOn" firestore:"sentOn"`
+	SoldOn                 *time.Time          `json:"soldOn" firestore:"soldOn" firestore:"soldOn"`
+	Created                time.Time           `json:"created" firestore:"created" firestore:"created"`
+	Updated                time.Time           `json:"updated" firestore:"updated" firestore:"updated"`
+	Hidden                 bool                `json:"hidden" firestore:"hidden" firestore:"hidden"`
+	Locked                 bool                `json:"locked" firestore:"locked" firestore:"locked"`
+	Status                 QuoteStatus         `json:"status" firestore:"status"`
+	StatusHistory          []QuoteStatusChange `json:"statusHistory" firestore:"statusHistory"`
+	UnlockHistory          []QuoteUnlock       `json:"unlockHistory" firestore:"unlockHistory"`
+	CreatedBy              ID                  `json:"createdBy" firestore:"createdBy"`
+	CustomerId             ID                  `json:"customerId" firestore:"customerId"`
+	Options                []QuoteOption       `json:"options" firestore:"options"`
+	ChosenOptionId         ID                  `json:"chosenOptionId" firestore:"chosenOptionId"`
+	Acceptance             *QuoteAcceptance    `json:"acceptance" firestore:"acceptance"`
+	PaymentSchedule        []PaymentMilestone  `json:"paymentSchedule" firestore:"paymentSchedule"`
+	// ChangeOrderOf the original quote when this quote is a change order to it
+	ChangeOrderOf ID `json:"changeOrderOf" firestore:"changeOrderOf"`
+	// ChangeOrderNumber counts up from 1 across the original quote's change orders
+	ChangeOrderNumber int `json:"changeOrderNumber" firestore:"changeOrderNumber"`
+	// Approvals requests to send or accept the quote despite the group's pricing policy, oldest first
+	Approvals []QuoteApproval `json:"approvals" firestore:"approvals"`
+	// ExpiryLocked the expiration sweep locked the quote, so re-issuing it unlocks it again
+	ExpiryLocked bool `json:"expiryLocked" firestore:"expiryLocked"`
+	// DiscountRole role of whoever last changed the quote's adjustments, whose discount limit the quote is held to
+	DiscountRole Role `json:"discountRole" firestore:"discountRole"`
+}
+
+// PaymentMilestone is one payment in a quote's payment schedule, due on a date
+// or when a trigger such as "rough-in complete" is reached.
+type PaymentMilestone struct {
+	Id          ID     `json:"id" firestore:"id"`
+	Description string `json:"description" firestore:"description"`
+	// Type AdjustmentTypePercent when Amount is a percentage of the total, otherwise AdjustmentTypeFlat pennies
+	Type    AdjustmentType `json:"type" firestore:"type"`
+	Amount  int            `json:"amount" firestore:"amount"`
+	DueOn   *time.Time     `json:"dueOn" firestore:"dueOn"`
+	Trigger string         `json:"trigger" firestore:"trigger"`
+	// PayUrl where the milestone is paid, falling back to the quote's PayUrl
+	PayUrl string `json:"payUrl" firestore:"payUrl"`
+}
+
+// Signature is the evidence captured from the customer when they accept a
+// quote.
+type Signature struct {
+	Name  string `json:"name" firestore:"name"`
+	Title string `json:"title" firestore:"title"`
+	// Image the drawn signature
+	Image     *Image    `json:"image" firestore:"image"`
+	IP        string    `json:"ip" firestore:"ip"`
+	UserAgent string    `json:"userAgent" firestore:"userAgent"`
+	SignedAt  time.Time `json:"signedAt" firestore:"signedAt"`
+}
+
+// QuoteAcceptance records who accepted a quote and exactly what they agreed to.
+type QuoteAcceptance struct {
+	Signature Signature `json:"signature" firestore:"signature"`
+	// ContentHash hex encoded sha256 of the quote's content at the moment it was signed
+	ContentHash string `json:"contentHash" firestore:"contentHash"`
+	// RevisionNumber the revision holding a snapshot of the signed content
+	RevisionNumber int `json:"revisionNumber" firestore:"revisionNumber"`
+}
+
+// QuoteOption is one of several alternative scopes offered on a single quote,
+// such as Good, Better and Best. Top level line items and adjustments are
+// placed in an option by its id; those without one are shared by every option.
+type QuoteOption struct {
+	Id          ID     `json:"id" firestore:"id"`
+	Name        string `json:"name" firestore:"name"`
+	Description string `json:"description" firestore:"description"`
+}
+
+// QuoteOptionTotals prices one option of a quote, its own line items and
+// adjustments together with the shared ones.
+type QuoteOptionTotals struct {
+	Option QuoteOption  `json:"option" firestore:"option"`
+	Totals *QuoteTotals `json:"totals" firestore:"totals"`
+	Chosen bool         `json:"chosen" firestore:"chosen"`
+}
+
+// QuoteLockedError is returned when a locked quote, or one of its line items
+// or adjustments, is changed.
+type QuoteLockedError struct {
+	QuoteId ID
+}
+
+func (e *QuoteLockedError) Error() string {
+	return "quote " + e.QuoteId + " is locked and cannot be changed"
+}
+
+// ApprovalRequiredError is returned when a quote that breaks its group's
+// pricing policy is sent or accepted before it is approved.
+type ApprovalRequiredError struct {
+	QuoteId    ID
+	Violations []PolicyViolation
+}
+
+func (e *ApprovalRequiredError) Error() string {
+	return "quote " + e.QuoteId + " breaks the group's pricing policy and needs approval"
+}
+
+// QuoteUnlock records who unlocked a quote and why.
+type QuoteUnlock struct {
+	Actor  ID        `json:"actor" firestore:"actor"`
+	Reason string    `json:"reason" firestore:"reason"`
+	On     time.Time `json:"on" firestore:"on"`
+}
+
+// QuoteStatus is where a quote is in its lifecycle. See quoteTransitions for
+// the moves allowed between statuses.
+type QuoteStatus = string
+
+const (
+	QuoteStatusDraft     QuoteStatus = "draft"
+	QuoteStatusSent      QuoteStatus = "sent"
+	QuoteStatusViewed    QuoteStatus = "viewed"
+	QuoteStatusAccepted  QuoteStatus = "accepted"
+	QuoteStatusDeclined  QuoteStatus = "declined"
+	QuoteStatusExpired   QuoteStatus = "expired"
+	QuoteStatusConverted QuoteStatus = "converted"
+	QuoteStatusVoid      QuoteStatus = "void"
+)
+
+// QuoteStatusChange records a single transition of a quote's status.
+type QuoteStatusChange struct {
+	From  QuoteStatus `json:"from" firestore:"from"`
+	To    QuoteStatus `json:"to" firestore:"to"`
+	Actor ID          `json:"actor" firestore:"actor"`
+	On    time.Time   `json:"on" firestore:"on"`
+}
+
+// QuoteRevision is an immutable snapshot of a quote along with its line items,
+// adjustments and contacts. Revisions of a quote are numbered from 1.
+type QuoteRevision struct {
+	Id          ID            `json:"id" firestore:"id"`
+	QuoteId     ID            `json:"quoteId" firestore:"quoteId"`
+	Number      int           `json:"number" firestore:"number"`
+	Quote       Quote         `json:"quote" firestore:"quote"`
+	LineItems   []*LineItem   `json:"lineItems" firestore:"lineItems"`
+	Adjustments []*Adjustment `json:"adjustments" firestore:"adjustments"`
+	Contacts    []*Contact    `json:"contacts" firestore:"contacts"`
+	Created     time.Time     `json:"created" firestore:"created"`
+}
+
+// QuoteRevisionDiff lists the line items added, removed and changed between
+// two revisions and how much the total moved.
+type QuoteRevisionDiff struct {
+	From       int              `json:"from" firestore:"from"`
+	To         int              `json:"to" firestore:"to"`
+	Added      []*LineItem      `json:"added" firestore:"added"`
+	Removed    []*LineItem      `json:"removed" firestore:"removed"`
+	Changed    []LineItemChange `json:"changed" firestore:"changed"`
+	TotalDelta int              `json:"totalDelta" firestore:"totalDelta"`
+}
+
+// LineItemChange is a line item present in both revisions of a diff. Fields
+// names the json fields that differ.
+type LineItemChange struct {
+	Before *LineItem `json:"before" firestore:"before"`
+	After  *LineItem `json:"after" firestore:"after"`
+	Fields []string  `json:"fields" firestore:"fields"`
+}
+
+// QuoteSort is the field quotes are listed in order of.
+type QuoteSort = string
+
+const (
+	QuoteSortCreated QuoteSort = "created"
+	QuoteSortUpdated QuoteSort = "updated"
+	QuoteSortCode    QuoteSort = "code"
+	QuoteSortTotal   QuoteSort = "total"
+	QuoteSortSentOn  QuoteSort = "sentOn"
+	QuoteSortSoldOn  QuoteSort = "soldOn"
+)
+
+// QuoteFilter narrows and orders the quotes returned by ListQuotes. Zero
+// valued fields do not filter. Date ranges include From and exclude To.
+type QuoteFilter struct {
+	Statuses []QuoteStatus `json:"statuses" firestore:"statuses"`
+	SentFrom *time.Time    `json:"sentFrom" firestore:"sentFrom"`
+	SentTo   *time.Time    `json:"sentTo" firestore:"sentTo"`
+	SoldFrom *time.Time    `json:"soldFrom" firestore:"soldFrom"`
+	SoldTo   *time.Time    `json:"soldTo" firestore:"soldTo"`
+	BillToId *ID           `json:"billToId" firestore:"billToId"`
+	// Search matches anywhere in the code or order number, ignoring case
+	Search     string `json:"search" firestore:"search"`
+	MinTotal   *int   `json:"minTotal" firestore:"minTotal"`
+	MaxTotal   *int   `json:"maxTotal" firestore:"maxTotal"`
+	CreatedBy  *ID    `json:"createdBy" firestore:"createdBy"`
+	CustomerId *ID    `json:"customerId" firestore:"customerId"`
+	// ChangeOrderOf only returns change orders to this quote
+	ChangeOrderOf *ID `json:"changeOrderOf" firestore:"changeOrderOf"`
+	// IncludeDeleted also returns quotes that have been deleted
+	IncludeDeleted bool `json:"includeDeleted" firestore:"includeDeleted"`
+	// Sort defaults to QuoteSortCreated
+	Sort       QuoteSort `json:"sort" firestore:"sort"`
+	Descending bool      `json:"descending" firestore:"descending"`
+	// Limit defaults to DefaultQuoteLimit and is capped at MaxQuoteLimit
+	Limit  int `json:"limit" firestore:"limit"`
+	Offset int `json:"offset" firestore:"offset"`
+}
+
+const (
+	DefaultQuoteLimit = 50
+	MaxQuoteLimit     = 500
+)
+
+// QuotePage is one page of a quote listing. Total counts every quote matching
+// the filter, across all pages.
+type QuotePage struct {
+	Quotes []*Quote `json:"quotes" firestore:"quotes"`
+	Total  int      `json:"total" firestore:"total"`
+}
+
+// QuoteNumberAssignment is when quotes are given a number from their group's
+// sequence.
+type QuoteNumberAssignment = string
+
+const (
+	QuoteNumberOnCreate QuoteNumberAssignment = "create"
+	QuoteNumberOnSend   QuoteNumberAssignment = "send"
+)
+
+// QuoteNumberSequence numbers the quotes of a group. Pattern is expanded by
+// formatQuoteNumber, e.g. "EST-{YYYY}-{N:5}" gives EST-2026-00042. Counter is
+// the last number handed out and Year the year it was handed out in.
+type QuoteNumberSequence struct {
+	OrgId       ID                    `json:"orgId" firestore:"orgId"`
+	GroupId     ID                    `json:"groupId" firestore:"groupId"`
+	Pattern     string                `json:"pattern" firestore:"pattern"`
+	AssignOn    QuoteNumberAssignment `json:"assignOn" firestore:"assignOn"`
+	ResetYearly bool                  `json:"resetYearly" firestore:"resetYearly"`
+	Counter     int                   `json:"counter" firestore:"counter"`
+	Year        int                   `json:"year" firestore:"year"`
+	Updated     time.Time             `json:"updated" firestore:"updated"`
+}
+
+// QuoteTemplate is a reusable starting point for quotes, holding the line
+// item trees, adjustments, payment terms, notes and colors of the quote it was
+// saved from. Line items added from the pricebook keep their source item and
+// price, and are repriced from the pricebook when a quote is created from the
+// template.
+type QuoteTemplate struct {
+	Id                     ID                 `json:"id" firestore:"id"`
+	OrgId                  ID                 `json:"orgId" firestore:"orgId"`
+	GroupId                ID                 `json:"groupId" firestore:"groupId"`
+	Name                   string             `json:"name" firestore:"name"`
+	Description            string             `json:"description" firestore:"description"`
+	LogoId                 ID                 `json:"logoId" firestore:"logoId"`
+	PrimaryBackgroundColor string             `json:"primaryBackgroundColor" firestore:"primaryBackgroundColor"`
+	PrimaryTextColor       string             `json:"primaryTextColor" firestore:"primaryTextColor"`
+	PaymentTerms           string             `json:"paymentTerms" firestore:"paymentTerms"`
+	Notes                  string             `json:"notes" firestore:"notes"`
+	BalancePercentDue      int                `json:"balancePercentDue" firestore:"balancePercentDue"`
+	LineItemIds            []ID               `json:"lineItemIds" firestore:"lineItemIds"`
+	LineItems              []*LineItem        `json:"lineItems" firestore:"lineItems"`
+	Adjustments            []*Adjustment      `json:"adjustments" firestore:"adjustments"`
+	Options                []QuoteOption      `json:"options" firestore:"options"`
+	PaymentSchedule        []PaymentMilestone `json:"paymentSchedule" firestore:"paymentSchedule"`
+	Created                time.Time          `json:"created" firestore:"created"`
+	Updated                time.Time          `json:"updated" firestore:"updated"`
+	Hidden                 bool               `json:"hidden" firestore:"hidden"`
+}
+
+// QuoteTemplateOverrides replaces values taken from a template when a quote is
+// created from it. Nil fields keep the template's value.
+type QuoteTemplateOverrides struct {
+	LogoId                 *ID        `json:"logoId" firestore:"logoId"`
+	PrimaryBackgroundColor *string    `json:"primaryBackgroundColor" firestore:"primaryBackgroundColor"`
+	PrimaryTextColor       *string    `json:"primaryTextColor" firestore:"primaryTextColor"`
+	PaymentTerms           *string    `json:"paymentTerms" firestore:"paymentTerms"`
+	Notes                  *string    `json:"notes" firestore:"notes"`
+	BalancePercentDue      *int       `json:"balancePercentDue" firestore:"balancePercentDue"`
+	IssueDate              *time.Time `json:"issueDate" firestore:"issueDate"`
+	ExpirationDate         *time.Time `json:"expirationDate" firestore:"expirationDate"`
+	SenderId               *ID        `json:"senderId" firestore:"senderId"`
+	BillToId               *ID        `json:"billToId" firestore:"billToId"`
+	ShipToId               *ID        `json:"shipToId" firestore:"shipToId"`
+	// KeepTemplatePrices skips repricing line items from the pricebook
+	KeepTemplatePrices bool `json:"keepTemplatePrices" firestore:"keepTemplatePrices"`
 }
 
 type LineItem struct {
-	Id              ID        `json:"id" firestore:"id" firestore:"id"`
-	QuoteId         ID        `json:"quoteId" firestore:"quoteId" firestore:"quoteId"`
-	ParentId        *ID       `json:"parentId" firestore:"parentId" firestore:"parentId"`
-	SubItemIds      []ID      `json:"subItemIds" firestore:"subItemIds" firestore:"subItemIds"`
-	ImageId         *ID       `json:"imageId" firestore:"imageId" firestore:"imageId"`
-	Description     string    `json:"description" firestore:"description" firestore:"description"`
-	Quantity        int       `json:"quantity" firestore:"quantity" firestore:"quantity"`
-	QuantitySuffix  string    `json:"quantitySuffix" firestore:"quantitySuffix" firestore:"quantitySuffix"`
-	QuantityPrefix  string    `json:"quantityPrefix" firestore:"quantityPrefix" firestore:"quantityPrefix"`
-	UnitPrice       int       `json:"unitPrice" firestore:"unitPrice" firestore:"unitPrice"`
-	UnitPriceSuffix string    `json:"unitSuffix" firestore:"unitSuffix" firestore:"unitSuffix"`
-	UnitPricePrefix string    `json:"unitPrefix" firestore:"unitPrefix" firestore:"unitPrefix"`
-	Amount          *int      `json:"amount" firestore:"amount" firestore:"amount"`
-	AmountSuffix    string    `json:"amountSuffix" firestore:"amountSuffix" firestore:"amountSuffix"`
-	AmountPrefix    string    `json:"amountPrefix" firestore:"amountPrefix" firestore:"amountPrefix"`
-	Open            bool      `json:"open" firestore:"open" firestore:"open"`
-	Created         time.Time `json:"created" firestore:"created" firestore:"created"`
-	Updated         time.Time `json:"updated" firestore:"updated" firestore:"updated"`
+	Id               ID     `json:"id" firestore:"id" firestore:"id"`
+	QuoteId          ID     `json:"quoteId" firestore:"quoteId" firestore:"quoteId"`
+	ParentId         *ID    `json:"parentId" firestore:"parentId" firestore:"parentId"`
+	SubItemIds       []ID   `json:"subItemIds" firestore:"subItemIds" firestore:"subItemIds"`
+	ImageId          *ID    `json:"imageId" firestore:"imageId" firestore:"imageId"`
+	Description      string `json:"description" firestore:"description" firestore:"description"`
+	Quantity         int    `json:"quantity" firestore:"quantity" firestore:"quantity"`
+	QuantitySuffix   string `json:"quantitySuffix" firestore:"quantitySuffix" firestore:"quantitySuffix"`
+	QuantityPrefix   string `json:"quantityPrefix" firestore:"quantityPrefix" firestore:"quantityPrefix"`
+	UnitPrice        int    `json:"unitPrice" firestore:"unitPrice" firestore:"unitPrice"`
+	UnitPriceSuffix  string `json:"unitSuffix" firestore:"unitSuffix" firestore:"unitSuffix"`
+	UnitPricePrefix  string `json:"unitPrefix" firestore:"unitPrefix" firestore:"unitPrefix"`
+	Amount           *int   `json:"amount" firestore:"amount" firestore:"amount"`
+	AmountSuffix     string `json:"amountSuffix" firestore:"amountSuffix" firestore:"amountSuffix"`
+	AmountPrefix     string `json:"amountPrefix" firestore:"amountPrefix" firestore:"amountPrefix"`
+	Open             bool   `json:"open" firestore:"open" firestore:"open"`
+	Name             string `json:"name" firestore:"name" firestore:"name"`
+	SourceItemId     *ID    `json:"sourceItemId" firestore:"sourceItemId" firestore:"sourceItemId"`
+	SourcePriceId    *ID    `json:"sourcePriceId" firestore:"sourcePriceId" firestore:"sourcePriceId"`
+	HideFromCustomer bool   `json:"hideFromCustomer" firestore:"hideFromCustomer" firestore:"hideFromCustomer"`
+	Optional         bool   `json:"optional" firestore:"optional"`
+	AlternateGroup   string `json:"alternateGroup" firestore:"alternateGroup"`
+	Selected         bool   `json:"selected" firestore:"selected"`
+	OptionId         ID     `json:"optionId" firestore:"optionId"`
+	// UnitCost what one unit costs the business, snapshotted from Item.Cost. Never printed
+	UnitCost int `json:"unitCost" firestore:"unitCost"`
+	// RemovesLineItemId on a change order, the original quote's line item this one credits back
+	RemovesLineItemId ID        `json:"removesLineItemId" firestore:"removesLineItemId"`
+	Created           time.Time `json:"created" firestore:"created" firestore:"created"`
+	Updated           time.Time `json:"updated" firestore:"updated" firestore:"updated"`
+}
+
+// PriceDrift describes a line item whose snapshot no longer matches the
+// pricebook item and price it was added from.
+type PriceDrift struct {
+	LineItemId         ID     `json:"lineItemId" firestore:"lineItemId"`
+	SourceItemId       ID     `json:"sourceItemId" firestore:"sourceItemId"`
+	SourcePriceId      *ID    `json:"sourcePriceId" firestore:"sourcePriceId"`
+	Description        string `json:"description" firestore:"description"`
+	CurrentDescription string `json:"currentDescription" firestore:"currentDescription"`
+	UnitPrice          int    `json:"unitPrice" firestore:"unitPrice"`
+	CurrentUnitPrice   int    `json:"currentUnitPrice" firestore:"currentUnitPrice"`
+	// SourceMissing is set when the item has been deleted or the price removed,
+	// in which case there is nothing to refresh from
+	SourceMissing bool `json:"sourceMissing" firestore:"sourceMissing"`
 }
 
 type Adjustment struct {
@@ -63,6 +398,7 @@ type Adjustment struct {
 	Description string         `json:"description" firestore:"description" firestore:"description"`
 	Type        AdjustmentType `json:"type" firestore:"type" firestore:"type"`
 	Amount      int            `json:"amount" firestore:"amount" firestore:"amount"`
+	OptionId    ID             `json:"optionId" firestore:"optionId"`
 	Created     time.Time      `json:"created" firestore:"created" firestore:"created"`
 	Updated     time.Time      `json:"updated" firestore:"updated" firestore:"updated"`
 }
@@ -76,48 +412,103 @@ const (
 
 // Contact represents a contact in the company.
 type Contact struct {
-	Id          ID       `json:"id" firestore:"id"`
-	Name        string   `json:"name" firestore:"name"`
-	CompanyName string   `json:"companyName" firestore:"companyName"`
-	Phones      []string `json:"phones" firestore:"phones"`
-	Emails      []string `json:"emails" firestore:"emails"`
-	Websites    []string `json:"websites" firestore:"websites"`
-	Street      string   `json:"street" firestore:"street"`
-	City        string   `json:"city" firestore:"city"`
-	State       string   `json:"state" firestore:"state"`
-	Zip         string   `json:"zip" firestore:"zip"`
+	Id          ID        `json:"id" firestore:"id"`
+	OrgId       ID        `json:"orgId" firestore:"orgId"`
+	GroupId     ID        `json:"groupId" firestore:"groupId"`
+	Name        string    `json:"name" firestore:"name"`
+	CompanyName string    `json:"companyName" firestore:"companyName"`
+	Phones      []string  `json:"phones" firestore:"phones"`
+	Emails      []string  `json:"emails" firestore:"emails"`
+	Websites    []string  `json:"websites" firestore:"websites"`
+	Street      string    `json:"street" firestore:"street"`
+	City        string    `json:"city" firestore:"city"`
+	State       string    `json:"state" firestore:"state"`
+	Zip         string    `json:"zip" firestore:"zip"`
+	Created     time.Time `json:"created" firestore:"created"`
+	Updated     time.Time `json:"updated" firestore:"updated"`
+	Hidden      bool      `json:"hidden" firestore:"hidden"`
+}
+
+// ContactMatch is an existing contact that looks like the same person or
+// company as another. Reasons lists what matched: "email", "phone" or "name".
+type ContactMatch struct {
+	Contact *Contact `json:"contact" firestore:"contact"`
+	Reasons []string `json:"reasons" firestore:"reasons"`
 }
 
 // PrintableQuote represents a printable quote.
 type PrintableQuote struct {
-	Id                     ID                   `json:"id" firestore:"id"`
-	Code                   string               `json:"code" firestore:"code"`
-	OrderNumber            string               `json:"orderNumber" firestore:"orderNumber"`
-	Logo                   *Image               `json:"logo" firestore:"logo"`
-	PrimaryBackgroundColor string               `json:"primaryBackgroundColor" firestore:"primaryBackgroundColor"`
-	PrimaryTextColor       string               `json:"primaryTextColor" firestore:"primaryTextColor"`
-	IssueDate              *time.Time           `json:"issueDate" firestore:"issueDate"`
-	ExpirationDate         *time.Time           `json:"expirationDate" firestore:"expirationDate"`
-	PaymentTerms           string               `json:"paymentTerms" firestore:"paymentTerms"`
-	Notes                  string               `json:"notes" firestore:"notes"`
-	Sender                 *Contact             `json:"sender" firestore:"sender"`
-	BillTo                 *Contact             `json:"billTo" firestore:"billTo"`
-	ShipTo                 *Contact             `json:"shipTo" firestore:"shipTo"`
-	LineItems              []*PrintableLineItem `json:"lineItems" firestore:"lineItems"`
-	SubTotal               int                  `json:"subTotal" firestore:"subTotal"`
-	Adjustments            []*Adjustment        `json:"adjustments" firestore:"adjustments"`
-	Total                  int                  `json:"total" firestore:"total"`
-	BalanceDue             int                  `json:"balanceDue" firestore:"balanceDue"`
-	BalanceDueOn           *time.Time           `json:"balanceDueOn" firestore:"balanceDueOn"`
-	PayUrl                 string               `json:"payUrl" firestore:"payUrl"`
-	Sent                   bool                 `json:"sent" firestore:"sent"`
-	SentOn                 *time.Time           `json:"sentOn" firestore:"sentOn"`
-	Sold                   bool                 `json:"sold" firestore:"sold"`
-	SoldOn                 *time.Time           `json:"soldOn" firestore:"soldOn"`
-	Created                time.Time            `json:"created" firestore:"created"`
-	Updated                time.Time            `json:"updated" firestore:"updated"`
-	Hidden                 bool                 `json:"hidden" firestore:"hidden"`
-	Locked                 bool                 `json:"locked" firestore:"locked"`
+	Id                     ID                    `json:"id" firestore:"id"`
+	Code                   string                `json:"code" firestore:"code"`
+	OrderNumber            string                `json:"orderNumber" firestore:"orderNumber"`
+	Logo                   *Image                `json:"logo" firestore:"logo"`
+	PrimaryBackgroundColor string                `json:"primaryBackgroundColor" firestore:"primaryBackgroundColor"`
+	PrimaryTextColor       string                `json:"primaryTextColor" firestore:"primaryTextColor"`
+	IssueDate              *time.Time            `json:"issueDate" firestore:"issueDate"`
+	ExpirationDate         *time.Time            `json:"expirationDate" firestore:"expirationDate"`
+	PaymentTerms           string                `json:"paymentTerms" firestore:"paymentTerms"`
+	Notes                  string                `json:"notes" firestore:"notes"`
+	Sender                 *Contact              `json:"sender" firestore:"sender"`
+	BillTo                 *Contact              `json:"billTo" firestore:"billTo"`
+	ShipTo                 *Contact              `json:"shipTo" firestore:"shipTo"`
+	LineItems              []*PrintableLineItem  `json:"lineItems" firestore:"lineItems"`
+	SubTotal               int                   `json:"subTotal" firestore:"subTotal"`
+	Adjustments            []*Adjustment         `json:"adjustments" firestore:"adjustments"`
+	Total                  int                   `json:"total" firestore:"total"`
+	BalanceDue             int                   `json:"balanceDue" firestore:"balanceDue"`
+	BalanceDueOn           *time.Time            `json:"balanceDueOn" firestore:"balanceDueOn"`
+	PayUrl                 string                `json:"payUrl" firestore:"payUrl"`
+	SentOn                 *time.Time            `json:"sentOn" firestore:"sentOn"`
+	SoldOn                 *time.Time            `json:"soldOn" firestore:"soldOn"`
+	Created                time.Time             `json:"created" firestore:"created"`
+	Updated                time.Time             `json:"updated" firestore:"updated"`
+	Hidden                 bool                  `json:"hidden" firestore:"hidden"`
+	Locked                 bool                  `json:"locked" firestore:"locked"`
+	Status                 QuoteStatus           `json:"status" firestore:"status"`
+	Revision               int                   `json:"revision" firestore:"revision"`
+	Options                []*PrintableOption    `json:"options" firestore:"options"`
+	Acceptance             *QuoteAcceptance      `json:"acceptance" firestore:"acceptance"`
+	PaymentSchedule        []*PrintableMilestone `json:"paymentSchedule" firestore:"paymentSchedule"`
+	Payments               []*Payment            `json:"payments" firestore:"payments"`
+	AmountPaid             int                   `json:"amountPaid" firestore:"amountPaid"`
+	RemainingBalance       int                   `json:"remainingBalance" firestore:"remainingBalance"`
+	Overpaid               int                   `json:"overpaid" firestore:"overpaid"`
+	// OriginalCode the code of the quote a change order is made to
+	OriginalCode      string `json:"originalCode" firestore:"originalCode"`
+	ChangeOrderNumber int    `json:"changeOrderNumber" firestore:"changeOrderNumber"`
+	// PreviousContractTotal the original total plus earlier accepted change orders
+	PreviousContractTotal int `json:"previousContractTotal" firestore:"previousContractTotal"`
+	// ContractTotal the previous contract total plus this change order
+	ContractTotal int `json:"contractTotal" firestore:"contractTotal"`
+}
+
+// PrintableMilestone represents a payment milestone in the printable quote with
+// its amount worked out from the total.
+type PrintableMilestone struct {
+	Id          ID         `json:"id" firestore:"id"`
+	Description string     `json:"description" firestore:"description"`
+	Percent     int        `json:"percent" firestore:"percent"`
+	Amount      int        `json:"amount" firestore:"amount"`
+	DueOn       *time.Time `json:"dueOn" firestore:"dueOn"`
+	Trigger     string     `json:"trigger" firestore:"trigger"`
+	PayUrl      string     `json:"payUrl" firestore:"payUrl"`
+	Paid        int        `json:"paid" firestore:"paid"`
+	// Due set on the first milestone still to be paid
+	Due bool `json:"due" firestore:"due"`
+}
+
+// PrintableOption represents one option of the printable quote. Its totals
+// include the shared line items and adjustments.
+type PrintableOption struct {
+	Id          ID                   `json:"id" firestore:"id"`
+	Name        string               `json:"name" firestore:"name"`
+	Description string               `json:"description" firestore:"description"`
+	LineItems   []*PrintableLineItem `json:"lineItems" firestore:"lineItems"`
+	SubTotal    int                  `json:"subTotal" firestore:"subTotal"`
+	Adjustments []*Adjustment        `json:"adjustments" firestore:"adjustments"`
+	Total       int                  `json:"total" firestore:"total"`
+	BalanceDue  int                  `json:"balanceDue" firestore:"balanceDue"`
+	Chosen      bool                 `json:"chosen" firestore:"chosen"`
 }
 
 // PrintableLineItem represents a line item in the printable quote.
@@ -127,6 +518,7 @@ type PrintableLineItem struct {
 	Number           string               `json:"number" firestore:"number"`
 	SubItems         []*PrintableLineItem `json:"subItems" firestore:"subItems"`
 	Image            *Image               `json:"image" firestore:"image"`
+	Name             string               `json:"name" firestore:"name"`
 	Description      string               `json:"description" firestore:"description"`
 	Quantity         int                  `json:"quantity" firestore:"quantity"`
 	QuantitySuffix   string               `json:"quantitySuffix" firestore:"quantitySuffix"`
@@ -138,6 +530,12 @@ type PrintableLineItem struct {
 	Amount           int                  `json:"amount" firestore:"amount"`
 	AmountSuffix     string               `json:"amountSuffix" firestore:"amountSuffix"`
 	AmountPrefix     string               `json:"amountPrefix" firestore:"amountPrefix"`
-	Created          time.Time            `json:"created" firestore:"created"`
-	Updated          time.Time            `json:"updated" firestore:"updated"`
+	Optional         bool                 `json:"optional" firestore:"optional"`
+	AlternateGroup   string               `json:"alternateGroup" firestore:"alternateGroup"`
+	Selected         bool                 `json:"selected" firestore:"selected"`
+	Excluded         bool                 `json:"excluded" firestore:"excluded"`
+	// Removed set on change order line items that credit back an original line item
+	Removed bool      `json:"removed" firestore:"removed"`
+	Created time.Time `json:"created" firestore:"created"`
+	Updated time.Time `json:"updated" firestore:"updated"`
 }
@@ -0,0 +1,113 @@
+package pricey
+
+import (
+	"context"
+	"reflect"
+	"slices"
+)
+
+// snapshotQuote gathers the quote, its line items, adjustments and contacts
+// into a revision ready to be stored.
+func snapshotQuote(ctx context.Context, store Store, quoteId ID) (*QuoteRevision, error) {
+	quote, err := store.GetQuote(ctx, quoteId)
+	if err != nil {
+		return nil, err
+	}
+	lineItems, adjustments, err := loadQuoteLineItemsAndAdjustments(ctx, store, quote)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := loadQuoteContacts(ctx, store, quote)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := &QuoteRevision{
+		QuoteId:     quoteId,
+		Quote:       *quote,
+		LineItems:   []*LineItem{},
+		Adjustments: []*Adjustment{},
+		Contacts:    []*Contact{},
+	}
+	for _, lineItemId := range quote.LineItemIds {
+		revision.LineItems = append(revision.LineItems, lineItems[lineItemId])
+	}
+	for _, adjustmentId := range quote.AdjustmentIds {
+		revision.Adjustments = append(revision.Adjustments, adjustments[adjustmentId])
+	}
+	for _, contactId := range []ID{quote.SenderId, quote.BillToId, quote.ShipToId} {
+		if c := contacts[contactId]; c != nil && !slices.Contains(revision.Contacts, c) {
+			revision.Contacts = append(revision.Contacts, c)
+		}
+	}
+	return revision, nil
+}
+
+// revisionTotal prices the revision's snapshot.
+func revisionTotal(r *QuoteRevision) int {
+	lineItems := map[ID]*LineItem{}
+	for _, li := range r.LineItems {
+		lineItems[li.Id] = li
+	}
+	adjustments := map[ID]*Adjustment{}
+	for _, a := range r.Adjustments {
+		adjustments[a.Id] = a
+	}
+	return calculateQuoteTotals(&r.Quote, lineItems, adjustments).Total
+}
+
+// diffRevisions compares the line items of two revisions of the same quote,
+// matching them by id.
+func diffRevisions(a, b *QuoteRevision) *QuoteRevisionDiff {
+	diff := &QuoteRevisionDiff{
+		From:       a.Number,
+		To:         b.Number,
+		Added:      []*LineItem{},
+		Removed:    []*LineItem{},
+		Changed:    []LineItemChange{},
+		TotalDelta: revisionTotal(b) - revisionTotal(a),
+	}
+	before := map[ID]*LineItem{}
+	for _, li := range a.LineItems {
+		before[li.Id] = li
+	}
+	after := map[ID]*LineItem{}
+	for _, li := range b.LineItems {
+		after[li.Id] = li
+	}
+	for _, li := range a.LineItems {
+		if after[li.Id] == nil {
+			diff.Removed = append(diff.Removed, li)
+		}
+	}
+	for _, li := range b.LineItems {
+		old := before[li.Id]
+		if old == nil {
+			diff.Added = append(diff.Added, li)
+			continue
+		}
+		if fields := lineItemChangedFields(old, li); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, LineItemChange{Before: old, After: li, Fields: fields})
+		}
+	}
+	return diff
+}
+
+// lineItemChangedFields returns the json names of the fields that differ
+// between two versions of a line item, ignoring timestamps.
+func lineItemChangedFields(a, b *LineItem) []string {
+	var fields []string
+	va := reflect.ValueOf(a).Elem()
+	vb := reflect.ValueOf(b).Elem()
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		if name == "Created" || name == "Updated" {
+			continue
+		}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			fields = append(fields, t.Field(i).Tag.Get("json"))
+		}
+	}
+	return fields
+}
@@ -0,0 +1,41 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	a := &QuoteRevision{
+		Number: 1,
+		Quote:  Quote{LineItemIds: []ID{"1", "2", "3"}},
+		LineItems: []*LineItem{
+			{Id: "1", Description: "Water heater", Quantity: 100, UnitPrice: 90000},
+			{Id: "2", Description: "Haul away", Quantity: 100, UnitPrice: 5000},
+			{Id: "3", Description: "Permit", Quantity: 100, UnitPrice: 2500},
+		},
+	}
+	b := &QuoteRevision{
+		Number: 2,
+		Quote:  Quote{LineItemIds: []ID{"1", "3", "4"}},
+		LineItems: []*LineItem{
+			{Id: "1", Description: "Water heater", Quantity: 100, UnitPrice: 95000},
+			{Id: "3", Description: "Permit", Quantity: 100, UnitPrice: 2500},
+			{Id: "4", Description: "Expansion tank", Quantity: 100, UnitPrice: 12000},
+		},
+	}
+
+	diff := diffRevisions(a, b)
+	assert.Equal(t, 1, diff.From)
+	assert.Equal(t, 2, diff.To)
+	require.Len(t, diff.Added, 1)
+	assert.Equal(t, ID("4"), diff.Added[0].Id)
+	require.Len(t, diff.Removed, 1)
+	assert.Equal(t, ID("2"), diff.Removed[0].Id)
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, ID("1"), diff.Changed[0].After.Id)
+	assert.Equal(t, []string{"unitPrice"}, diff.Changed[0].Fields)
+	assert.Equal(t, 12000, diff.TotalDelta)
+}
@@ -0,0 +1,63 @@
+package pricey
+
+// paymentScheduleAmounts works out how much of total each milestone asks for.
+// Percentages are of the total, rounded down, with the rounding left over added
+// to the last percentage milestone so that percentages adding up to 100 cover
+// the total exactly.
+func paymentScheduleAmounts(schedule []PaymentMilestone, total int) []int {
+	amounts := make([]int, len(schedule))
+	percent := 0
+	last := -1
+	for i, m := range schedule {
+		if m.Type == AdjustmentTypePercent {
+			percent += m.Amount
+			last = i
+		}
+	}
+	percentTotal := total * percent / 100
+	for i, m := range schedule {
+		if m.Type == AdjustmentTypePercent {
+			amounts[i] = total * m.Amount / 100
+			percentTotal -= amounts[i]
+		} else {
+			amounts[i] = m.Amount
+		}
+	}
+	if last >= 0 {
+		amounts[last] += percentTotal
+	}
+	return amounts
+}
+
+// validatePaymentSchedule checks every milestone asks for a positive amount,
+// percentages being no more than 100, and that together they add up to total.
+// An empty schedule is always valid.
+func validatePaymentSchedule(schedule []PaymentMilestone, total int) error {
+	if len(schedule) == 0 {
+		return nil
+	}
+	for _, m := range schedule {
+		if m.Amount <= 0 || (m.Type == AdjustmentTypePercent && m.Amount > 100) {
+			return InvalidPaymentMilestoneError
+		}
+	}
+	sum := 0
+	for _, amount := range paymentScheduleAmounts(schedule, total) {
+		sum += amount
+	}
+	if sum != total {
+		return PaymentScheduleTotalError
+	}
+	return nil
+}
+
+// firstDueMilestone returns the index of the first milestone that is not yet
+// fully paid, or -1 when there is none.
+func firstDueMilestone(balance *QuoteBalance) int {
+	for i, m := range balance.Milestones {
+		if m.Paid < m.Amount {
+			return i
+		}
+	}
+	return -1
+}
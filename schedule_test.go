@@ -0,0 +1,121 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentScheduleAmounts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schedule []PaymentMilestone
+		total    int
+		expected []int
+	}{
+		{
+			name:     "empty",
+			schedule: nil,
+			total:    10000,
+			expected: []int{},
+		},
+		{
+			name: "percentages",
+			schedule: []PaymentMilestone{
+				{Type: AdjustmentTypePercent, Amount: 30},
+				{Type: AdjustmentTypePercent, Amount: 40},
+				{Type: AdjustmentTypePercent, Amount: 30},
+			},
+			total:    10000,
+			expected: []int{3000, 4000, 3000},
+		},
+		{
+			name: "rounding goes to the last percentage",
+			schedule: []PaymentMilestone{
+				{Type: AdjustmentTypePercent, Amount: 33},
+				{Type: AdjustmentTypePercent, Amount: 33},
+				{Type: AdjustmentTypePercent, Amount: 34},
+				{Type: AdjustmentTypeFlat, Amount: 50},
+			},
+			total:    10001,
+			expected: []int{3300, 3300, 3401, 50},
+		},
+		{
+			name: "fixed deposit and percentages",
+			schedule: []PaymentMilestone{
+				{Type: AdjustmentTypeFlat, Amount: 50000},
+				{Type: AdjustmentTypePercent, Amount: 50},
+			},
+			total:    100000,
+			expected: []int{50000, 50000},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, paymentScheduleAmounts(tc.schedule, tc.total))
+		})
+	}
+}
+
+func TestValidatePaymentSchedule(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schedule []PaymentMilestone
+		total    int
+		expected error
+	}{
+		{name: "empty", schedule: nil, total: 10000, expected: nil},
+		{
+			name: "adds up",
+			schedule: []PaymentMilestone{
+				{Type: AdjustmentTypeFlat, Amount: 2500},
+				{Type: AdjustmentTypePercent, Amount: 75},
+			},
+			total:    10000,
+			expected: nil,
+		},
+		{
+			name: "short of the total",
+			schedule: []PaymentMilestone{
+				{Type: AdjustmentTypePercent, Amount: 30},
+				{Type: AdjustmentTypePercent, Amount: 40},
+			},
+			total:    10000,
+			expected: PaymentScheduleTotalError,
+		},
+		{
+			name: "over the total",
+			schedule: []PaymentMilestone{
+				{Type: AdjustmentTypeFlat, Amount: 5000},
+				{Type: AdjustmentTypePercent, Amount: 100},
+			},
+			total:    10000,
+			expected: PaymentScheduleTotalError,
+		},
+		{
+			name:     "zero amount",
+			schedule: []PaymentMilestone{{Type: AdjustmentTypeFlat, Amount: 0}},
+			total:    0,
+			expected: InvalidPaymentMilestoneError,
+		},
+		{
+			name:     "over 100 percent",
+			schedule: []PaymentMilestone{{Type: AdjustmentTypePercent, Amount: 120}},
+			total:    10000,
+			expected: InvalidPaymentMilestoneError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, validatePaymentSchedule(tc.schedule, tc.total))
+		})
+	}
+}
@@ -77,10 +77,13 @@ CREATE TABLE IF NOT EXISTS custom_value_configs (
     name        TEXT NOT NULL DEFAULT '',
     description TEXT NOT NULL DEFAULT '',
     descriptors JSONB NOT NULL DEFAULT '[]',
+    price_formula_key TEXT,
     created     TIMESTAMPTZ NOT NULL,
     updated     TIMESTAMPTZ NOT NULL
 );
 
+ALTER TABLE custom_value_configs ADD COLUMN IF NOT EXISTS price_formula_key TEXT;
+
 CREATE TABLE IF NOT EXISTS images (
     id       TEXT PRIMARY KEY,
     org_id   TEXT NOT NULL,
@@ -123,7 +126,102 @@ CREATE TABLE IF NOT EXISTS quotes (
     created                  TIMESTAMPTZ NOT NULL,
     updated                  TIMESTAMPTZ NOT NULL,
     hidden                   BOOLEAN NOT NULL DEFAULT FALSE,
-    locked                   BOOLEAN NOT NULL DEFAULT FALSE
+    locked                   BOOLEAN NOT NULL DEFAULT FALSE,
+    status                   TEXT NOT NULL DEFAULT 'draft',
+    status_history           JSONB NOT NULL DEFAULT '[]',
+    unlock_history           JSONB NOT NULL DEFAULT '[]',
+    created_by               TEXT NOT NULL DEFAULT '',
+    customer_id              TEXT NOT NULL DEFAULT '',
+    options                  JSONB NOT NULL DEFAULT '[]',
+    chosen_option_id         TEXT NOT NULL DEFAULT '',
+    acceptance               JSONB,
+    payment_schedule         JSONB NOT NULL DEFAULT '[]',
+    change_order_of          TEXT NOT NULL DEFAULT '',
+    change_order_number      INTEGER NOT NULL DEFAULT 0,
+    change_order_counter     INTEGER NOT NULL DEFAULT 0,
+    approvals                JSONB NOT NULL DEFAULT '[]',
+    expiry_locked            BOOLEAN NOT NULL DEFAULT FALSE,
+    discount_role            TEXT NOT NULL DEFAULT ''
+);
+
+-- quotes created before the status column existed take their status from the
+-- sent/sold flags, which are only kept for this migration. Accepted quotes are
+-- locked like any other accepted quote.
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS status TEXT;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS status_history JSONB NOT NULL DEFAULT '[]';
+UPDATE quotes SET status = CASE WHEN sold THEN 'accepted' WHEN sent THEN 'sent' ELSE 'draft' END, locked = locked OR sold WHERE status IS NULL;
+ALTER TABLE quotes ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE quotes ALTER COLUMN status SET NOT NULL;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS unlock_history JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS created_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS customer_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS options JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS chosen_option_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS acceptance JSONB;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS payment_schedule JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS change_order_of TEXT NOT NULL DEFAULT '';
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS change_order_number INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS approvals JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS change_order_counter INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS expiry_locked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS discount_role TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS quotes_org_group_created_idx ON quotes (org_id, group_id, created);
+CREATE INDEX IF NOT EXISTS quotes_org_group_status_idx ON quotes (org_id, group_id, status);
+CREATE INDEX IF NOT EXISTS quotes_org_group_bill_to_idx ON quotes (org_id, group_id, bill_to_id);
+CREATE INDEX IF NOT EXISTS quotes_org_group_created_by_idx ON quotes (org_id, group_id, created_by);
+CREATE INDEX IF NOT EXISTS quotes_org_group_sent_on_idx ON quotes (org_id, group_id, sent_on);
+CREATE INDEX IF NOT EXISTS quotes_org_group_sold_on_idx ON quotes (org_id, group_id, sold_on);
+CREATE INDEX IF NOT EXISTS quotes_org_group_total_idx ON quotes (org_id, group_id, total);
+CREATE INDEX IF NOT EXISTS quotes_org_group_customer_idx ON quotes (org_id, group_id, customer_id);
+CREATE INDEX IF NOT EXISTS quotes_org_group_expiration_idx ON quotes (org_id, group_id, expiration_date) WHERE status IN ('sent', 'viewed');
+CREATE UNIQUE INDEX IF NOT EXISTS quotes_change_order_number_idx ON quotes (change_order_of, change_order_number) WHERE change_order_of <> '';
+
+CREATE TABLE IF NOT EXISTS quote_revisions (
+    id       TEXT PRIMARY KEY,
+    org_id   TEXT NOT NULL,
+    group_id TEXT NOT NULL,
+    quote_id TEXT NOT NULL,
+    number   INTEGER NOT NULL,
+    snapshot JSONB NOT NULL,
+    created  TIMESTAMPTZ NOT NULL,
+    UNIQUE (quote_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS quote_templates (
+    id          TEXT PRIMARY KEY,
+    org_id      TEXT NOT NULL,
+    group_id    TEXT NOT NULL,
+    name        TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    template    JSONB NOT NULL,
+    created     TIMESTAMPTZ NOT NULL,
+    updated     TIMESTAMPTZ NOT NULL,
+    hidden      BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS quote_templates_org_group_idx ON quote_templates (org_id, group_id);
+
+CREATE TABLE IF NOT EXISTS quote_number_sequences (
+    org_id       TEXT NOT NULL,
+    group_id     TEXT NOT NULL,
+    pattern      TEXT NOT NULL,
+    assign_on    TEXT NOT NULL,
+    reset_yearly BOOLEAN NOT NULL DEFAULT FALSE,
+    counter      INTEGER NOT NULL DEFAULT 0,
+    year         INTEGER NOT NULL DEFAULT 0,
+    updated      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (org_id, group_id)
+);
+
+CREATE TABLE IF NOT EXISTS quote_policies (
+    org_id         TEXT NOT NULL,
+    group_id       TEXT NOT NULL,
+    min_margin     INTEGER NOT NULL DEFAULT 0,
+    max_discount   JSONB NOT NULL DEFAULT '{}',
+    approver_roles JSONB NOT NULL DEFAULT '[]',
+    updated        TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (org_id, group_id)
 );
 
 CREATE TABLE IF NOT EXISTS line_items (
@@ -145,10 +243,31 @@ CREATE TABLE IF NOT EXISTS line_items (
     amount_suffix    TEXT NOT NULL DEFAULT '',
     amount_prefix    TEXT NOT NULL DEFAULT '',
     open             BOOLEAN NOT NULL DEFAULT FALSE,
+    name             TEXT NOT NULL DEFAULT '',
+    source_item_id   TEXT,
+    source_price_id  TEXT,
+    hide_from_customer BOOLEAN NOT NULL DEFAULT FALSE,
+    optional         BOOLEAN NOT NULL DEFAULT FALSE,
+    alternate_group  TEXT NOT NULL DEFAULT '',
+    selected         BOOLEAN NOT NULL DEFAULT FALSE,
+    option_id        TEXT NOT NULL DEFAULT '',
+    removes_line_item_id TEXT NOT NULL DEFAULT '',
+    unit_cost        INTEGER NOT NULL DEFAULT 0,
     created          TIMESTAMPTZ NOT NULL,
     updated          TIMESTAMPTZ NOT NULL
 );
 
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS name TEXT NOT NULL DEFAULT '';
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS source_item_id TEXT;
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS source_price_id TEXT;
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS hide_from_customer BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS optional BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS alternate_group TEXT NOT NULL DEFAULT '';
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS selected BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS option_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS removes_line_item_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS unit_cost INTEGER NOT NULL DEFAULT 0;
+
 CREATE TABLE IF NOT EXISTS adjustments (
     id          TEXT PRIMARY KEY,
     org_id      TEXT NOT NULL,
@@ -157,10 +276,13 @@ CREATE TABLE IF NOT EXISTS adjustments (
     description TEXT NOT NULL DEFAULT '',
     type        INTEGER NOT NULL DEFAULT 0,
     amount      INTEGER NOT NULL DEFAULT 0,
+    option_id   TEXT NOT NULL DEFAULT '',
     created     TIMESTAMPTZ NOT NULL,
     updated     TIMESTAMPTZ NOT NULL
 );
 
+ALTER TABLE adjustments ADD COLUMN IF NOT EXISTS option_id TEXT NOT NULL DEFAULT '';
+
 CREATE TABLE IF NOT EXISTS contacts (
     id           TEXT PRIMARY KEY,
     org_id       TEXT NOT NULL,
@@ -173,5 +295,74 @@ CREATE TABLE IF NOT EXISTS contacts (
     street       TEXT NOT NULL DEFAULT '',
     city         TEXT NOT NULL DEFAULT '',
     state        TEXT NOT NULL DEFAULT '',
-    zip          TEXT NOT NULL DEFAULT ''
+    zip          TEXT NOT NULL DEFAULT '',
+    created      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    hidden       BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS created TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS updated TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS contacts_org_group_idx ON contacts (org_id, group_id);
+
+CREATE TABLE IF NOT EXISTS customers (
+    id          TEXT PRIMARY KEY,
+    org_id      TEXT NOT NULL,
+    group_id    TEXT NOT NULL,
+    name        TEXT NOT NULL DEFAULT '',
+    contact_ids JSONB NOT NULL DEFAULT '[]',
+    address_ids JSONB NOT NULL DEFAULT '[]',
+    tags        JSONB NOT NULL DEFAULT '[]',
+    notes       TEXT NOT NULL DEFAULT '',
+    created     TIMESTAMPTZ NOT NULL,
+    updated     TIMESTAMPTZ NOT NULL,
+    hidden      BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS customers_org_group_idx ON customers (org_id, group_id);
+
+CREATE TABLE IF NOT EXISTS payments (
+    id           TEXT PRIMARY KEY,
+    org_id       TEXT NOT NULL,
+    group_id     TEXT NOT NULL,
+    quote_id     TEXT NOT NULL,
+    amount       INTEGER NOT NULL,
+    refund       BOOLEAN NOT NULL DEFAULT FALSE,
+    method       TEXT NOT NULL DEFAULT '',
+    reference    TEXT NOT NULL DEFAULT '',
+    received_on  TIMESTAMPTZ NOT NULL,
+    milestone_id TEXT NOT NULL DEFAULT '',
+    created      TIMESTAMPTZ NOT NULL,
+    updated      TIMESTAMPTZ NOT NULL,
+    hidden       BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS payments_org_group_quote_idx ON payments (org_id, group_id, quote_id);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id       TEXT PRIMARY KEY,
+    org_id   TEXT NOT NULL,
+    group_id TEXT NOT NULL,
+    quote_id TEXT NOT NULL,
+    number   TEXT NOT NULL,
+    invoice  JSONB NOT NULL,
+    created  TIMESTAMPTZ NOT NULL,
+    updated  TIMESTAMPTZ NOT NULL,
+    hidden   BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (org_id, group_id, number)
+);
+
+CREATE INDEX IF NOT EXISTS invoices_org_group_quote_idx ON invoices (org_id, group_id, quote_id);
+
+CREATE TABLE IF NOT EXISTS invoice_number_sequences (
+    org_id       TEXT NOT NULL,
+    group_id     TEXT NOT NULL,
+    pattern      TEXT NOT NULL,
+    reset_yearly BOOLEAN NOT NULL DEFAULT FALSE,
+    counter      INTEGER NOT NULL DEFAULT 0,
+    year         INTEGER NOT NULL DEFAULT 0,
+    updated      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (org_id, group_id)
 );
@@ -0,0 +1,38 @@
+package pricey
+
+import (
+	"slices"
+)
+
+// lineItemSelections works out the Selected flag of every optional and
+// alternate line item on the quote once selectedIds have been chosen. Anything
+// not listed is deselected. Each selected id must be an optional or alternate
+// line item on the quote, and at most one may come from each alternate group.
+func lineItemSelections(quote *Quote, lineItems map[ID]*LineItem, selectedIds []ID) (map[ID]bool, error) {
+	groups := map[string]ID{}
+	for _, id := range selectedIds {
+		l := lineItems[id]
+		if l == nil || !slices.Contains(quote.LineItemIds, id) {
+			return nil, LineItemNotOnQuoteError
+		}
+		if !l.Optional && l.AlternateGroup == "" {
+			return nil, LineItemNotSelectableError
+		}
+		if l.AlternateGroup != "" {
+			if other, ok := groups[l.AlternateGroup]; ok && other != id {
+				return nil, AlternateSelectionError
+			}
+			groups[l.AlternateGroup] = id
+		}
+	}
+
+	selections := map[ID]bool{}
+	for _, lineItemId := range quote.LineItemIds {
+		l := lineItems[lineItemId]
+		if l == nil || (!l.Optional && l.AlternateGroup == "") {
+			continue
+		}
+		selections[lineItemId] = slices.Contains(selectedIds, lineItemId)
+	}
+	return selections, nil
+}
@@ -0,0 +1,67 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineItemSelections(t *testing.T) {
+	quote := &Quote{LineItemIds: []ID{"1", "2", "3", "4", "5"}}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1"},
+		"2": {Id: "2", Optional: true, Selected: true},
+		"3": {Id: "3", Optional: true},
+		"4": {Id: "4", AlternateGroup: "roof"},
+		"5": {Id: "5", AlternateGroup: "roof", Selected: true},
+		"6": {Id: "6", Optional: true},
+	}
+	testCases := []struct {
+		name        string
+		selectedIds []ID
+		expected    map[ID]bool
+		err         error
+	}{
+		{
+			name:        "nothing selected",
+			selectedIds: nil,
+			expected:    map[ID]bool{"2": false, "3": false, "4": false, "5": false},
+		},
+		{
+			name:        "optional and alternate",
+			selectedIds: []ID{"3", "4"},
+			expected:    map[ID]bool{"2": false, "3": true, "4": true, "5": false},
+		},
+		{
+			name:        "repeated id",
+			selectedIds: []ID{"4", "4"},
+			expected:    map[ID]bool{"2": false, "3": false, "4": true, "5": false},
+		},
+		{
+			name:        "two alternates from one group",
+			selectedIds: []ID{"4", "5"},
+			err:         AlternateSelectionError,
+		},
+		{
+			name:        "required line item",
+			selectedIds: []ID{"1"},
+			err:         LineItemNotSelectableError,
+		},
+		{
+			name:        "line item not on the quote",
+			selectedIds: []ID{"6"},
+			err:         LineItemNotOnQuoteError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			selections, err := lineItemSelections(quote, lineItems, tc.selectedIds)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.expected, selections)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package pricey
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// quoteTransitions lists the statuses a quote may move to from each status.
+// Converted and void quotes are final.
+var quoteTransitions = map[QuoteStatus][]QuoteStatus{
+	QuoteStatusDraft:     {QuoteStatusSent, QuoteStatusVoid},
+	QuoteStatusSent:      {QuoteStatusViewed, QuoteStatusAccepted, QuoteStatusDeclined, QuoteStatusExpired, QuoteStatusVoid},
+	QuoteStatusViewed:    {QuoteStatusAccepted, QuoteStatusDeclined, QuoteStatusExpired, QuoteStatusVoid},
+	QuoteStatusAccepted:  {QuoteStatusConverted, QuoteStatusVoid},
+	QuoteStatusDeclined:  {QuoteStatusDraft, QuoteStatusVoid},
+	QuoteStatusExpired:   {QuoteStatusDraft, QuoteStatusVoid},
+	QuoteStatusConverted: {},
+	QuoteStatusVoid:      {},
+}
+
+// canTransitionQuote reports whether a quote may move from one status to another.
+func canTransitionQuote(from, to QuoteStatus) bool {
+	return slices.Contains(quoteTransitions[from], to)
+}
+
+// quoteStatus is the quote's status, treating a quote that has none yet as a
+// draft.
+func quoteStatus(q *Quote) QuoteStatus {
+	if q.Status != "" {
+		return q.Status
+	}
+	return QuoteStatusDraft
+}
+
+// transitionQuote moves the quote to a new status, recording who moved it and
+// when. SentOn and SoldOn are stamped as the quote is sent and accepted, quotes
+// without a number are numbered as they are sent, and accepted and void quotes
+// are locked. A quote with options can only be accepted once one is chosen, an
+// expired quote only once it is re-issued, and a quote is only sent or
+// accepted while its payment schedule adds up and, when it breaks its group's
+// pricing policy, once it is approved.
+func transitionQuote(ctx context.Context, store Store, id ID, to QuoteStatus, actor ID) (*Quote, error) {
+	q, err := store.GetQuote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = checkQuoteTransition(ctx, store, q, to)
+	if err != nil {
+		return nil, err
+	}
+	return applyQuoteTransition(ctx, store, q, to, actor)
+}
+
+// checkQuoteTransition refuses the moves transitionQuote does not allow,
+// without writing anything. Callers that write more alongside the move check
+// it first, since transactions are not atomic on every store.
+func checkQuoteTransition(ctx context.Context, store Store, q *Quote, to QuoteStatus) error {
+	if to == QuoteStatusAccepted && quoteExpired(q, time.Now()) {
+		return QuoteExpiredError
+	}
+	if !canTransitionQuote(quoteStatus(q), to) {
+		return InvalidQuoteStatusError
+	}
+	if to == QuoteStatusAccepted && len(q.Options) > 0 && quoteOption(q, q.ChosenOptionId) == nil {
+		return QuoteOptionRequiredError
+	}
+	if to == QuoteStatusSent || to == QuoteStatusAccepted {
+		if err := validatePaymentSchedule(q.PaymentSchedule, q.Total); err != nil {
+			return err
+		}
+		if err := enforceQuotePolicy(ctx, store, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyQuoteTransition writes a move already allowed by checkQuoteTransition.
+func applyQuoteTransition(ctx context.Context, store Store, q *Quote, to QuoteStatus, actor ID) (*Quote, error) {
+	id, from := q.Id, quoteStatus(q)
+	now := time.Now()
+	var err error
+	switch to {
+	case QuoteStatusSent:
+		_, err = store.UpdateQuoteSentOn(ctx, id, &now)
+		if err != nil {
+			return nil, err
+		}
+		_, err = assignQuoteNumber(ctx, store, q, true)
+		if err != nil {
+			return nil, err
+		}
+	case QuoteStatusAccepted:
+		_, err = store.UpdateQuoteSoldOn(ctx, id, &now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if to == QuoteStatusAccepted || to == QuoteStatusVoid {
+		_, err = store.LockQuote(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return store.UpdateQuoteStatus(ctx, id, to, QuoteStatusChange{From: from, To: to, Actor: actor, On: now})
+}
@@ -0,0 +1,55 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionQuote(t *testing.T) {
+	testCases := []struct {
+		from     QuoteStatus
+		to       QuoteStatus
+		expected bool
+	}{
+		{from: QuoteStatusDraft, to: QuoteStatusSent, expected: true},
+		{from: QuoteStatusDraft, to: QuoteStatusAccepted, expected: false},
+		{from: QuoteStatusSent, to: QuoteStatusViewed, expected: true},
+		{from: QuoteStatusViewed, to: QuoteStatusAccepted, expected: true},
+		{from: QuoteStatusAccepted, to: QuoteStatusDraft, expected: false},
+		{from: QuoteStatusAccepted, to: QuoteStatusConverted, expected: true},
+		{from: QuoteStatusDeclined, to: QuoteStatusDraft, expected: true},
+		{from: QuoteStatusConverted, to: QuoteStatusVoid, expected: false},
+		{from: QuoteStatusVoid, to: QuoteStatusDraft, expected: false},
+		{from: QuoteStatusSent, to: QuoteStatusSent, expected: false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.from+"->"+tc.to, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, canTransitionQuote(tc.from, tc.to))
+		})
+	}
+}
+
+func TestQuoteStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		quote    *Quote
+		expected QuoteStatus
+	}{
+		{name: "status set", quote: &Quote{Status: QuoteStatusViewed}, expected: QuoteStatusViewed},
+		{name: "no status", quote: &Quote{}, expected: QuoteStatusDraft},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, quoteStatus(tc.quote))
+		})
+	}
+}
@@ -103,6 +103,7 @@ type Store interface {
 	AddCustomValueConfigDescriptor(ctx context.Context, id ID, key ID, label string, defaultValue string, valueType CustomValueType) (*CustomValueConfig, error)
 	UpdateCustomValueConfigDescriptor(ctx context.Context, id ID, key ID, label string, defaultValue string) (*CustomValueConfig, error)
 	DeleteCustomValueConfigDescriptor(ctx context.Context, id ID, key ID) (*CustomValueConfig, error)
+	UpdateCustomValueConfigPriceFormula(ctx context.Context, id ID, key *ID) (*CustomValueConfig, error)
 	DeleteCustomValueConfig(ctx context.Context, id ID) error
 
 	// ////////////
@@ -119,36 +120,73 @@ type Store interface {
 	// QUOTE
 	// ////////////
 
-	CreateQuote(ctx context.Context) (*Quote, error)
-	CreateDuplicateQuote(ctx context.Context, quoteId ID) (*Quote, error)
+	CreateQuote(ctx context.Context, createdBy ID) (*Quote, error)
+	CreateDuplicateQuote(ctx context.Context, quoteId ID, createdBy ID) (*Quote, error)
 	GetQuote(ctx context.Context, id ID) (*Quote, error)
+	ListQuotes(ctx context.Context, filter QuoteFilter) (*QuotePage, error)
 	UpdateQuoteCode(ctx context.Context, id ID, code string) (*Quote, error)
 	UpdateQuoteOrderNumber(ctx context.Context, id ID, orderNumber string) (*Quote, error)
 	UpdateQuoteLogoId(ctx context.Context, id ID, logoId ID) (*Quote, error)
 	UpdateQuoteIssueDate(ctx context.Context, id ID, issueDate *time.Time) (*Quote, error)
 	UpdateQuoteExpirationDate(ctx context.Context, id ID, expirationDate *time.Time) (*Quote, error)
+	UpdateQuoteColors(ctx context.Context, id ID, backgroundColor, textColor string) (*Quote, error)
 	UpdateQuotePaymentTerms(ctx context.Context, id ID, paymentTerms string) (*Quote, error)
 	UpdateQuoteNotes(ctx context.Context, id ID, notes string) (*Quote, error)
 	UpdateQuoteSenderId(ctx context.Context, id ID, contactId ID) (*Quote, error)
 	UpdateQuoteBillToId(ctx context.Context, id ID, contactId ID) (*Quote, error)
 	UpdateQuoteShipToId(ctx context.Context, id ID, contactId ID) (*Quote, error)
+	UpdateQuoteCustomerId(ctx context.Context, id ID, customerId ID) (*Quote, error)
 	UpdateQuoteSubTotal(ctx context.Context, id ID, subTotal int) (*Quote, error)
 	UpdateQuoteTotal(ctx context.Context, id ID, total int) (*Quote, error)
 	UpdateQuoteBalanceDue(ctx context.Context, id ID, balanceDue int) (*Quote, error)
 	UpdateQuoteBalancePercentDue(ctx context.Context, id ID, balancePercentDue int) (*Quote, error)
 	UpdateQuoteBalanceDueOn(ctx context.Context, id ID, balanceDueOn *time.Time) (*Quote, error)
 	UpdateQuotePayUrl(ctx context.Context, id ID, payUrl string) (*Quote, error)
-	UpdateQuoteSent(ctx context.Context, id ID, sent bool) (*Quote, error)
 	UpdateQuoteSentOn(ctx context.Context, id ID, sentOn *time.Time) (*Quote, error)
-	UpdateQuoteSold(ctx context.Context, id ID, sold bool) (*Quote, error)
 	UpdateQuoteSoldOn(ctx context.Context, id ID, soldOn *time.Time) (*Quote, error)
+	UpdateQuoteStatus(ctx context.Context, id ID, status QuoteStatus, change QuoteStatusChange) (*Quote, error)
+	// ListOverdueQuotes returns up to limit sent or viewed quotes whose expiration date is before now
+	ListOverdueQuotes(ctx context.Context, now time.Time, limit int) ([]*Quote, error)
+	// ExpireQuote moves an overdue quote to expired in one step, returning
+	// InvalidQuoteStatusError when it is no longer overdue from change.From
+	ExpireQuote(ctx context.Context, id ID, change QuoteStatusChange, lock bool) (*Quote, error)
 	LockQuote(ctx context.Context, id ID) (*Quote, error)
+	UnlockQuote(ctx context.Context, id ID, unlock QuoteUnlock) (*Quote, error)
 	DeleteQuote(ctx context.Context, id ID) (*Quote, error)
 
+	CreateQuoteRevision(ctx context.Context, revision QuoteRevision) (*QuoteRevision, error)
+	GetQuoteRevision(ctx context.Context, id ID) (*QuoteRevision, error)
+	GetQuoteRevisions(ctx context.Context, quoteId ID) ([]*QuoteRevision, error)
+
+	CreateQuoteTemplate(ctx context.Context, template QuoteTemplate) (*QuoteTemplate, error)
+	GetQuoteTemplate(ctx context.Context, id ID) (*QuoteTemplate, error)
+	GetQuoteTemplates(ctx context.Context) ([]*QuoteTemplate, error)
+	SearchQuoteTemplates(ctx context.Context, search string) ([]*QuoteTemplate, error)
+	UpdateQuoteTemplateInfo(ctx context.Context, id ID, name, description string) (*QuoteTemplate, error)
+	DeleteQuoteTemplate(ctx context.Context, id ID) error
+
+	SetQuoteNumberSequence(ctx context.Context, seq QuoteNumberSequence) (*QuoteNumberSequence, error)
+	GetQuoteNumberSequence(ctx context.Context) (*QuoteNumberSequence, error)
+	AllocateQuoteNumber(ctx context.Context, year int) (*QuoteNumberSequence, error)
+
+	SetQuotePolicy(ctx context.Context, policy QuotePolicy) (*QuotePolicy, error)
+	GetQuotePolicy(ctx context.Context) (*QuotePolicy, error)
+
 	QuoteAddLineItem(ctx context.Context, id ID, lineItemId ID) (*Quote, error)
 	QuoteRemoveLineItem(ctx context.Context, id ID, lineItemId ID) (*Quote, error)
 	QuoteAddAdjustment(ctx context.Context, id ID, adjustmentId ID) (*Quote, error)
 	QuoteRemoveAdjustment(ctx context.Context, id ID, adjustmentId ID) (*Quote, error)
+	QuoteAddOption(ctx context.Context, id ID, name, description string) (*QuoteOption, error)
+	UpdateQuoteOptions(ctx context.Context, id ID, options []QuoteOption) (*Quote, error)
+	UpdateQuoteChosenOptionId(ctx context.Context, id ID, optionId ID) (*Quote, error)
+	UpdateQuoteAcceptance(ctx context.Context, id ID, acceptance *QuoteAcceptance) (*Quote, error)
+	UpdateQuotePaymentSchedule(ctx context.Context, id ID, schedule []PaymentMilestone) (*Quote, error)
+	UpdateQuoteChangeOrder(ctx context.Context, id ID, originalId ID, number int) (*Quote, error)
+	// AllocateChangeOrderNumber hands out the next change order number of the
+	// original quote, never the same number twice
+	AllocateChangeOrderNumber(ctx context.Context, originalId ID) (int, error)
+	UpdateQuoteApprovals(ctx context.Context, id ID, approvals []QuoteApproval) (*Quote, error)
+	UpdateQuoteDiscountRole(ctx context.Context, id ID, role Role) (*Quote, error)
 
 	// ////////////
 	// LINE ITEM
@@ -156,15 +194,26 @@ type Store interface {
 
 	CreateLineItem(ctx context.Context, quoteId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error)
 	CreateSubLineItem(ctx context.Context, quoteId, parentId ID, description string, quantity, unitPrice int, amount *int) (*LineItem, error)
-	CreateDuplicateLineItem(ctx context.Context, id ID) (*LineItem, error)
+	CreateDuplicateLineItem(ctx context.Context, id ID, quoteId ID, parentId *ID) (*LineItem, error)
 	GetLineItem(ctx context.Context, id ID) (*LineItem, error)
 	MoveLineItem(ctx context.Context, id ID, parentId *ID, index *int) (*LineItem, error)
 	UpdateLineItemImage(ctx context.Context, id ID, imageId *ID) (*LineItem, error)
 	UpdateLineItemDescription(ctx context.Context, id ID, description string) (*LineItem, error)
 	UpdateLineItemQuantity(ctx context.Context, id ID, quantity int, prefix, suffix string) (*LineItem, error)
 	UpdateLineItemUnitPrice(ctx context.Context, id ID, unitPrice int, prefix, suffix string) (*LineItem, error)
+	UpdateLineItemUnitCost(ctx context.Context, id ID, unitCost int) (*LineItem, error)
 	UpdateLineItemAmount(ctx context.Context, id ID, amount *int, prefix, suffix string) (*LineItem, error)
 	UpdateLineItemOpen(ctx context.Context, id ID, open bool) (*LineItem, error)
+	UpdateLineItemName(ctx context.Context, id ID, name string) (*LineItem, error)
+	UpdateLineItemSource(ctx context.Context, id ID, itemId, priceId *ID) (*LineItem, error)
+	UpdateLineItemHideFromCustomer(ctx context.Context, id ID, hideFromCustomer bool) (*LineItem, error)
+	UpdateLineItemOptional(ctx context.Context, id ID, optional bool) (*LineItem, error)
+	UpdateLineItemAlternateGroup(ctx context.Context, id ID, alternateGroup string) (*LineItem, error)
+	UpdateLineItemSelected(ctx context.Context, id ID, selected bool) (*LineItem, error)
+	UpdateLineItemOptionId(ctx context.Context, id ID, optionId ID) (*LineItem, error)
+	UpdateLineItemRemovesLineItemId(ctx context.Context, id ID, removesLineItemId ID) (*LineItem, error)
+	LineItemAddSubItem(ctx context.Context, id ID, subItemId ID) (*LineItem, error)
+	LineItemRemoveSubItem(ctx context.Context, id ID, subItemId ID) (*LineItem, error)
 	DeleteLineItem(ctx context.Context, id ID) error
 
 	// ////////////
@@ -174,13 +223,59 @@ type Store interface {
 	CreateAdjustment(ctx context.Context, quoteId ID, description string, amount int, adjustmentType AdjustmentType) (*Adjustment, error)
 	GetAdjustment(ctx context.Context, id ID) (*Adjustment, error)
 	UpdateAdjustment(ctx context.Context, id ID, description string, amount int, adjustmentType AdjustmentType) (*Adjustment, error)
+	UpdateAdjustmentOptionId(ctx context.Context, id ID, optionId ID) (*Adjustment, error)
 	RemoveAdjustment(ctx context.Context, id ID) error
 
 	// ////////////
 	// CONTACT
 	// ////////////
 
+	CreateContact(ctx context.Context, contact Contact) (*Contact, error)
 	GetContact(ctx context.Context, id ID) (*Contact, error)
+	GetContacts(ctx context.Context) ([]*Contact, error)
+	SearchContacts(ctx context.Context, search string) ([]*Contact, error)
+	UpdateContact(ctx context.Context, contact Contact) (*Contact, error)
+	DeleteContact(ctx context.Context, id ID) error
+	CreateDuplicateContact(ctx context.Context, id ID) (*Contact, error)
+
+	// ////////////
+	// CUSTOMER
+	// ////////////
+
+	CreateCustomer(ctx context.Context, customer Customer) (*Customer, error)
+	GetCustomer(ctx context.Context, id ID) (*Customer, error)
+	GetCustomers(ctx context.Context) ([]*Customer, error)
+	SearchCustomers(ctx context.Context, search string) ([]*Customer, error)
+	UpdateCustomerInfo(ctx context.Context, id ID, name string, tags []string, notes string) (*Customer, error)
+	CustomerAddContact(ctx context.Context, id ID, contactId ID) (*Customer, error)
+	CustomerRemoveContact(ctx context.Context, id ID, contactId ID) (*Customer, error)
+	CustomerAddAddress(ctx context.Context, id ID, contactId ID) (*Customer, error)
+	CustomerRemoveAddress(ctx context.Context, id ID, contactId ID) (*Customer, error)
+	DeleteCustomer(ctx context.Context, id ID) error
+
+	// ////////////
+	// PAYMENT
+	// ////////////
+
+	CreatePayment(ctx context.Context, payment Payment) (*Payment, error)
+	GetPayment(ctx context.Context, id ID) (*Payment, error)
+	GetQuotePayments(ctx context.Context, quoteId ID) ([]*Payment, error)
+	DeletePayment(ctx context.Context, id ID) error
+
+	// ////////////
+	// INVOICE
+	// ////////////
+
+	CreateInvoice(ctx context.Context, invoice Invoice) (*Invoice, error)
+	GetInvoice(ctx context.Context, id ID) (*Invoice, error)
+	GetQuoteInvoices(ctx context.Context, quoteId ID) ([]*Invoice, error)
+	UpdateInvoiceDueDate(ctx context.Context, id ID, dueDate *time.Time) (*Invoice, error)
+	UpdateInvoicePaymentTerms(ctx context.Context, id ID, paymentTerms string) (*Invoice, error)
+	DeleteInvoice(ctx context.Context, id ID) error
+
+	SetInvoiceNumberSequence(ctx context.Context, seq InvoiceNumberSequence) (*InvoiceNumberSequence, error)
+	GetInvoiceNumberSequence(ctx context.Context) (*InvoiceNumberSequence, error)
+	AllocateInvoiceNumber(ctx context.Context, year int) (*InvoiceNumberSequence, error)
 
 	// ////////////
 	// HELPER
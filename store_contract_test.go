@@ -65,8 +65,8 @@ func TestPostgresStoreContract(t *testing.T) {
 		t.Helper()
 		tables := []string{
 			"pricebooks", "categories", "items", "tags",
-			"custom_value_configs", "images", "quotes",
-			"line_items", "adjustments", "contacts",
+			"custom_value_configs", "images", "quotes", "quote_revisions", "quote_templates", "quote_number_sequences", "quote_policies",
+			"line_items", "adjustments", "contacts", "customers", "payments", "invoices", "invoice_number_sequences",
 		}
 		for _, tbl := range tables {
 			_, err := pool.Exec(ctx, "TRUNCATE TABLE "+tbl+" CASCADE")
@@ -694,6 +694,29 @@ func runStoreContractTests(
 		assert.Error(t, err)
 	})
 
+	t.Run("CustomValueConfig/PriceFormula", func(t *testing.T) {
+		reset(t)
+		cvc, _ := store.CreateCustomValueConfig(ctx, "Labor", "")
+		_, _ = store.AddCustomValueConfigDescriptor(ctx, cvc.Id, "hours", "Hours", "1", CustomValueTypeNumber)
+		_, _ = store.AddCustomValueConfigDescriptor(ctx, cvc.Id, "suggested", "Suggested", "hours * 9000", CustomValueTypeFormula)
+
+		key := ID("suggested")
+		updated, err := store.UpdateCustomValueConfigPriceFormula(ctx, cvc.Id, &key)
+		require.NoError(t, err)
+		require.NotNil(t, updated.PriceFormulaKey)
+		assert.Equal(t, key, *updated.PriceFormulaKey)
+
+		got, err := store.GetCustomValueConfig(ctx, cvc.Id)
+		require.NoError(t, err)
+		require.NotNil(t, got.PriceFormulaKey)
+		assert.Equal(t, key, *got.PriceFormulaKey)
+		assert.Equal(t, CustomValueTypeFormula, got.Descriptors[1].ValueType)
+
+		cleared, err := store.UpdateCustomValueConfigPriceFormula(ctx, cvc.Id, nil)
+		require.NoError(t, err)
+		assert.Nil(t, cleared.PriceFormulaKey)
+	})
+
 	t.Run("CustomValueConfig/ClearFromPricebook", func(t *testing.T) {
 		reset(t)
 		cvc, _ := store.CreateCustomValueConfig(ctx, "Specs", "")
@@ -753,7 +776,7 @@ func runStoreContractTests(
 
 	t.Run("Quote/Create", func(t *testing.T) {
 		reset(t)
-		q, err := store.CreateQuote(ctx)
+		q, err := store.CreateQuote(ctx, "")
 		require.NoError(t, err)
 		assert.NotEmpty(t, q.Id)
 		assert.Empty(t, q.LineItemIds)
@@ -763,7 +786,7 @@ func runStoreContractTests(
 
 	t.Run("Quote/Get", func(t *testing.T) {
 		reset(t)
-		created, _ := store.CreateQuote(ctx)
+		created, _ := store.CreateQuote(ctx, "")
 
 		got, err := store.GetQuote(ctx, created.Id)
 		require.NoError(t, err)
@@ -772,7 +795,7 @@ func runStoreContractTests(
 
 	t.Run("Quote/UpdateFields", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 
 		q2, err := store.UpdateQuoteCode(ctx, q.Id, "Q-001")
 		require.NoError(t, err)
@@ -791,16 +814,131 @@ func runStoreContractTests(
 
 	t.Run("Quote/Lock", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 
 		locked, err := store.LockQuote(ctx, q.Id)
 		require.NoError(t, err)
 		assert.True(t, locked.Locked)
 	})
 
+	t.Run("Quote/LockEnforced", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		li, _ := store.CreateLineItem(ctx, q.Id, "Widget", 100, 1000, nil)
+		a, _ := store.CreateAdjustment(ctx, q.Id, "Discount", -500, AdjustmentTypeFlat)
+		_, err := store.LockQuote(ctx, q.Id)
+		require.NoError(t, err)
+
+		var lockedErr *QuoteLockedError
+		_, err = store.UpdateQuoteNotes(ctx, q.Id, "changed")
+		require.ErrorAs(t, err, &lockedErr)
+		assert.Equal(t, q.Id, lockedErr.QuoteId)
+
+		_, err = store.CreateLineItem(ctx, q.Id, "Another", 100, 1000, nil)
+		assert.ErrorAs(t, err, &lockedErr)
+		_, err = store.UpdateLineItemDescription(ctx, li.Id, "changed")
+		assert.ErrorAs(t, err, &lockedErr)
+		_, err = store.MoveLineItem(ctx, li.Id, nil, nil)
+		assert.ErrorAs(t, err, &lockedErr)
+		assert.ErrorAs(t, store.DeleteLineItem(ctx, li.Id), &lockedErr)
+		_, err = store.UpdateAdjustment(ctx, a.Id, "Tax", 800, AdjustmentTypePercent)
+		assert.ErrorAs(t, err, &lockedErr)
+		_, err = store.QuoteAddLineItem(ctx, q.Id, li.Id)
+		assert.ErrorAs(t, err, &lockedErr)
+
+		// locking again is allowed
+		_, err = store.LockQuote(ctx, q.Id)
+		require.NoError(t, err)
+	})
+
+	t.Run("Quote/Unlock", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		_, _ = store.LockQuote(ctx, q.Id)
+
+		unlocked, err := store.UnlockQuote(ctx, q.Id, QuoteUnlock{Actor: "user-1", Reason: "customer changed scope", On: time.Now()})
+		require.NoError(t, err)
+		assert.False(t, unlocked.Locked)
+		require.Len(t, unlocked.UnlockHistory, 1)
+		assert.Equal(t, "customer changed scope", unlocked.UnlockHistory[0].Reason)
+
+		_, err = store.UpdateQuoteNotes(ctx, q.Id, "changed")
+		require.NoError(t, err)
+	})
+
+	t.Run("Quote/Status", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		assert.Equal(t, QuoteStatusDraft, q.Status)
+		assert.Empty(t, q.StatusHistory)
+
+		now := time.Now()
+		q2, err := store.UpdateQuoteStatus(ctx, q.Id, QuoteStatusSent, QuoteStatusChange{From: QuoteStatusDraft, To: QuoteStatusSent, Actor: "user-1", On: now})
+		require.NoError(t, err)
+		assert.Equal(t, QuoteStatusSent, q2.Status)
+
+		q3, err := store.UpdateQuoteStatus(ctx, q.Id, QuoteStatusViewed, QuoteStatusChange{From: QuoteStatusSent, To: QuoteStatusViewed, Actor: "user-2", On: now})
+		require.NoError(t, err)
+		assert.Equal(t, QuoteStatusViewed, q3.Status)
+		require.Len(t, q3.StatusHistory, 2)
+		assert.Equal(t, ID("user-1"), q3.StatusHistory[0].Actor)
+		assert.Equal(t, QuoteStatusViewed, q3.StatusHistory[1].To)
+	})
+
+	t.Run("Quote/Expire", func(t *testing.T) {
+		reset(t)
+		now := time.Now()
+		past := now.Add(-time.Hour)
+		future := now.Add(time.Hour)
+		overdue, _ := store.CreateQuote(ctx, "")
+		_, _ = store.UpdateQuoteExpirationDate(ctx, overdue.Id, &past)
+		_, _ = store.UpdateQuoteStatus(ctx, overdue.Id, QuoteStatusSent, QuoteStatusChange{From: QuoteStatusDraft, To: QuoteStatusSent, On: now})
+		current, _ := store.CreateQuote(ctx, "")
+		_, _ = store.UpdateQuoteExpirationDate(ctx, current.Id, &future)
+		_, _ = store.UpdateQuoteStatus(ctx, current.Id, QuoteStatusSent, QuoteStatusChange{From: QuoteStatusDraft, To: QuoteStatusSent, On: now})
+		draft, _ := store.CreateQuote(ctx, "")
+		_, _ = store.UpdateQuoteExpirationDate(ctx, draft.Id, &past)
+
+		quotes, err := store.ListOverdueQuotes(ctx, now, 10)
+		require.NoError(t, err)
+		require.Len(t, quotes, 1)
+		assert.Equal(t, overdue.Id, quotes[0].Id)
+
+		change := QuoteStatusChange{From: QuoteStatusSent, To: QuoteStatusExpired, Actor: ExpirationSweepActor, On: now}
+		expired, err := store.ExpireQuote(ctx, overdue.Id, change, true)
+		require.NoError(t, err)
+		assert.Equal(t, QuoteStatusExpired, expired.Status)
+		assert.True(t, expired.Locked)
+		assert.True(t, expired.ExpiryLocked)
+		require.Len(t, expired.StatusHistory, 2)
+		assert.Equal(t, ExpirationSweepActor, expired.StatusHistory[1].Actor)
+
+		_, err = store.ExpireQuote(ctx, overdue.Id, change, true)
+		assert.ErrorIs(t, err, InvalidQuoteStatusError, "a quote is only expired once")
+		_, err = store.ExpireQuote(ctx, current.Id, change, false)
+		assert.ErrorIs(t, err, InvalidQuoteStatusError)
+
+		quotes, err = store.ListOverdueQuotes(ctx, now, 10)
+		require.NoError(t, err)
+		assert.Empty(t, quotes)
+
+		unlocked, err := store.UnlockQuote(ctx, overdue.Id, QuoteUnlock{Reason: "re-issued", On: now})
+		require.NoError(t, err)
+		assert.False(t, unlocked.ExpiryLocked)
+
+		held, _ := store.CreateQuote(ctx, "")
+		_, _ = store.UpdateQuoteExpirationDate(ctx, held.Id, &past)
+		_, _ = store.UpdateQuoteStatus(ctx, held.Id, QuoteStatusSent, QuoteStatusChange{From: QuoteStatusDraft, To: QuoteStatusSent, On: now})
+		_, _ = store.LockQuote(ctx, held.Id)
+		expired, err = store.ExpireQuote(ctx, held.Id, change, true)
+		require.NoError(t, err)
+		assert.True(t, expired.Locked)
+		assert.False(t, expired.ExpiryLocked, "a quote locked before the sweep is not the sweep's to unlock")
+	})
+
 	t.Run("Quote/Delete", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 
 		deleted, err := store.DeleteQuote(ctx, q.Id)
 		require.NoError(t, err)
@@ -809,23 +947,385 @@ func runStoreContractTests(
 
 	t.Run("Quote/Duplicate", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 		_, _ = store.UpdateQuoteCode(ctx, q.Id, "ORIG")
+		_, _ = store.UpdateQuoteNotes(ctx, q.Id, "Thanks!")
+		issued := time.Now()
+		_, _ = store.UpdateQuoteIssueDate(ctx, q.Id, &issued)
 
-		dup, err := store.CreateDuplicateQuote(ctx, q.Id)
+		dup, err := store.CreateDuplicateQuote(ctx, q.Id, "")
 		require.NoError(t, err)
 		assert.NotEqual(t, q.Id, dup.Id)
-		assert.Equal(t, "ORIG", dup.Code)
+		assert.Empty(t, dup.Code)
+		assert.Nil(t, dup.IssueDate)
+		assert.Equal(t, "Thanks!", dup.Notes)
+		assert.Equal(t, QuoteStatusDraft, dup.Status)
 		assert.Empty(t, dup.LineItemIds)
 	})
 
+	t.Run("Quote/Revisions", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		li, _ := store.CreateLineItem(ctx, q.Id, "Widget", 100, 1000, nil)
+
+		r1, err := store.CreateQuoteRevision(ctx, QuoteRevision{QuoteId: q.Id, Quote: *q, LineItems: []*LineItem{li}})
+		require.NoError(t, err)
+		assert.NotEmpty(t, r1.Id)
+		assert.Equal(t, 1, r1.Number)
+
+		r2, err := store.CreateQuoteRevision(ctx, QuoteRevision{QuoteId: q.Id, Quote: *q})
+		require.NoError(t, err)
+		assert.Equal(t, 2, r2.Number)
+
+		got, err := store.GetQuoteRevision(ctx, r1.Id)
+		require.NoError(t, err)
+		assert.Equal(t, q.Id, got.QuoteId)
+		require.Len(t, got.LineItems, 1)
+		assert.Equal(t, "Widget", got.LineItems[0].Description)
+
+		all, err := store.GetQuoteRevisions(ctx, q.Id)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, r1.Id, all[0].Id)
+		assert.Equal(t, r2.Id, all[1].Id)
+	})
+
+	t.Run("Quote/List", func(t *testing.T) {
+		reset(t)
+		i := func(num int) *int { return &num }
+		id := func(str string) *ID { return &str }
+
+		a, _ := store.CreateQuote(ctx, "alice")
+		_, _ = store.UpdateQuoteCode(ctx, a.Id, "EST-2026-00001")
+		_, _ = store.UpdateQuoteTotal(ctx, a.Id, 10000)
+		_, _ = store.UpdateQuoteBillToId(ctx, a.Id, "contact-1")
+		b, _ := store.CreateQuote(ctx, "bob")
+		_, _ = store.UpdateQuoteCode(ctx, b.Id, "EST-2026-00002")
+		_, _ = store.UpdateQuoteTotal(ctx, b.Id, 50000)
+		_, _ = store.UpdateQuoteStatus(ctx, b.Id, QuoteStatusSent, QuoteStatusChange{From: QuoteStatusDraft, To: QuoteStatusSent})
+		c, _ := store.CreateQuote(ctx, "alice")
+		_, _ = store.UpdateQuoteOrderNumber(ctx, c.Id, "PO-77")
+		_, _ = store.UpdateQuoteTotal(ctx, c.Id, 90000)
+		deleted, _ := store.CreateQuote(ctx, "alice")
+		_, _ = store.DeleteQuote(ctx, deleted.Id)
+
+		ids := func(page *QuotePage) []ID {
+			var out []ID
+			for _, q := range page.Quotes {
+				out = append(out, q.Id)
+			}
+			return out
+		}
+
+		page, err := store.ListQuotes(ctx, QuoteFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.Total)
+		assert.Equal(t, []ID{a.Id, b.Id, c.Id}, ids(page))
+
+		page, err = store.ListQuotes(ctx, QuoteFilter{IncludeDeleted: true})
+		require.NoError(t, err)
+		assert.Equal(t, 4, page.Total)
+
+		page, err = store.ListQuotes(ctx, QuoteFilter{Statuses: []QuoteStatus{QuoteStatusSent}})
+		require.NoError(t, err)
+		assert.Equal(t, []ID{b.Id}, ids(page))
+
+		page, err = store.ListQuotes(ctx, QuoteFilter{CreatedBy: id("alice"), Sort: QuoteSortTotal, Descending: true})
+		require.NoError(t, err)
+		assert.Equal(t, []ID{c.Id, a.Id}, ids(page))
+
+		page, err = store.ListQuotes(ctx, QuoteFilter{MinTotal: i(20000), MaxTotal: i(90000)})
+		require.NoError(t, err)
+		assert.Equal(t, []ID{b.Id, c.Id}, ids(page))
+
+		page, err = store.ListQuotes(ctx, QuoteFilter{Search: "po-7"})
+		require.NoError(t, err)
+		assert.Equal(t, []ID{c.Id}, ids(page))
+
+		page, err = store.ListQuotes(ctx, QuoteFilter{BillToId: id("contact-1")})
+		require.NoError(t, err)
+		assert.Equal(t, []ID{a.Id}, ids(page))
+
+		page, err = store.ListQuotes(ctx, QuoteFilter{Limit: 2, Offset: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.Total)
+		assert.Equal(t, []ID{c.Id}, ids(page))
+
+		_, err = store.ListQuotes(ctx, QuoteFilter{Sort: "notes"})
+		assert.ErrorIs(t, err, InvalidQuoteSortError)
+	})
+
+	t.Run("Quote/Colors", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+
+		updated, err := store.UpdateQuoteColors(ctx, q.Id, "#003366", "#ffffff")
+		require.NoError(t, err)
+		assert.Equal(t, "#003366", updated.PrimaryBackgroundColor)
+		assert.Equal(t, "#ffffff", updated.PrimaryTextColor)
+	})
+
+	t.Run("Quote/Options", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		assert.Empty(t, q.Options)
+
+		good, err := store.QuoteAddOption(ctx, q.Id, "Good", "Builder grade")
+		require.NoError(t, err)
+		assert.NotEmpty(t, good.Id)
+		best, err := store.QuoteAddOption(ctx, q.Id, "Best", "")
+		require.NoError(t, err)
+
+		got, err := store.GetQuote(ctx, q.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []QuoteOption{*good, *best}, got.Options)
+
+		renamed := []QuoteOption{*good, {Id: best.Id, Name: "Premium"}}
+		updated, err := store.UpdateQuoteOptions(ctx, q.Id, renamed)
+		require.NoError(t, err)
+		assert.Equal(t, renamed, updated.Options)
+
+		updated, err = store.UpdateQuoteChosenOptionId(ctx, q.Id, best.Id)
+		require.NoError(t, err)
+		assert.Equal(t, best.Id, updated.ChosenOptionId)
+
+		li, _ := store.CreateLineItem(ctx, q.Id, "Furnace", 100, 300000, nil)
+		li, err = store.UpdateLineItemOptionId(ctx, li.Id, good.Id)
+		require.NoError(t, err)
+		assert.Equal(t, good.Id, li.OptionId)
+		a, _ := store.CreateAdjustment(ctx, q.Id, "Rebate", -5000, AdjustmentTypeFlat)
+		a, err = store.UpdateAdjustmentOptionId(ctx, a.Id, best.Id)
+		require.NoError(t, err)
+		assert.Equal(t, best.Id, a.OptionId)
+
+		gotLi, _ := store.GetLineItem(ctx, li.Id)
+		assert.Equal(t, good.Id, gotLi.OptionId)
+		gotA, _ := store.GetAdjustment(ctx, a.Id)
+		assert.Equal(t, best.Id, gotA.OptionId)
+
+		dup, err := store.CreateDuplicateQuote(ctx, q.Id, "")
+		require.NoError(t, err)
+		assert.Equal(t, renamed, dup.Options)
+		assert.Empty(t, dup.ChosenOptionId)
+	})
+
+	t.Run("Quote/Acceptance", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		assert.Nil(t, q.Acceptance)
+
+		acceptance := &QuoteAcceptance{
+			Signature:      Signature{Name: "Jane Doe", IP: "203.0.113.7", SignedAt: time.Now().UTC().Truncate(time.Second)},
+			ContentHash:    "abc123",
+			RevisionNumber: 1,
+		}
+		updated, err := store.UpdateQuoteAcceptance(ctx, q.Id, acceptance)
+		require.NoError(t, err)
+		require.NotNil(t, updated.Acceptance)
+		assert.Equal(t, "Jane Doe", updated.Acceptance.Signature.Name)
+		assert.True(t, acceptance.Signature.SignedAt.Equal(updated.Acceptance.Signature.SignedAt))
+		assert.Equal(t, "abc123", updated.Acceptance.ContentHash)
+
+		dup, err := store.CreateDuplicateQuote(ctx, q.Id, "")
+		require.NoError(t, err)
+		assert.Nil(t, dup.Acceptance)
+	})
+
+	t.Run("Quote/PaymentSchedule", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		assert.Empty(t, q.PaymentSchedule)
+
+		dueOn := time.Now().UTC().Truncate(time.Second)
+		updated, err := store.UpdateQuotePaymentSchedule(ctx, q.Id, []PaymentMilestone{
+			{Description: "Deposit", Type: AdjustmentTypePercent, Amount: 30, DueOn: &dueOn},
+			{Id: "existing", Description: "Completion", Type: AdjustmentTypePercent, Amount: 70, Trigger: "on completion"},
+		})
+		require.NoError(t, err)
+		require.Len(t, updated.PaymentSchedule, 2)
+		assert.NotEmpty(t, updated.PaymentSchedule[0].Id)
+		assert.Equal(t, ID("existing"), updated.PaymentSchedule[1].Id)
+		assert.Equal(t, "on completion", updated.PaymentSchedule[1].Trigger)
+		require.NotNil(t, updated.PaymentSchedule[0].DueOn)
+		assert.True(t, dueOn.Equal(*updated.PaymentSchedule[0].DueOn))
+
+		dup, err := store.CreateDuplicateQuote(ctx, q.Id, "")
+		require.NoError(t, err)
+		assert.Equal(t, updated.PaymentSchedule, dup.PaymentSchedule)
+	})
+
+	t.Run("Quote/ChangeOrder", func(t *testing.T) {
+		reset(t)
+		original, _ := store.CreateQuote(ctx, "")
+		li, _ := store.CreateLineItem(ctx, original.Id, "Ceiling fan", 100, 25000, nil)
+		co, _ := store.CreateQuote(ctx, "")
+		_, _ = store.CreateQuote(ctx, "")
+
+		updated, err := store.UpdateQuoteChangeOrder(ctx, co.Id, original.Id, 1)
+		require.NoError(t, err)
+		assert.Equal(t, original.Id, updated.ChangeOrderOf)
+		assert.Equal(t, 1, updated.ChangeOrderNumber)
+
+		page, err := store.ListQuotes(ctx, QuoteFilter{ChangeOrderOf: &original.Id})
+		require.NoError(t, err)
+		require.Equal(t, 1, page.Total)
+		assert.Equal(t, co.Id, page.Quotes[0].Id)
+
+		other, _ := store.CreateQuote(ctx, "")
+		_, err = store.UpdateQuoteChangeOrder(ctx, other.Id, original.Id, 1)
+		assert.Error(t, err, "change order numbers are unique per original quote")
+
+		number, err := store.AllocateChangeOrderNumber(ctx, original.Id)
+		require.NoError(t, err)
+		assert.Equal(t, 2, number, "numbers carry on from change orders made before the counter")
+		number, err = store.AllocateChangeOrderNumber(ctx, original.Id)
+		require.NoError(t, err)
+		assert.Equal(t, 3, number)
+		_, err = store.AllocateChangeOrderNumber(ctx, "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		amount := -25000
+		credit, _ := store.CreateLineItem(ctx, co.Id, "Ceiling fan", 0, 0, &amount)
+		credit, err = store.UpdateLineItemRemovesLineItemId(ctx, credit.Id, li.Id)
+		require.NoError(t, err)
+		assert.Equal(t, li.Id, credit.RemovesLineItemId)
+		got, err := store.GetLineItem(ctx, credit.Id)
+		require.NoError(t, err)
+		assert.Equal(t, li.Id, got.RemovesLineItemId)
+	})
+
+	t.Run("Quote/Templates", func(t *testing.T) {
+		reset(t)
+		li := &LineItem{Id: "1", Description: "Water heater", Quantity: 100, UnitPrice: 90000}
+
+		tmpl, err := store.CreateQuoteTemplate(ctx, QuoteTemplate{
+			Name:         "Water heater replacement",
+			Description:  "Standard 50 gallon swap",
+			PaymentTerms: "Net 30",
+			LineItemIds:  []ID{li.Id},
+			LineItems:    []*LineItem{li},
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, tmpl.Id)
+		_, _ = store.CreateQuoteTemplate(ctx, QuoteTemplate{Name: "Furnace tune up"})
+
+		got, err := store.GetQuoteTemplate(ctx, tmpl.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "Water heater replacement", got.Name)
+		assert.Equal(t, "Net 30", got.PaymentTerms)
+		require.Len(t, got.LineItems, 1)
+		assert.Equal(t, "Water heater", got.LineItems[0].Description)
+
+		all, err := store.GetQuoteTemplates(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+
+		found, err := store.SearchQuoteTemplates(ctx, "gallon")
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, tmpl.Id, found[0].Id)
+
+		updated, err := store.UpdateQuoteTemplateInfo(ctx, tmpl.Id, "Water heater", "Any size")
+		require.NoError(t, err)
+		assert.Equal(t, "Water heater", updated.Name)
+		require.Len(t, updated.LineItems, 1)
+
+		require.NoError(t, store.DeleteQuoteTemplate(ctx, tmpl.Id))
+		all, err = store.GetQuoteTemplates(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("Quote/NumberSequence", func(t *testing.T) {
+		reset(t)
+
+		_, err := store.GetQuoteNumberSequence(ctx)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.AllocateQuoteNumber(ctx, 2026)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		seq, err := store.SetQuoteNumberSequence(ctx, QuoteNumberSequence{Pattern: "EST-{YYYY}-{N:5}", AssignOn: QuoteNumberOnCreate, ResetYearly: true})
+		require.NoError(t, err)
+		assert.Equal(t, 0, seq.Counter)
+
+		seq, err = store.AllocateQuoteNumber(ctx, 2025)
+		require.NoError(t, err)
+		assert.Equal(t, 1, seq.Counter)
+		seq, err = store.AllocateQuoteNumber(ctx, 2025)
+		require.NoError(t, err)
+		assert.Equal(t, 2, seq.Counter)
+
+		// reconfiguring keeps the counter
+		seq, err = store.SetQuoteNumberSequence(ctx, QuoteNumberSequence{Pattern: "Q{N}", AssignOn: QuoteNumberOnSend, ResetYearly: true})
+		require.NoError(t, err)
+		assert.Equal(t, 2, seq.Counter)
+		assert.Equal(t, "Q{N}", seq.Pattern)
+
+		seq, err = store.AllocateQuoteNumber(ctx, 2026)
+		require.NoError(t, err)
+		assert.Equal(t, 1, seq.Counter)
+		assert.Equal(t, 2026, seq.Year)
+
+		got, err := store.GetQuoteNumberSequence(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, QuoteNumberOnSend, got.AssignOn)
+		assert.Equal(t, 1, got.Counter)
+	})
+
+	t.Run("Quote/Policy", func(t *testing.T) {
+		reset(t)
+
+		_, err := store.GetQuotePolicy(ctx)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		policy, err := store.SetQuotePolicy(ctx, QuotePolicy{MinMargin: 30, MaxDiscount: map[Role]int{"tech": 10, AnyRole: 5}, ApproverRoles: []Role{"manager"}})
+		require.NoError(t, err)
+		assert.Equal(t, 30, policy.MinMargin)
+
+		// setting the policy again replaces it
+		_, err = store.SetQuotePolicy(ctx, QuotePolicy{MinMargin: 25, ApproverRoles: []Role{"manager", "owner"}})
+		require.NoError(t, err)
+		got, err := store.GetQuotePolicy(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 25, got.MinMargin)
+		assert.Empty(t, got.MaxDiscount)
+		assert.Equal(t, []Role{"manager", "owner"}, got.ApproverRoles)
+	})
+
+	t.Run("Quote/Approvals", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		assert.Empty(t, q.Approvals)
+
+		violations := []PolicyViolation{{Rule: PolicyRuleMaxDiscount, Role: "tech", Limit: 10, Actual: 20}}
+		q, err := store.UpdateQuoteApprovals(ctx, q.Id, []QuoteApproval{{Status: ApprovalStatusPending, Violations: violations, Total: 8000, RequestedBy: "u1", RequestedRole: "tech"}})
+		require.NoError(t, err)
+		require.Len(t, q.Approvals, 1)
+		assert.NotEmpty(t, q.Approvals[0].Id)
+		assert.Equal(t, violations, q.Approvals[0].Violations)
+
+		q, err = store.UpdateQuoteDiscountRole(ctx, q.Id, "tech")
+		require.NoError(t, err)
+		assert.Equal(t, "tech", q.DiscountRole)
+
+		got, err := store.GetQuote(ctx, q.Id)
+		require.NoError(t, err)
+		assert.Equal(t, q.Approvals, got.Approvals)
+		assert.Equal(t, "tech", got.DiscountRole)
+
+		dup, err := store.CreateDuplicateQuote(ctx, q.Id, "")
+		require.NoError(t, err)
+		assert.Empty(t, dup.Approvals, "approvals belong to the original")
+		assert.Equal(t, "tech", dup.DiscountRole, "the copied adjustments keep their role")
+	})
+
 	// ──────────────────────────────────────────────
 	// LINE ITEM
 	// ──────────────────────────────────────────────
 
 	t.Run("LineItem/CreateAndGet", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 
 		li, err := store.CreateLineItem(ctx, q.Id, "Install labor", 100, 5000, nil)
 		require.NoError(t, err)
@@ -844,7 +1344,7 @@ func runStoreContractTests(
 
 	t.Run("LineItem/CreateSub", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 		parent, _ := store.CreateLineItem(ctx, q.Id, "Parent", 100, 1000, nil)
 
 		sub, err := store.CreateSubLineItem(ctx, q.Id, parent.Id, "Sub item", 100, 500, nil)
@@ -855,19 +1355,31 @@ func runStoreContractTests(
 
 	t.Run("LineItem/Duplicate", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 		li, _ := store.CreateLineItem(ctx, q.Id, "Widget", 200, 3000, nil)
+		_, _ = store.UpdateLineItemName(ctx, li.Id, "Widget name")
+		sub, _ := store.CreateSubLineItem(ctx, q.Id, li.Id, "Sub", 100, 500, nil)
+		_, _ = store.LineItemAddSubItem(ctx, li.Id, sub.Id)
 
-		dup, err := store.CreateDuplicateLineItem(ctx, li.Id)
+		dup, err := store.CreateDuplicateLineItem(ctx, li.Id, q.Id, nil)
 		require.NoError(t, err)
 		assert.NotEqual(t, li.Id, dup.Id)
 		assert.Equal(t, li.Description, dup.Description)
 		assert.Equal(t, li.Quantity, dup.Quantity)
+		assert.Equal(t, "Widget name", dup.Name)
+		assert.Empty(t, dup.SubItemIds)
+
+		other, _ := store.CreateQuote(ctx, "")
+		subDup, err := store.CreateDuplicateLineItem(ctx, sub.Id, other.Id, &dup.Id)
+		require.NoError(t, err)
+		assert.Equal(t, other.Id, subDup.QuoteId)
+		require.NotNil(t, subDup.ParentId)
+		assert.Equal(t, dup.Id, *subDup.ParentId)
 	})
 
 	t.Run("LineItem/UpdateFields", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 		li, _ := store.CreateLineItem(ctx, q.Id, "Widget", 100, 1000, nil)
 
 		li2, err := store.UpdateLineItemDescription(ctx, li.Id, "New desc")
@@ -895,9 +1407,147 @@ func runStoreContractTests(
 		assert.True(t, li6.Open)
 	})
 
+	t.Run("LineItem/Source", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		li, _ := store.CreateLineItem(ctx, q.Id, "Widget", 100, 1000, nil)
+		assert.Empty(t, li.Name)
+		assert.Nil(t, li.SourceItemId)
+		assert.False(t, li.HideFromCustomer)
+
+		li2, err := store.UpdateLineItemName(ctx, li.Id, "Water Heater")
+		require.NoError(t, err)
+		assert.Equal(t, "Water Heater", li2.Name)
+
+		itemId, priceId := ID("item-1"), ID("price-1")
+		li3, err := store.UpdateLineItemSource(ctx, li.Id, &itemId, &priceId)
+		require.NoError(t, err)
+		require.NotNil(t, li3.SourceItemId)
+		require.NotNil(t, li3.SourcePriceId)
+
+		li4, err := store.UpdateLineItemHideFromCustomer(ctx, li.Id, true)
+		require.NoError(t, err)
+		assert.True(t, li4.HideFromCustomer)
+
+		got, err := store.GetLineItem(ctx, li.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "Water Heater", got.Name)
+		assert.Equal(t, itemId, *got.SourceItemId)
+		assert.Equal(t, priceId, *got.SourcePriceId)
+		assert.True(t, got.HideFromCustomer)
+	})
+
+	t.Run("LineItem/UnitCost", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		li, _ := store.CreateLineItem(ctx, q.Id, "Labor", 100, 15000, nil)
+		assert.Zero(t, li.UnitCost)
+
+		updated, err := store.UpdateLineItemUnitCost(ctx, li.Id, 6000)
+		require.NoError(t, err)
+		assert.Equal(t, 6000, updated.UnitCost)
+
+		dup, err := store.CreateDuplicateLineItem(ctx, li.Id, q.Id, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 6000, dup.UnitCost)
+
+		_, _ = store.LockQuote(ctx, q.Id)
+		updated, err = store.UpdateLineItemUnitCost(ctx, li.Id, 6500)
+		require.NoError(t, err, "cost can be corrected on a locked quote")
+		got, err := store.GetLineItem(ctx, li.Id)
+		require.NoError(t, err)
+		assert.Equal(t, 6500, got.UnitCost)
+	})
+
+	t.Run("LineItem/OptionalAndAlternate", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		li, _ := store.CreateLineItem(ctx, q.Id, "Gutter guards", 100, 45000, nil)
+		assert.False(t, li.Optional)
+		assert.Empty(t, li.AlternateGroup)
+		assert.False(t, li.Selected)
+
+		li2, err := store.UpdateLineItemOptional(ctx, li.Id, true)
+		require.NoError(t, err)
+		assert.True(t, li2.Optional)
+
+		li3, err := store.UpdateLineItemAlternateGroup(ctx, li.Id, "gutters")
+		require.NoError(t, err)
+		assert.Equal(t, "gutters", li3.AlternateGroup)
+
+		li4, err := store.UpdateLineItemSelected(ctx, li.Id, true)
+		require.NoError(t, err)
+		assert.True(t, li4.Selected)
+
+		got, err := store.GetLineItem(ctx, li.Id)
+		require.NoError(t, err)
+		assert.True(t, got.Optional)
+		assert.Equal(t, "gutters", got.AlternateGroup)
+		assert.True(t, got.Selected)
+
+		dup, err := store.CreateDuplicateLineItem(ctx, li.Id, q.Id, nil)
+		require.NoError(t, err)
+		assert.True(t, dup.Optional)
+		assert.Equal(t, "gutters", dup.AlternateGroup)
+		assert.True(t, dup.Selected)
+	})
+
+	t.Run("LineItem/AddRemoveSubItem", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		parent, _ := store.CreateLineItem(ctx, q.Id, "Parent", 100, 1000, nil)
+		sub, _ := store.CreateSubLineItem(ctx, q.Id, parent.Id, "Sub item", 100, 500, nil)
+
+		p2, err := store.LineItemAddSubItem(ctx, parent.Id, sub.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []ID{sub.Id}, p2.SubItemIds)
+
+		// Idempotent
+		p3, err := store.LineItemAddSubItem(ctx, parent.Id, sub.Id)
+		require.NoError(t, err)
+		assert.Len(t, p3.SubItemIds, 1)
+
+		p4, err := store.LineItemRemoveSubItem(ctx, parent.Id, sub.Id)
+		require.NoError(t, err)
+		assert.Empty(t, p4.SubItemIds)
+	})
+
+	t.Run("LineItem/Move", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		a, _ := store.CreateLineItem(ctx, q.Id, "A", 100, 1000, nil)
+		b, _ := store.CreateLineItem(ctx, q.Id, "B", 100, 1000, nil)
+		_, _ = store.QuoteAddLineItem(ctx, q.Id, a.Id)
+		_, _ = store.QuoteAddLineItem(ctx, q.Id, b.Id)
+
+		zero := 0
+		moved, err := store.MoveLineItem(ctx, b.Id, &a.Id, &zero)
+		require.NoError(t, err)
+		require.NotNil(t, moved.ParentId)
+		assert.Equal(t, a.Id, *moved.ParentId)
+
+		parent, err := store.GetLineItem(ctx, a.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []ID{b.Id}, parent.SubItemIds)
+
+		_, err = store.MoveLineItem(ctx, a.Id, &b.Id, nil)
+		assert.ErrorIs(t, err, LineItemCycleError)
+
+		moved, err = store.MoveLineItem(ctx, b.Id, nil, &zero)
+		require.NoError(t, err)
+		assert.Nil(t, moved.ParentId)
+
+		q2, err := store.GetQuote(ctx, q.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []ID{b.Id, a.Id}, q2.LineItemIds)
+		parent, err = store.GetLineItem(ctx, a.Id)
+		require.NoError(t, err)
+		assert.Empty(t, parent.SubItemIds)
+	})
+
 	t.Run("LineItem/Delete", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 		li, _ := store.CreateLineItem(ctx, q.Id, "Widget", 100, 1000, nil)
 
 		err := store.DeleteLineItem(ctx, li.Id)
@@ -913,7 +1563,7 @@ func runStoreContractTests(
 
 	t.Run("Quote/AddRemoveLineItem", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 		li, _ := store.CreateLineItem(ctx, q.Id, "Widget", 100, 1000, nil)
 
 		q2, err := store.QuoteAddLineItem(ctx, q.Id, li.Id)
@@ -936,7 +1586,7 @@ func runStoreContractTests(
 
 	t.Run("Adjustment/CreateGetUpdate", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 
 		a, err := store.CreateAdjustment(ctx, q.Id, "Discount", -500, AdjustmentTypeFlat)
 		require.NoError(t, err)
@@ -964,7 +1614,7 @@ func runStoreContractTests(
 
 	t.Run("Quote/AddRemoveAdjustment", func(t *testing.T) {
 		reset(t)
-		q, _ := store.CreateQuote(ctx)
+		q, _ := store.CreateQuote(ctx, "")
 		a, _ := store.CreateAdjustment(ctx, q.Id, "Tax", 100, AdjustmentTypeFlat)
 
 		q2, err := store.QuoteAddAdjustment(ctx, q.Id, a.Id)
@@ -976,6 +1626,242 @@ func runStoreContractTests(
 		assert.NotContains(t, q3.AdjustmentIds, a.Id)
 	})
 
+	// ──────────────────────────────────────────────
+	// CONTACT
+	// ──────────────────────────────────────────────
+
+	t.Run("Contact/CRUD", func(t *testing.T) {
+		reset(t)
+
+		c, err := store.CreateContact(ctx, Contact{Name: "Dana Smith", CompanyName: "Acme", Emails: []string{"dana@example.com"}})
+		require.NoError(t, err)
+		assert.NotEmpty(t, c.Id)
+		assert.Equal(t, org1, c.OrgId)
+		assert.Equal(t, []string{}, c.Phones)
+
+		got, err := store.GetContact(ctx, c.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "Dana Smith", got.Name)
+		assert.Equal(t, []string{"dana@example.com"}, got.Emails)
+
+		got.City = "Springfield"
+		got.Phones = []string{"(555) 010-0100"}
+		updated, err := store.UpdateContact(ctx, *got)
+		require.NoError(t, err)
+		assert.Equal(t, "Springfield", updated.City)
+
+		_, _ = store.CreateContact(ctx, Contact{Name: "Pat Jones"})
+		all, err := store.GetContacts(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, "Dana Smith", all[0].Name)
+
+		require.NoError(t, store.DeleteContact(ctx, c.Id))
+		all, err = store.GetContacts(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+		// quotes that use a deleted contact can still show it
+		_, err = store.GetContact(ctx, c.Id)
+		require.NoError(t, err)
+
+		_, err = store.GetContact(makeCtx(org1, "grp-2"), all[0].Id)
+		assert.Error(t, err)
+	})
+
+	t.Run("Contact/Search", func(t *testing.T) {
+		reset(t)
+		dana, _ := store.CreateContact(ctx, Contact{Name: "Dana Smith", Emails: []string{"dana@example.com"}, Phones: []string{"(555) 010-0100"}})
+		pat, _ := store.CreateContact(ctx, Contact{Name: "Pat Jones", CompanyName: "Acme Plumbing"})
+
+		for search, expected := range map[string]ID{
+			"smith":      dana.Id,
+			"acme":       pat.Id,
+			"DANA@":      dana.Id,
+			"555-010":    dana.Id,
+			"5550100100": dana.Id,
+		} {
+			found, err := store.SearchContacts(ctx, search)
+			require.NoError(t, err)
+			require.Len(t, found, 1, search)
+			assert.Equal(t, expected, found[0].Id, search)
+		}
+	})
+
+	// ──────────────────────────────────────────────
+	// CUSTOMER
+	// ──────────────────────────────────────────────
+
+	t.Run("Customer/CRUD", func(t *testing.T) {
+		reset(t)
+		dana, _ := store.CreateContact(ctx, Contact{Name: "Dana Smith", Emails: []string{"dana@example.com"}})
+		home, _ := store.CreateContact(ctx, Contact{Street: "12 Elm St", City: "Springfield"})
+
+		c, err := store.CreateCustomer(ctx, Customer{Name: "Smith Residence"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, c.Id)
+		assert.Equal(t, []ID{}, c.ContactIds)
+
+		c, err = store.CustomerAddContact(ctx, c.Id, dana.Id)
+		require.NoError(t, err)
+		c, err = store.CustomerAddContact(ctx, c.Id, dana.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []ID{dana.Id}, c.ContactIds)
+		c, err = store.CustomerAddAddress(ctx, c.Id, home.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []ID{home.Id}, c.AddressIds)
+
+		c, err = store.UpdateCustomerInfo(ctx, c.Id, "Smith Residence", []string{"vip"}, "Dog in the yard")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"vip"}, c.Tags)
+
+		got, err := store.GetCustomer(ctx, c.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "Dog in the yard", got.Notes)
+		assert.Equal(t, []ID{dana.Id}, got.ContactIds)
+
+		for _, search := range []string{"residence", "vip", "dana@", "elm st"} {
+			found, err := store.SearchCustomers(ctx, search)
+			require.NoError(t, err)
+			require.Len(t, found, 1, search)
+			assert.Equal(t, c.Id, found[0].Id, search)
+		}
+
+		c, err = store.CustomerRemoveContact(ctx, c.Id, dana.Id)
+		require.NoError(t, err)
+		assert.Empty(t, c.ContactIds)
+		c, err = store.CustomerRemoveAddress(ctx, c.Id, home.Id)
+		require.NoError(t, err)
+		assert.Empty(t, c.AddressIds)
+
+		require.NoError(t, store.DeleteCustomer(ctx, c.Id))
+		all, err := store.GetCustomers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+
+	t.Run("Customer/Quotes", func(t *testing.T) {
+		reset(t)
+		c, _ := store.CreateCustomer(ctx, Customer{Name: "Smith Residence"})
+		q, _ := store.CreateQuote(ctx, "")
+		_, _ = store.CreateQuote(ctx, "")
+
+		updated, err := store.UpdateQuoteCustomerId(ctx, q.Id, c.Id)
+		require.NoError(t, err)
+		assert.Equal(t, c.Id, updated.CustomerId)
+
+		page, err := store.ListQuotes(ctx, QuoteFilter{CustomerId: &c.Id})
+		require.NoError(t, err)
+		require.Len(t, page.Quotes, 1)
+		assert.Equal(t, q.Id, page.Quotes[0].Id)
+	})
+
+	t.Run("Payment/CRUD", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		other, _ := store.CreateQuote(ctx, "")
+		_, _ = store.LockQuote(ctx, q.Id)
+
+		later := time.Now().UTC().Truncate(time.Second)
+		earlier := later.Add(-24 * time.Hour)
+		second, err := store.CreatePayment(ctx, Payment{QuoteId: q.Id, Amount: 2500, Method: PaymentMethodCard, Reference: "ch_123", ReceivedOn: later})
+		require.NoError(t, err, "payments are recorded against locked quotes")
+		assert.NotEmpty(t, second.Id)
+		first, err := store.CreatePayment(ctx, Payment{QuoteId: q.Id, Amount: 5000, Method: PaymentMethodCheck, ReceivedOn: earlier, MilestoneId: "deposit"})
+		require.NoError(t, err)
+		_, err = store.CreatePayment(ctx, Payment{QuoteId: other.Id, Amount: 100, ReceivedOn: later})
+		require.NoError(t, err)
+
+		got, err := store.GetPayment(ctx, second.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "ch_123", got.Reference)
+		assert.True(t, later.Equal(got.ReceivedOn))
+
+		payments, err := store.GetQuotePayments(ctx, q.Id)
+		require.NoError(t, err)
+		require.Len(t, payments, 2)
+		assert.Equal(t, first.Id, payments[0].Id)
+		assert.Equal(t, ID("deposit"), payments[0].MilestoneId)
+		assert.Equal(t, second.Id, payments[1].Id)
+
+		require.NoError(t, store.DeletePayment(ctx, first.Id))
+		payments, err = store.GetQuotePayments(ctx, q.Id)
+		require.NoError(t, err)
+		require.Len(t, payments, 1)
+		assert.Equal(t, second.Id, payments[0].Id)
+	})
+
+	t.Run("Invoice/CRUD", func(t *testing.T) {
+		reset(t)
+		q, _ := store.CreateQuote(ctx, "")
+		_, _ = store.LockQuote(ctx, q.Id)
+		amount := 5000
+
+		inv, err := store.CreateInvoice(ctx, Invoice{
+			QuoteId:          q.Id,
+			Number:           "INV-00001",
+			QuoteLineItemIds: []ID{"1"},
+			LineItems:        []*LineItem{{Id: "1", Description: "Labor", Amount: &amount}},
+			Total:            5000,
+		})
+		require.NoError(t, err, "invoices are created from locked quotes")
+		assert.NotEmpty(t, inv.Id)
+
+		got, err := store.GetInvoice(ctx, inv.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "INV-00001", got.Number)
+		require.Len(t, got.LineItems, 1)
+		assert.Equal(t, 5000, *got.LineItems[0].Amount)
+
+		dueDate := time.Now().UTC().Truncate(time.Second)
+		got, err = store.UpdateInvoiceDueDate(ctx, inv.Id, &dueDate)
+		require.NoError(t, err)
+		assert.True(t, dueDate.Equal(*got.DueDate))
+		got, err = store.UpdateInvoicePaymentTerms(ctx, inv.Id, "Net 15")
+		require.NoError(t, err)
+		assert.Equal(t, "Net 15", got.PaymentTerms)
+		got, err = store.GetInvoice(ctx, inv.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "Net 15", got.PaymentTerms)
+		require.NotNil(t, got.DueDate)
+
+		_, err = store.CreateInvoice(ctx, Invoice{QuoteId: q.Id, Number: "INV-00001"})
+		assert.Error(t, err, "invoice numbers are unique")
+
+		invoices, err := store.GetQuoteInvoices(ctx, q.Id)
+		require.NoError(t, err)
+		require.Len(t, invoices, 1)
+		require.NoError(t, store.DeleteInvoice(ctx, inv.Id))
+		invoices, err = store.GetQuoteInvoices(ctx, q.Id)
+		require.NoError(t, err)
+		assert.Empty(t, invoices)
+	})
+
+	t.Run("Invoice/NumberSequence", func(t *testing.T) {
+		reset(t)
+		_, err := store.GetInvoiceNumberSequence(ctx)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		seq, err := store.AllocateInvoiceNumber(ctx, 2026)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultInvoiceNumberPattern, seq.Pattern)
+		assert.Equal(t, 1, seq.Counter)
+
+		seq, err = store.SetInvoiceNumberSequence(ctx, InvoiceNumberSequence{Pattern: "INV-{YYYY}-{N}", ResetYearly: true})
+		require.NoError(t, err)
+		assert.Equal(t, 1, seq.Counter, "changing the pattern carries on the counter")
+		seq, err = store.AllocateInvoiceNumber(ctx, 2026)
+		require.NoError(t, err)
+		assert.Equal(t, 2, seq.Counter)
+		seq, err = store.AllocateInvoiceNumber(ctx, 2027)
+		require.NoError(t, err)
+		assert.Equal(t, 1, seq.Counter)
+		assert.Equal(t, "INV-{YYYY}-{N}", seq.Pattern)
+
+		quoteSeq, err := store.GetQuoteNumberSequence(ctx)
+		assert.ErrorIs(t, err, ErrNotFound, "invoices are numbered separately from quotes")
+		assert.Nil(t, quoteSeq)
+	})
+
 	// ──────────────────────────────────────────────
 	// TRANSACTION
 	// ──────────────────────────────────────────────
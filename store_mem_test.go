@@ -0,0 +1,252 @@
+package pricey
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// memStore is an in-memory Store for exercising the service layer without a
+// database. It holds only what a test puts in it; methods no test needs fall
+// through to the embedded nil Store and panic.
+type memStore struct {
+	Store
+	quotes      map[ID]*Quote
+	lineItems   map[ID]*LineItem
+	adjustments map[ID]*Adjustment
+	items       map[ID]*Item
+	revisions   []*QuoteRevision
+	policy      *QuotePolicy
+	payments    []*Payment
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		quotes:      map[ID]*Quote{},
+		lineItems:   map[ID]*LineItem{},
+		adjustments: map[ID]*Adjustment{},
+		items:       map[ID]*Item{},
+	}
+}
+
+func (m *memStore) Transaction(ctx context.Context, f func(ctx context.Context) error) error {
+	return f(ctx)
+}
+
+func (m *memStore) GetItem(ctx context.Context, id ID) (*Item, error) {
+	item, ok := m.items[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *item
+	return &c, nil
+}
+
+func (m *memStore) GetQuote(ctx context.Context, id ID) (*Quote, error) {
+	q, ok := m.quotes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *q
+	return &c, nil
+}
+
+// ListQuotes only filters by ChangeOrderOf and IncludeDeleted, and returns
+// every match on one page.
+func (m *memStore) ListQuotes(ctx context.Context, filter QuoteFilter) (*QuotePage, error) {
+	page := &QuotePage{Quotes: []*Quote{}}
+	for id, q := range m.quotes {
+		if (filter.ChangeOrderOf != nil && q.ChangeOrderOf != *filter.ChangeOrderOf) || (q.Hidden && !filter.IncludeDeleted) {
+			continue
+		}
+		c, _ := m.GetQuote(ctx, id)
+		page.Quotes = append(page.Quotes, c)
+	}
+	page.Total = len(page.Quotes)
+	return page, nil
+}
+
+func (m *memStore) updateQuote(id ID, change func(q *Quote)) (*Quote, error) {
+	q, ok := m.quotes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if q.Locked {
+		return nil, &QuoteLockedError{QuoteId: id}
+	}
+	change(q)
+	return m.GetQuote(context.Background(), id)
+}
+
+func (m *memStore) UpdateQuoteSubTotal(ctx context.Context, id ID, subTotal int) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.SubTotal = subTotal })
+}
+
+func (m *memStore) UpdateQuoteTotal(ctx context.Context, id ID, total int) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.Total = total })
+}
+
+func (m *memStore) UpdateQuoteBalanceDue(ctx context.Context, id ID, balanceDue int) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.BalanceDue = balanceDue })
+}
+
+func (m *memStore) UpdateQuoteSoldOn(ctx context.Context, id ID, soldOn *time.Time) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.SoldOn = soldOn })
+}
+
+func (m *memStore) UpdateQuoteApprovals(ctx context.Context, id ID, approvals []QuoteApproval) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.Approvals = approvals })
+}
+
+func (m *memStore) UpdateQuoteDiscountRole(ctx context.Context, id ID, role Role) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.DiscountRole = role })
+}
+
+func (m *memStore) UpdateQuoteChosenOptionId(ctx context.Context, id ID, optionId ID) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.ChosenOptionId = optionId })
+}
+
+func (m *memStore) UpdateQuoteIssueDate(ctx context.Context, id ID, issueDate *time.Time) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.IssueDate = issueDate })
+}
+
+func (m *memStore) UpdateQuoteExpirationDate(ctx context.Context, id ID, expirationDate *time.Time) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.ExpirationDate = expirationDate })
+}
+
+func (m *memStore) UpdateQuoteSentOn(ctx context.Context, id ID, sentOn *time.Time) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.SentOn = sentOn })
+}
+
+func (m *memStore) UpdateQuoteAcceptance(ctx context.Context, id ID, acceptance *QuoteAcceptance) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.Acceptance = acceptance })
+}
+
+// UpdateQuoteStatus, LockQuote and UnlockQuote are allowed on locked quotes, as accepted
+// quotes can still be converted or voided.
+func (m *memStore) UpdateQuoteStatus(ctx context.Context, id ID, status QuoteStatus, change QuoteStatusChange) (*Quote, error) {
+	q, ok := m.quotes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	q.Status = status
+	q.StatusHistory = append(q.StatusHistory, change)
+	return m.GetQuote(ctx, id)
+}
+
+func (m *memStore) LockQuote(ctx context.Context, id ID) (*Quote, error) {
+	q, ok := m.quotes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	q.Locked = true
+	return m.GetQuote(ctx, id)
+}
+
+func (m *memStore) UnlockQuote(ctx context.Context, id ID, unlock QuoteUnlock) (*Quote, error) {
+	q, ok := m.quotes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	q.Locked = false
+	q.ExpiryLocked = false
+	q.UnlockHistory = append(q.UnlockHistory, unlock)
+	return m.GetQuote(ctx, id)
+}
+
+func (m *memStore) CreateQuoteRevision(ctx context.Context, revision QuoteRevision) (*QuoteRevision, error) {
+	revision.Number = len(m.revisions) + 1
+	m.revisions = append(m.revisions, &revision)
+	return &revision, nil
+}
+
+func (m *memStore) GetQuotePolicy(ctx context.Context) (*QuotePolicy, error) {
+	if m.policy == nil {
+		return nil, ErrNotFound
+	}
+	return m.policy, nil
+}
+
+func (m *memStore) GetLineItem(ctx context.Context, id ID) (*LineItem, error) {
+	li, ok := m.lineItems[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *li
+	return &c, nil
+}
+
+// updateLineItem changes the line item, refusing like the real stores do when
+// its quote is locked.
+func (m *memStore) updateLineItem(id ID, change func(li *LineItem)) (*LineItem, error) {
+	li, ok := m.lineItems[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if q, ok := m.quotes[li.QuoteId]; ok && q.Locked {
+		return nil, &QuoteLockedError{QuoteId: li.QuoteId}
+	}
+	change(li)
+	return m.GetLineItem(context.Background(), id)
+}
+
+func (m *memStore) UpdateLineItemDescription(ctx context.Context, id ID, description string) (*LineItem, error) {
+	return m.updateLineItem(id, func(li *LineItem) { li.Description = description })
+}
+
+func (m *memStore) UpdateLineItemUnitPrice(ctx context.Context, id ID, unitPrice int, prefix, suffix string) (*LineItem, error) {
+	return m.updateLineItem(id, func(li *LineItem) {
+		li.UnitPrice = unitPrice
+		li.UnitPricePrefix = prefix
+		li.UnitPriceSuffix = suffix
+	})
+}
+
+// UpdateLineItemUnitCost is allowed on locked quotes, like the real stores.
+func (m *memStore) UpdateLineItemUnitCost(ctx context.Context, id ID, unitCost int) (*LineItem, error) {
+	li, ok := m.lineItems[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	li.UnitCost = unitCost
+	return m.GetLineItem(ctx, id)
+}
+
+func (m *memStore) UpdateLineItemSource(ctx context.Context, id ID, itemId, priceId *ID) (*LineItem, error) {
+	return m.updateLineItem(id, func(li *LineItem) {
+		li.SourceItemId = itemId
+		li.SourcePriceId = priceId
+	})
+}
+
+func (m *memStore) GetAdjustment(ctx context.Context, id ID) (*Adjustment, error) {
+	a, ok := m.adjustments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *a
+	return &c, nil
+}
+
+func (m *memStore) CreateAdjustment(ctx context.Context, quoteId ID, description string, amount int, adjustmentType AdjustmentType) (*Adjustment, error) {
+	if q, ok := m.quotes[quoteId]; ok && q.Locked {
+		return nil, &QuoteLockedError{QuoteId: quoteId}
+	}
+	a := &Adjustment{Id: ID(fmt.Sprintf("a%d", len(m.adjustments)+1)), QuoteId: quoteId, Description: description, Amount: amount, Type: adjustmentType}
+	m.adjustments[a.Id] = a
+	return m.GetAdjustment(ctx, a.Id)
+}
+
+func (m *memStore) QuoteAddAdjustment(ctx context.Context, id ID, adjustmentId ID) (*Quote, error) {
+	return m.updateQuote(id, func(q *Quote) { q.AdjustmentIds = append(q.AdjustmentIds, adjustmentId) })
+}
+
+func (m *memStore) GetQuotePayments(ctx context.Context, quoteId ID) ([]*Payment, error) {
+	payments := []*Payment{}
+	for _, p := range m.payments {
+		if p.QuoteId == quoteId {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
@@ -0,0 +1,40 @@
+package pricey
+
+// templateFromQuote captures the quote's line items, adjustments, options,
+// payment terms and schedule, notes and colors as a template. Line items keep
+// their ids so the template's trees can be rebuilt, but lose their quote.
+func templateFromQuote(quote *Quote, lineItems map[ID]*LineItem, adjustments map[ID]*Adjustment) *QuoteTemplate {
+	t := &QuoteTemplate{
+		LogoId:                 quote.LogoId,
+		PrimaryBackgroundColor: quote.PrimaryBackgroundColor,
+		PrimaryTextColor:       quote.PrimaryTextColor,
+		PaymentTerms:           quote.PaymentTerms,
+		Notes:                  quote.Notes,
+		BalancePercentDue:      quote.BalancePercentDue,
+		LineItemIds:            []ID{},
+		LineItems:              []*LineItem{},
+		Adjustments:            []*Adjustment{},
+		Options:                append([]QuoteOption{}, quote.Options...),
+		PaymentSchedule:        append([]PaymentMilestone{}, quote.PaymentSchedule...),
+	}
+	for _, lineItemId := range quote.LineItemIds {
+		li := lineItems[lineItemId]
+		if li == nil {
+			continue
+		}
+		copied := *li
+		copied.QuoteId = ""
+		t.LineItemIds = append(t.LineItemIds, lineItemId)
+		t.LineItems = append(t.LineItems, &copied)
+	}
+	for _, adjustmentId := range quote.AdjustmentIds {
+		a := adjustments[adjustmentId]
+		if a == nil {
+			continue
+		}
+		copied := *a
+		copied.QuoteId = ""
+		t.Adjustments = append(t.Adjustments, &copied)
+	}
+	return t
+}
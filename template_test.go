@@ -0,0 +1,46 @@
+package pricey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateFromQuote(t *testing.T) {
+	s := func(str string) *string {
+		return &str
+	}
+	quote := &Quote{
+		Id:                     "q",
+		PrimaryBackgroundColor: "#003366",
+		PaymentTerms:           "Net 30",
+		Notes:                  "Includes haul away",
+		BalancePercentDue:      50,
+		LineItemIds:            []ID{"1", "2", "missing"},
+		AdjustmentIds:          []ID{"a"},
+	}
+	lineItems := map[ID]*LineItem{
+		"1": {Id: "1", QuoteId: "q", Description: "Water heater", SubItemIds: []ID{"2"}, SourceItemId: s("item")},
+		"2": {Id: "2", QuoteId: "q", ParentId: s("1"), Description: "Expansion tank"},
+	}
+	adjustments := map[ID]*Adjustment{
+		"a": {Id: "a", QuoteId: "q", Description: "Tax", Type: AdjustmentTypePercent, Amount: 8},
+	}
+
+	tmpl := templateFromQuote(quote, lineItems, adjustments)
+	assert.Equal(t, "#003366", tmpl.PrimaryBackgroundColor)
+	assert.Equal(t, "Net 30", tmpl.PaymentTerms)
+	assert.Equal(t, "Includes haul away", tmpl.Notes)
+	assert.Equal(t, 50, tmpl.BalancePercentDue)
+	assert.Equal(t, []ID{"1", "2"}, tmpl.LineItemIds)
+	require.Len(t, tmpl.LineItems, 2)
+	assert.Empty(t, tmpl.LineItems[0].QuoteId)
+	assert.Equal(t, ID("item"), *tmpl.LineItems[0].SourceItemId)
+	assert.Equal(t, ID("1"), *tmpl.LineItems[1].ParentId)
+	require.Len(t, tmpl.Adjustments, 1)
+	assert.Empty(t, tmpl.Adjustments[0].QuoteId)
+
+	// the quote's own line items are left untouched
+	assert.Equal(t, ID("q"), lineItems["1"].QuoteId)
+}